@@ -2,7 +2,6 @@ package ast
 
 import (
 	"bytes"
-	"fmt"
 	"strings"
 
 	"github.com/Arch-4ng3l/Monkey/token"
@@ -88,10 +87,99 @@ func (fl *ForLoop) String() string {
 	return out.String()
 }
 
-type FunctionLiteral struct {
+type TypeAnnotation struct {
+	Token token.Token
+	Name  string
+	Elem  *TypeAnnotation
+}
+
+func (ta *TypeAnnotation) TokenLiteral() string {
+	return ta.Token.Literal
+}
+func (ta *TypeAnnotation) String() string {
+	if ta.Elem != nil {
+		return "[" + ta.Elem.String() + "]"
+	}
+	return ta.Name
+}
+
+type ForInLoop struct {
+	Token    token.Token
+	Pattern  Expression
+	Iterable Expression
+	Body     *BlockStatement
+}
+
+func (fi *ForInLoop) expressionNode() {}
+func (fi *ForInLoop) TokenLiteral() string {
+	return fi.Token.Literal
+}
+func (fi *ForInLoop) String() string {
+	var out bytes.Buffer
+	out.WriteString("for (")
+	out.WriteString(fi.Pattern.String())
+	out.WriteString(" in ")
+	out.WriteString(fi.Iterable.String())
+	out.WriteString(") {\n")
+	out.WriteString(fi.Body.String())
+	out.WriteString("\n}")
+
+	return out.String()
+}
+
+type ArrayPattern struct {
+	Token    token.Token
+	Elements []Expression
+	Rest     *Ident
+}
+
+func (ap *ArrayPattern) expressionNode() {}
+func (ap *ArrayPattern) TokenLiteral() string {
+	return ap.Token.Literal
+}
+func (ap *ArrayPattern) String() string {
+	elements := []string{}
+	for _, el := range ap.Elements {
+		elements = append(elements, el.String())
+	}
+	if ap.Rest != nil {
+		elements = append(elements, "..."+ap.Rest.String())
+	}
+
+	return "[" + strings.Join(elements, ", ") + "]"
+}
+
+type HashPattern struct {
 	Token  token.Token
-	Params []*Ident
-	Body   *BlockStatement
+	Keys   []string
+	Values []Expression
+}
+
+func (hp *HashPattern) expressionNode() {}
+func (hp *HashPattern) TokenLiteral() string {
+	return hp.Token.Literal
+}
+func (hp *HashPattern) String() string {
+	pairs := []string{}
+	for i, key := range hp.Keys {
+		if ident, ok := hp.Values[i].(*Ident); ok && ident.Value == key {
+			pairs = append(pairs, key)
+		} else {
+			pairs = append(pairs, key+": "+hp.Values[i].String())
+		}
+	}
+
+	return "{" + strings.Join(pairs, ", ") + "}"
+}
+
+type FunctionLiteral struct {
+	Token         token.Token
+	Name          string
+	Params        []*Ident
+	ParamPatterns []Expression
+	ParamTypes    []*TypeAnnotation
+	ReturnType    *TypeAnnotation
+	Body          *BlockStatement
 }
 
 func (fl *FunctionLiteral) expressionNode() {}
@@ -104,20 +192,57 @@ func (fl *FunctionLiteral) String() string {
 	out.WriteString(fl.TokenLiteral())
 	out.WriteString("(")
 	params := []string{}
-	for _, p := range fl.Params {
-		params = append(params, p.String())
+	for i, p := range fl.Params {
+		if i < len(fl.ParamPatterns) && fl.ParamPatterns[i] != nil {
+			params = append(params, fl.ParamPatterns[i].String())
+		} else if i < len(fl.ParamTypes) && fl.ParamTypes[i] != nil {
+			params = append(params, p.String()+": "+fl.ParamTypes[i].String())
+		} else {
+			params = append(params, p.String())
+		}
 	}
 
 	out.WriteString(strings.Join(params, ", "))
 	out.WriteString(") ")
+	if fl.ReturnType != nil {
+		out.WriteString("-> " + fl.ReturnType.String() + " ")
+	}
 	out.WriteString(fl.Body.String())
 	return out.String()
 }
 
+type MacroLiteral struct {
+	Token  token.Token
+	Params []*Ident
+	Body   *BlockStatement
+}
+
+func (ml *MacroLiteral) expressionNode() {}
+func (ml *MacroLiteral) TokenLiteral() string {
+	return ml.Token.Literal
+}
+func (ml *MacroLiteral) String() string {
+	var out bytes.Buffer
+
+	params := []string{}
+	for _, p := range ml.Params {
+		params = append(params, p.String())
+	}
+
+	out.WriteString(ml.TokenLiteral())
+	out.WriteString("(")
+	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(") ")
+	out.WriteString(ml.Body.String())
+
+	return out.String()
+}
+
 type CallExpression struct {
 	Token    token.Token
 	Function Expression
 	Args     []Expression
+	Tail     bool
 }
 
 func (ce *CallExpression) expressionNode() {}
@@ -127,7 +252,6 @@ func (ce *CallExpression) TokenLiteral() string {
 
 func (ce *CallExpression) String() string {
 	var out bytes.Buffer
-	fmt.Println("call")
 	args := []string{}
 
 	for _, a := range ce.Args {
@@ -182,10 +306,92 @@ func (rs *ReturnStatement) String() string {
 	return out.String()
 }
 
-type LetStatement struct {
+type EnumVariant struct {
+	Name   *Ident
+	Params []*Ident
+}
+
+func (ev *EnumVariant) String() string {
+	if len(ev.Params) == 0 {
+		return ev.Name.String()
+	}
+	params := []string{}
+	for _, p := range ev.Params {
+		params = append(params, p.String())
+	}
+	return ev.Name.String() + "(" + strings.Join(params, ", ") + ")"
+}
+
+type EnumStatement struct {
+	Token    token.Token
+	Name     *Ident
+	Variants []*EnumVariant
+}
+
+func (es *EnumStatement) statementNode() {}
+func (es *EnumStatement) TokenLiteral() string {
+	return es.Token.Literal
+}
+func (es *EnumStatement) String() string {
+	variants := []string{}
+	for _, v := range es.Variants {
+		variants = append(variants, v.String())
+	}
+	return "enum " + es.Name.String() + " { " + strings.Join(variants, ", ") + " }"
+}
+
+type MatchArm struct {
+	Variant  *Ident
+	Bindings []*Ident
+	Body     *BlockStatement
+}
+
+func (ma *MatchArm) IsWildcard() bool {
+	return ma.Variant.Value == "_"
+}
+
+func (ma *MatchArm) String() string {
+	pattern := (&EnumVariant{Name: ma.Variant, Params: ma.Bindings}).String()
+	return pattern + " => " + ma.Body.String()
+}
+
+type MatchExpression struct {
+	Token   token.Token
+	Subject Expression
+	Arms    []*MatchArm
+}
+
+func (me *MatchExpression) expressionNode() {}
+func (me *MatchExpression) TokenLiteral() string {
+	return me.Token.Literal
+}
+func (me *MatchExpression) String() string {
+	arms := []string{}
+	for _, arm := range me.Arms {
+		arms = append(arms, arm.String())
+	}
+	return "match (" + me.Subject.String() + ") { " + strings.Join(arms, ", ") + " }"
+}
+
+type DeferStatement struct {
 	Token token.Token
-	Name  *Ident
-	Value Expression
+	Call  Expression
+}
+
+func (ds *DeferStatement) statementNode() {}
+func (ds *DeferStatement) TokenLiteral() string {
+	return ds.Token.Literal
+}
+func (ds *DeferStatement) String() string {
+	return ds.TokenLiteral() + " " + ds.Call.String() + ";"
+}
+
+type LetStatement struct {
+	Token   token.Token
+	Name    *Ident
+	Pattern Expression
+	Type    *TypeAnnotation
+	Value   Expression
 }
 
 func (ls *LetStatement) statementNode() {}
@@ -196,9 +402,15 @@ func (ls *LetStatement) String() string {
 	var out bytes.Buffer
 
 	out.WriteString(ls.TokenLiteral() + " ")
-	out.WriteString(ls.Name.String())
+	if ls.Pattern != nil {
+		out.WriteString(ls.Pattern.String())
+	} else {
+		out.WriteString(ls.Name.String())
+	}
+	if ls.Type != nil {
+		out.WriteString(": " + ls.Type.String())
+	}
 	out.WriteString(" = ")
-	fmt.Println(ls.Value.String())
 	if ls.Value != nil {
 		out.WriteString(ls.Value.String())
 	}
@@ -207,10 +419,38 @@ func (ls *LetStatement) String() string {
 	return out.String()
 }
 
-type IndexExpression struct {
+func (ls *LetStatement) IsConst() bool {
+	return ls.Token.Type == token.CONST
+}
+
+type IndexAssignExpression struct {
 	Token token.Token
 	Left  Expression
 	Index Expression
+	Value Expression
+}
+
+func (ia *IndexAssignExpression) expressionNode() {}
+func (ia *IndexAssignExpression) TokenLiteral() string {
+	return ia.Token.Literal
+}
+func (ia *IndexAssignExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(ia.Left.String())
+	out.WriteString("[")
+	out.WriteString(ia.Index.String())
+	out.WriteString("] = ")
+	out.WriteString(ia.Value.String())
+
+	return out.String()
+}
+
+type IndexExpression struct {
+	Token    token.Token
+	Left     Expression
+	Index    Expression
+	Optional bool
 }
 
 func (ie *IndexExpression) expressionNode() {}
@@ -222,6 +462,9 @@ func (ie *IndexExpression) String() string {
 
 	out.WriteString("(")
 	out.WriteString(ie.Left.String())
+	if ie.Optional {
+		out.WriteString("?")
+	}
 	out.WriteString("[")
 	out.WriteString(ie.Index.String())
 	out.WriteString("]")
@@ -255,6 +498,31 @@ func (as *ArrayLiteral) String() string {
 	return out.String()
 }
 
+type HashLiteral struct {
+	Token  token.Token
+	Keys   []Expression
+	Values []Expression
+}
+
+func (hl *HashLiteral) expressionNode() {}
+func (hl *HashLiteral) TokenLiteral() string {
+	return hl.Token.Literal
+}
+func (hl *HashLiteral) String() string {
+	var out bytes.Buffer
+
+	pairs := []string{}
+	for i, key := range hl.Keys {
+		pairs = append(pairs, key.String()+": "+hl.Values[i].String())
+	}
+
+	out.WriteString("{")
+	out.WriteString(strings.Join(pairs, ", "))
+	out.WriteString("}")
+
+	return out.String()
+}
+
 type StrLiteral struct {
 	Token token.Token
 	Value string
@@ -268,6 +536,19 @@ func (sl *StrLiteral) String() string {
 	return sl.Token.Literal
 }
 
+type RegexLiteral struct {
+	Token token.Token
+	Value string
+}
+
+func (rl *RegexLiteral) expressionNode() {}
+func (rl *RegexLiteral) TokenLiteral() string {
+	return rl.Token.Literal
+}
+func (rl *RegexLiteral) String() string {
+	return "/" + rl.Value + "/"
+}
+
 type FloatLiteral struct {
 	Token token.Token
 	Value float64
@@ -307,6 +588,31 @@ func (b *Boolean) String() string {
 	return b.Token.Literal
 }
 
+type TernaryExpression struct {
+	Token       token.Token
+	Condition   Expression
+	Consequence Expression
+	Alternative Expression
+}
+
+func (te *TernaryExpression) expressionNode() {}
+func (te *TernaryExpression) TokenLiteral() string {
+	return te.Token.Literal
+}
+func (te *TernaryExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("(")
+	out.WriteString(te.Condition.String())
+	out.WriteString(" ? ")
+	out.WriteString(te.Consequence.String())
+	out.WriteString(" : ")
+	out.WriteString(te.Alternative.String())
+	out.WriteString(")")
+
+	return out.String()
+}
+
 type IfExpression struct {
 	Token     token.Token
 	Condition Expression
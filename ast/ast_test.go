@@ -32,3 +32,96 @@ func TestString(t *testing.T) {
 	}
 
 }
+
+func TestModify(t *testing.T) {
+	one := func() Expression {
+		return &IntLiteral{Token: token.Token{Type: token.INT, Literal: "1"}, Value: 1}
+	}
+	two := func() Expression {
+		return &IntLiteral{Token: token.Token{Type: token.INT, Literal: "2"}, Value: 2}
+	}
+
+	turnOneIntoTwo := func(node Node) Node {
+		integer, ok := node.(*IntLiteral)
+		if !ok || integer.Value != 1 {
+			return node
+		}
+		return two()
+	}
+
+	tests := []struct {
+		input    Node
+		expected Node
+	}{
+		{one(), two()},
+		{
+			&InfixExpression{Left: one(), Operator: "+", Right: two()},
+			&InfixExpression{Left: two(), Operator: "+", Right: two()},
+		},
+		{
+			&ArrayLiteral{Elements: []Expression{one(), one()}},
+			&ArrayLiteral{Elements: []Expression{two(), two()}},
+		},
+		{
+			&HashLiteral{Keys: []Expression{one()}, Values: []Expression{one()}},
+			&HashLiteral{Keys: []Expression{two()}, Values: []Expression{two()}},
+		},
+		{
+			&IfExpression{
+				Condition: one(),
+				If:        &BlockStatement{Statements: []Statement{&ExpresssionStatement{Expression: one()}}},
+			},
+			&IfExpression{
+				Condition: two(),
+				If:        &BlockStatement{Statements: []Statement{&ExpresssionStatement{Expression: two()}}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		before := tt.input.String()
+		modified := Modify(tt.input, turnOneIntoTwo)
+
+		if modified.String() != tt.expected.String() {
+			t.Errorf("not modified want %s got %s", tt.expected.String(), modified.String())
+		}
+		if tt.input.String() != before {
+			t.Errorf("input was changed in place got %s", tt.input.String())
+		}
+	}
+
+	count := 0
+	Walk(&ArrayLiteral{Elements: []Expression{one(), two()}}, func(n Node) { count++ })
+	if count != 3 {
+		t.Errorf("Walk visited wrong number of nodes got %d", count)
+	}
+}
+
+func TestMarkTailCalls(t *testing.T) {
+	call := func() *CallExpression {
+		return &CallExpression{Function: &Ident{Value: "f"}}
+	}
+	inner, tail, ifTail, elseTail, returned := call(), call(), call(), call(), call()
+
+	fn := &FunctionLiteral{
+		Body: &BlockStatement{
+			Statements: []Statement{
+				&ExpresssionStatement{Expression: &InfixExpression{Left: inner, Operator: "+", Right: &IntLiteral{}}},
+				&ReturnStatement{Value: returned},
+				&ExpresssionStatement{Expression: &IfExpression{
+					If:   &BlockStatement{Statements: []Statement{&ExpresssionStatement{Expression: ifTail}}},
+					Else: &BlockStatement{Statements: []Statement{&ExpresssionStatement{Expression: elseTail}}},
+				}},
+			},
+		},
+	}
+	fn.Body.Statements = append(fn.Body.Statements, &ExpresssionStatement{Expression: tail})
+	MarkTailCalls(fn)
+
+	if inner.Tail || ifTail.Tail || elseTail.Tail {
+		t.Errorf("calls outside of tail position were marked")
+	}
+	if !tail.Tail || !returned.Tail {
+		t.Errorf("calls in tail position were not marked")
+	}
+}
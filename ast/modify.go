@@ -0,0 +1,243 @@
+package ast
+
+type ModifierFunc func(Node) Node
+
+// Modify rebuilds the tree bottom up, passing every node to modifier after its
+// children have been modified. The input tree is never changed in place.
+func Modify(node Node, modifier ModifierFunc) Node {
+	switch node := node.(type) {
+
+	case *Program:
+		n := *node
+		n.Statements = modifyStatements(node.Statements, modifier)
+		return modifier(&n)
+
+	case *ExpresssionStatement:
+		n := *node
+		n.Expression = modifyExpression(node.Expression, modifier)
+		return modifier(&n)
+
+	case *BlockStatement:
+		n := *node
+		n.Statements = modifyStatements(node.Statements, modifier)
+		return modifier(&n)
+
+	case *LetStatement:
+		n := *node
+		if node.Name != nil {
+			if name, ok := Modify(node.Name, modifier).(*Ident); ok {
+				n.Name = name
+			}
+		}
+		n.Pattern = modifyExpression(node.Pattern, modifier)
+		n.Value = modifyExpression(node.Value, modifier)
+		return modifier(&n)
+
+	case *ReturnStatement:
+		n := *node
+		n.Value = modifyExpression(node.Value, modifier)
+		return modifier(&n)
+
+	case *MatchExpression:
+		n := *node
+		n.Subject = modifyExpression(node.Subject, modifier)
+		n.Arms = make([]*MatchArm, len(node.Arms))
+		for i, arm := range node.Arms {
+			a := *arm
+			a.Body = modifyBlock(arm.Body, modifier)
+			n.Arms[i] = &a
+		}
+		return modifier(&n)
+
+	case *DeferStatement:
+		n := *node
+		n.Call = modifyExpression(node.Call, modifier)
+		return modifier(&n)
+
+	case *ReasignExpression:
+		n := *node
+		if v, ok := Modify(node.Var, modifier).(*Ident); ok {
+			n.Var = v
+		}
+		n.Value = modifyExpression(node.Value, modifier)
+		return modifier(&n)
+
+	case *InfixExpression:
+		n := *node
+		n.Left = modifyExpression(node.Left, modifier)
+		n.Right = modifyExpression(node.Right, modifier)
+		return modifier(&n)
+
+	case *PrefixExpression:
+		n := *node
+		n.Right = modifyExpression(node.Right, modifier)
+		return modifier(&n)
+
+	case *IndexExpression:
+		n := *node
+		n.Left = modifyExpression(node.Left, modifier)
+		n.Index = modifyExpression(node.Index, modifier)
+		return modifier(&n)
+
+	case *IndexAssignExpression:
+		n := *node
+		n.Left = modifyExpression(node.Left, modifier)
+		n.Index = modifyExpression(node.Index, modifier)
+		n.Value = modifyExpression(node.Value, modifier)
+		return modifier(&n)
+
+	case *TernaryExpression:
+		n := *node
+		n.Condition = modifyExpression(node.Condition, modifier)
+		n.Consequence = modifyExpression(node.Consequence, modifier)
+		n.Alternative = modifyExpression(node.Alternative, modifier)
+		return modifier(&n)
+
+	case *IfExpression:
+		n := *node
+		n.Condition = modifyExpression(node.Condition, modifier)
+		n.If = modifyBlock(node.If, modifier)
+		n.Else = modifyBlock(node.Else, modifier)
+		return modifier(&n)
+
+	case *WhileLoop:
+		n := *node
+		n.LoopCond = modifyExpression(node.LoopCond, modifier)
+		n.Body = modifyBlock(node.Body, modifier)
+		return modifier(&n)
+
+	case *ForLoop:
+		n := *node
+		if node.LoopVar != nil {
+			if lv, ok := Modify(node.LoopVar, modifier).(*LetStatement); ok {
+				n.LoopVar = lv
+			}
+		}
+		n.LoopCond = modifyExpression(node.LoopCond, modifier)
+		n.PostLoop = modifyExpression(node.PostLoop, modifier)
+		n.Body = modifyBlock(node.Body, modifier)
+		return modifier(&n)
+
+	case *ForInLoop:
+		n := *node
+		n.Pattern = modifyExpression(node.Pattern, modifier)
+		n.Iterable = modifyExpression(node.Iterable, modifier)
+		n.Body = modifyBlock(node.Body, modifier)
+		return modifier(&n)
+
+	case *ArrayPattern:
+		n := *node
+		n.Elements = modifyExpressions(node.Elements, modifier)
+		if node.Rest != nil {
+			if rest, ok := Modify(node.Rest, modifier).(*Ident); ok {
+				n.Rest = rest
+			}
+		}
+		return modifier(&n)
+
+	case *HashPattern:
+		n := *node
+		n.Values = modifyExpressions(node.Values, modifier)
+		return modifier(&n)
+
+	case *FunctionLiteral:
+		n := *node
+		n.Params = modifyIdents(node.Params, modifier)
+		n.ParamPatterns = modifyExpressions(node.ParamPatterns, modifier)
+		n.Body = modifyBlock(node.Body, modifier)
+		return modifier(&n)
+
+	case *MacroLiteral:
+		n := *node
+		n.Params = modifyIdents(node.Params, modifier)
+		n.Body = modifyBlock(node.Body, modifier)
+		return modifier(&n)
+
+	case *CallExpression:
+		n := *node
+		n.Function = modifyExpression(node.Function, modifier)
+		n.Args = modifyExpressions(node.Args, modifier)
+		return modifier(&n)
+
+	case *ArrayLiteral:
+		n := *node
+		n.Elements = modifyExpressions(node.Elements, modifier)
+		return modifier(&n)
+
+	case *HashLiteral:
+		n := *node
+		n.Keys = modifyExpressions(node.Keys, modifier)
+		n.Values = modifyExpressions(node.Values, modifier)
+		return modifier(&n)
+	}
+
+	return modifier(node)
+}
+
+// Walk calls fn for every node in the tree, children before their parents.
+func Walk(node Node, fn func(Node)) {
+	Modify(node, func(n Node) Node {
+		fn(n)
+		return n
+	})
+}
+
+func modifyExpression(exp Expression, modifier ModifierFunc) Expression {
+	if exp == nil {
+		return nil
+	}
+	modified, ok := Modify(exp, modifier).(Expression)
+	if !ok {
+		return exp
+	}
+	return modified
+}
+
+func modifyBlock(block *BlockStatement, modifier ModifierFunc) *BlockStatement {
+	if block == nil {
+		return nil
+	}
+	modified, ok := Modify(block, modifier).(*BlockStatement)
+	if !ok {
+		return block
+	}
+	return modified
+}
+
+func modifyStatements(stmts []Statement, modifier ModifierFunc) []Statement {
+	res := make([]Statement, 0, len(stmts))
+	for _, stmt := range stmts {
+		if modified, ok := Modify(stmt, modifier).(Statement); ok {
+			res = append(res, modified)
+		} else {
+			res = append(res, stmt)
+		}
+	}
+	return res
+}
+
+func modifyExpressions(exps []Expression, modifier ModifierFunc) []Expression {
+	if exps == nil {
+		return nil
+	}
+	res := make([]Expression, 0, len(exps))
+	for _, exp := range exps {
+		res = append(res, modifyExpression(exp, modifier))
+	}
+	return res
+}
+
+func modifyIdents(idents []*Ident, modifier ModifierFunc) []*Ident {
+	if idents == nil {
+		return nil
+	}
+	res := make([]*Ident, 0, len(idents))
+	for _, ident := range idents {
+		if modified, ok := Modify(ident, modifier).(*Ident); ok {
+			res = append(res, modified)
+		} else {
+			res = append(res, ident)
+		}
+	}
+	return res
+}
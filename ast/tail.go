@@ -0,0 +1,40 @@
+package ast
+
+// MarkTailCalls flags every call whose result is directly returned by fn, so
+// the engines can run it without growing the call stack.
+func MarkTailCalls(fn *FunctionLiteral) {
+	markTailBlock(fn.Body)
+}
+
+func markTailBlock(block *BlockStatement) {
+	if block == nil {
+		return
+	}
+	for i, stmt := range block.Statements {
+		switch stmt := stmt.(type) {
+		case *ReturnStatement:
+			markTail(stmt.Value)
+		case *ExpresssionStatement:
+			if i == len(block.Statements)-1 {
+				markTail(stmt.Expression)
+			}
+		}
+	}
+}
+
+func markTail(exp Expression) {
+	switch exp := exp.(type) {
+	case *CallExpression:
+		exp.Tail = true
+	case *IfExpression:
+		markTailBlock(exp.If)
+		markTailBlock(exp.Else)
+	case *TernaryExpression:
+		markTail(exp.Consequence)
+		markTail(exp.Alternative)
+	case *MatchExpression:
+		for _, arm := range exp.Arms {
+			markTailBlock(arm.Body)
+		}
+	}
+}
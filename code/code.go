@@ -37,6 +37,8 @@ func (ins Instructions) fmtInstruction(def *Definition, operands []int) string {
 		return def.Name
 	case 1:
 		return fmt.Sprintf("%s %d", def.Name, operands[0])
+	case 2:
+		return fmt.Sprintf("%s %d %d", def.Name, operands[0], operands[1])
 	}
 
 	return fmt.Sprintf("ERROR: unhandled operandCount for %s\n", def.Name)
@@ -72,6 +74,32 @@ const (
 	OpReturn
 	OpIndex
 	OpGetBuiltin
+	OpHash
+	OpSetIndex
+	OpJmpNull
+	OpJmpNotNull
+	OpDestructure
+	OpSlice
+	OpIterable
+	OpCompose
+	OpDefer
+	OpDeferEnd
+	OpIsVariant
+	OpNoMatch
+	OpTailCall
+	OpLen
+	OpClosure
+	OpCaptureLocal
+	OpCaptureFree
+	OpGetFree
+	OpSetFree
+	OpGreaterEqual
+	OpPow
+)
+
+const (
+	DestructureArray = iota
+	DestructureHash
 )
 
 type Definition struct {
@@ -106,7 +134,29 @@ var definitions = map[Opcode]*Definition{
 	OpCall:        {"OpCall", []int{1}},
 	OpReturn:      {"OpReturn", []int{}},
 	OpReturnValue: {"OpReturnValue", []int{}},
-	OpGetBuiltin: {"OpGetBuiltin", []int{1}},
+	OpGetBuiltin:  {"OpGetBuiltin", []int{2}},
+	OpHash:        {"OpHash", []int{2}},
+	OpSetIndex:    {"OpSetIndex", []int{}},
+	OpJmpNull:     {"OpJmpNull", []int{2}},
+	OpJmpNotNull:  {"OpJmpNotNull", []int{2}},
+	OpDestructure: {"OpDestructure", []int{1}},
+	OpSlice:       {"OpSlice", []int{2}},
+	OpIterable:    {"OpIterable", []int{}},
+	OpCompose:     {"OpCompose", []int{}},
+	OpDefer:       {"OpDefer", []int{2}},
+	OpDeferEnd:    {"OpDeferEnd", []int{}},
+	OpIsVariant:   {"OpIsVariant", []int{}},
+	OpNoMatch:     {"OpNoMatch", []int{}},
+	OpTailCall:    {"OpTailCall", []int{1}},
+	OpLen:         {"OpLen", []int{}},
+
+	OpClosure:      {"OpClosure", []int{2, 1}},
+	OpCaptureLocal: {"OpCaptureLocal", []int{1}},
+	OpCaptureFree:  {"OpCaptureFree", []int{1}},
+	OpGetFree:      {"OpGetFree", []int{1}},
+	OpSetFree:      {"OpSetFree", []int{1}},
+	OpGreaterEqual: {"OpGreaterEqual", []int{}},
+	OpPow:          {"OpPow", []int{}},
 }
 
 func Lookup(op byte) (*Definition, error) {
@@ -2,6 +2,8 @@ package compiler
 
 import (
 	"fmt"
+	"math"
+	"strings"
 
 	"github.com/Arch-4ng3l/Monkey/ast"
 	"github.com/Arch-4ng3l/Monkey/code"
@@ -19,11 +21,14 @@ type Compiler struct {
 	symbolTable *SymbolTable
 	scopes      []CompilationScope
 	scopeIdx    int
+
+	builtins *object.Registry
 }
 
 type Bytecode struct {
 	Instructions code.Instructions
 	Constants    []object.Object
+	Builtins     *object.Registry
 }
 
 type EmittedInstruction struct {
@@ -31,26 +36,50 @@ type EmittedInstruction struct {
 	Position int
 }
 
-func New() *Compiler {
+// New returns a Compiler for the builtins of r, or of a new default Registry
+// when r is nil.
+func New(r *object.Registry) *Compiler {
+	if r == nil {
+		r = object.NewDefaultRegistry()
+	}
 	mainScope := CompilationScope{
 		instructions:    code.Instructions{},
 		lastInstruction: EmittedInstruction{},
 		prevInstruction: EmittedInstruction{},
 	}
 	symbolTable := NewSymbolTable()
-	for i, v := range object.Builtins {
-		symbolTable.DefineBuiltin(i, v.Name)
-	}
+	DefineBuiltins(symbolTable, r)
 
 	return &Compiler{
 		constants:   []object.Object{},
 		symbolTable: symbolTable,
 		scopes:      []CompilationScope{mainScope},
 		scopeIdx:    0,
+		builtins:    r,
+	}
+}
+
+// DefineBuiltins defines the builtins of r that are not removed by a sandbox.
+// Builtins past the range of the OpGetBuiltin operand stay undefined.
+func DefineBuiltins(s *SymbolTable, r *object.Registry) {
+	for i := 0; i < r.Len() && i <= math.MaxUint16; i++ {
+		if r.At(i) != nil {
+			s.DefineBuiltin(i, r.Name(i))
+		}
+	}
+}
+
+// NewSandboxed returns a Compiler that only knows the builtins of r that p
+// allows.
+func NewSandboxed(r *object.Registry, p *object.Policy) *Compiler {
+	if r == nil {
+		r = object.NewDefaultRegistry()
 	}
+	return New(p.Apply(r))
 }
-func NewWithState(s *SymbolTable, constants []object.Object) *Compiler {
-	comp := New()
+
+func NewWithState(r *object.Registry, s *SymbolTable, constants []object.Object) *Compiler {
+	comp := New(r)
 	comp.symbolTable = s
 	comp.constants = constants
 	return comp
@@ -64,6 +93,19 @@ func (c *Compiler) loadSymbol(s Symbol) {
 		c.emit(code.OpGetLocal, s.Index)
 	case BuiltinScope:
 		c.emit(code.OpGetBuiltin, s.Index)
+	case FreeScope:
+		c.emit(code.OpGetFree, s.Index)
+	}
+}
+
+func (c *Compiler) storeSymbol(s Symbol) {
+	switch s.Scope {
+	case GlobalScope:
+		c.emit(code.OpSetGlobal, s.Index)
+	case FreeScope:
+		c.emit(code.OpSetFree, s.Index)
+	default:
+		c.emit(code.OpSetLocal, s.Index)
 	}
 }
 
@@ -84,17 +126,67 @@ func (c *Compiler) Compile(node ast.Node) error {
 		c.emit(code.OpPop)
 
 	case *ast.LetStatement:
+		if node.Pattern != nil {
+			err := c.Compile(node.Value)
+			if err != nil {
+				return err
+			}
+			return c.bindPattern(node.Pattern, node.IsConst())
+		}
+		if err := c.checkRedeclare(node.Name.Value, node.IsConst()); err != nil {
+			return err
+		}
+		define := c.symbolTable.Define
+		if node.IsConst() {
+			define = c.symbolTable.DefineConst
+		}
+
+		var symbol Symbol
+		_, recursive := node.Value.(*ast.FunctionLiteral)
+		if recursive {
+			symbol = define(node.Name.Value)
+		}
 		err := c.Compile(node.Value)
 		if err != nil {
 			return err
 		}
-		symbol := c.symbolTable.Define(node.Name.Value)
-		if symbol.Scope == GlobalScope {
-			c.emit(code.OpSetGlobal, symbol.Index)
-		} else {
-			c.emit(code.OpSetLocal, symbol.Index)
+		if !recursive {
+			symbol = define(node.Name.Value)
+		}
+		c.storeSymbol(symbol)
+
+	case *ast.ReasignExpression:
+		symbol, ok := c.symbolTable.Resolve(node.Var.Value)
+		if !ok {
+			return fmt.Errorf("undefined variable %s", node.Var.Value)
+		}
+		if symbol.Scope == BuiltinScope {
+			return fmt.Errorf("cant assign to builtin %s", node.Var.Value)
+		}
+		if symbol.Constant {
+			return fmt.Errorf("cant assign to constant %s", node.Var.Value)
 		}
 
+		if node.Operator != "=" {
+			c.loadSymbol(symbol)
+		}
+		err := c.Compile(node.Value)
+		if err != nil {
+			return err
+		}
+		switch node.Operator {
+		case "+=":
+			c.emit(code.OpAdd)
+		case "-=":
+			c.emit(code.OpSub)
+		case "*=":
+			c.emit(code.OpMul)
+		case "/=":
+			c.emit(code.OpDiv)
+		}
+		c.storeSymbol(symbol)
+		c.loadSymbol(symbol)
+
 	case *ast.IfExpression:
 		err := c.Compile(node.Condition)
 		if err != nil {
@@ -143,7 +235,50 @@ func (c *Compiler) Compile(node ast.Node) error {
 				return err
 			}
 		}
-		c.emit(code.OpCall, len(node.Args))
+		if node.Tail && c.scopeIdx > 0 {
+			c.emit(code.OpTailCall, len(node.Args))
+		} else {
+			c.emit(code.OpCall, len(node.Args))
+		}
+
+	case *ast.EnumStatement:
+		enum := &object.Enum{
+			Name:  node.Name.Value,
+			Arity: make(map[string]int),
+		}
+		for _, v := range node.Variants {
+			if enum.HasVariant(v.Name.Value) {
+				return fmt.Errorf("variant %s declared twice in %s", v.Name.Value, enum.Name)
+			}
+			enum.Variants = append(enum.Variants, v.Name.Value)
+			enum.Arity[v.Name.Value] = len(v.Params)
+		}
+
+		for i, member := range object.NewEnumMembers(enum) {
+			c.emit(code.OpConstant, c.addConstant(member))
+			c.storeSymbol(c.symbolTable.DefineVariant(enum.Variants[i], member))
+		}
+		c.emit(code.OpConstant, c.addConstant(enum))
+		c.storeSymbol(c.symbolTable.Define(enum.Name))
+
+	case *ast.MatchExpression:
+		return c.compileMatch(node)
+
+	case *ast.DeferStatement:
+		if c.scopeIdx == 0 {
+			return fmt.Errorf("cant defer outside of a function")
+		}
+		deferPos := c.emit(code.OpDefer, 9999)
+		err := c.Compile(node.Call)
+		if err != nil {
+			return err
+		}
+		c.emit(code.OpPop)
+		c.emit(code.OpDeferEnd)
+		c.changeOperand(deferPos, len(c.currentInstructions()))
+
+		c.emit(code.OpNull)
+		c.emit(code.OpPop)
 
 	case *ast.ReturnStatement:
 		err := c.Compile(node.Value)
@@ -155,8 +290,19 @@ func (c *Compiler) Compile(node ast.Node) error {
 
 	case *ast.FunctionLiteral:
 		c.enterScope()
+		params := []Symbol{}
 		for _, p := range node.Params {
-			c.symbolTable.Define(p.Value)
+			params = append(params, c.symbolTable.Define(p.Value))
+		}
+		for i, pattern := range node.ParamPatterns {
+			if pattern == nil {
+				continue
+			}
+			c.loadSymbol(params[i])
+			err := c.bindPattern(pattern, false)
+			if err != nil {
+				return err
+			}
 		}
 
 		err := c.Compile(node.Body)
@@ -173,13 +319,120 @@ func (c *Compiler) Compile(node ast.Node) error {
 		}
 
 		num := c.symbolTable.len
+		free := c.symbolTable.FreeSymbols
 		ins := c.leaveScope()
 		compiledFn := &object.CompiledFunction{
+			Name:         node.Name,
 			Instructions: ins,
 			NumLocals:    num,
 			NumParams:    len(node.Params),
 		}
-		c.emit(code.OpConstant, c.addConstant(compiledFn))
+		if len(free) == 0 {
+			c.emit(code.OpConstant, c.addConstant(compiledFn))
+			return nil
+		}
+
+		// Captured variables are shared with the enclosing function, so the
+		// closure gets their cells instead of their current values.
+		for _, s := range free {
+			if s.Scope == FreeScope {
+				c.emit(code.OpCaptureFree, s.Index)
+			} else {
+				c.emit(code.OpCaptureLocal, s.Index)
+			}
+		}
+		c.emit(code.OpClosure, c.addConstant(compiledFn), len(free))
+
+	case *ast.WhileLoop:
+		loopStart := len(c.currentInstructions())
+		err := c.Compile(node.LoopCond)
+		if err != nil {
+			return err
+		}
+		jmpNotTrue := c.emit(code.OpJmpNotTrue, 9999)
+
+		err = c.Compile(node.Body)
+		if err != nil {
+			return err
+		}
+		c.emit(code.OpJmp, loopStart)
+
+		c.changeOperand(jmpNotTrue, len(c.currentInstructions()))
+		c.emit(code.OpNull)
+
+	case *ast.ForLoop:
+		if node.LoopVar != nil {
+			err := c.Compile(node.LoopVar)
+			if err != nil {
+				return err
+			}
+		}
+
+		loopStart := len(c.currentInstructions())
+		err := c.Compile(node.LoopCond)
+		if err != nil {
+			return err
+		}
+		jmpNotTrue := c.emit(code.OpJmpNotTrue, 9999)
+
+		err = c.Compile(node.Body)
+		if err != nil {
+			return err
+		}
+		err = c.Compile(node.PostLoop)
+		if err != nil {
+			return err
+		}
+		c.emit(code.OpPop)
+		c.emit(code.OpJmp, loopStart)
+
+		c.changeOperand(jmpNotTrue, len(c.currentInstructions()))
+		c.emit(code.OpNull)
+
+	case *ast.ForInLoop:
+		err := c.Compile(node.Iterable)
+		if err != nil {
+			return err
+		}
+		c.emit(code.OpIterable)
+		items := c.hiddenSymbol()
+		c.storeSymbol(items)
+
+		c.emit(code.OpConstant, c.addConstant(&object.Integer{Value: 0}))
+		idx := c.hiddenSymbol()
+		c.storeSymbol(idx)
+
+		loopStart := len(c.currentInstructions())
+		c.loadSymbol(items)
+		c.emit(code.OpLen)
+		c.loadSymbol(idx)
+		c.emit(code.OpGreaterThan)
+		jmpNotTrue := c.emit(code.OpJmpNotTrue, 9999)
+
+		c.loadSymbol(items)
+		c.loadSymbol(idx)
+		c.emit(code.OpIndex)
+		err = c.bindPattern(node.Pattern, false)
+		if err != nil {
+			return err
+		}
+
+		err = c.Compile(node.Body)
+		if err != nil {
+			return err
+		}
+
+		c.loadSymbol(idx)
+		c.emit(code.OpConstant, c.addConstant(&object.Integer{Value: 1}))
+		c.emit(code.OpAdd)
+		c.storeSymbol(idx)
+		c.emit(code.OpJmp, loopStart)
+
+		c.changeOperand(jmpNotTrue, len(c.currentInstructions()))
+		c.emit(code.OpNull)
+
+	case *ast.MacroLiteral:
+		return fmt.Errorf("macros have to be defined with var at the top level")
 
 	case *ast.BlockStatement:
 		for i := range node.Statements {
@@ -189,8 +442,44 @@ func (c *Compiler) Compile(node ast.Node) error {
 			}
 		}
 
+	case *ast.TernaryExpression:
+		err := c.Compile(node.Condition)
+		if err != nil {
+			return err
+		}
+		jmpNotTrue := c.emit(code.OpJmpNotTrue, 9999)
+
+		err = c.Compile(node.Consequence)
+		if err != nil {
+			return err
+		}
+		jmp := c.emit(code.OpJmp, 9999)
+		c.changeOperand(jmpNotTrue, len(c.currentInstructions()))
+
+		err = c.Compile(node.Alternative)
+		if err != nil {
+			return err
+		}
+		c.changeOperand(jmp, len(c.currentInstructions()))
+
 	case *ast.InfixExpression:
-		if node.Operator == "<" {
+		if node.Operator == "??" {
+			err := c.Compile(node.Left)
+			if err != nil {
+				return err
+			}
+			jmpNotNull := c.emit(code.OpJmpNotNull, 9999)
+			c.emit(code.OpPop)
+
+			err = c.Compile(node.Right)
+			if err != nil {
+				return err
+			}
+			c.changeOperand(jmpNotNull, len(c.currentInstructions()))
+			return nil
+		}
+
+		if node.Operator == "<" || node.Operator == "<=" {
 			err := c.Compile(node.Right)
 			if err != nil {
 				return err
@@ -200,7 +489,11 @@ func (c *Compiler) Compile(node ast.Node) error {
 			if err != nil {
 				return err
 			}
-			c.emit(code.OpGreaterThan)
+			if node.Operator == "<" {
+				c.emit(code.OpGreaterThan)
+			} else {
+				c.emit(code.OpGreaterEqual)
+			}
 			return nil
 		}
 
@@ -223,15 +516,33 @@ func (c *Compiler) Compile(node ast.Node) error {
 			c.emit(code.OpMul)
 		case "/":
 			c.emit(code.OpDiv)
+		case "^":
+			c.emit(code.OpPow)
 		case ">":
 			c.emit(code.OpGreaterThan)
+		case ">=":
+			c.emit(code.OpGreaterEqual)
 		case "==":
 			c.emit(code.OpEqual)
 		case "!=":
 			c.emit(code.OpNotEqual)
+		case ">>":
+			c.emit(code.OpCompose)
+		default:
+			return fmt.Errorf("unknown operator %s", node.Operator)
 		}
 
 	case *ast.IndexExpression:
+		jmpsNull := []int{}
+		err := c.compileIndexChain(node, &jmpsNull)
+		if err != nil {
+			return err
+		}
+		for _, jmp := range jmpsNull {
+			c.changeOperand(jmp, len(c.currentInstructions()))
+		}
+
+	case *ast.IndexAssignExpression:
 		err := c.Compile(node.Left)
 		if err != nil {
 			return err
@@ -241,7 +552,26 @@ func (c *Compiler) Compile(node ast.Node) error {
 		if err != nil {
 			return err
 		}
-		c.emit(code.OpIndex)
+
+		err = c.Compile(node.Value)
+		if err != nil {
+			return err
+		}
+		c.emit(code.OpSetIndex)
+
+	case *ast.HashLiteral:
+		for i := range node.Keys {
+			err := c.Compile(node.Keys[i])
+			if err != nil {
+				return err
+			}
+
+			err = c.Compile(node.Values[i])
+			if err != nil {
+				return err
+			}
+		}
+		c.emit(code.OpHash, len(node.Keys)*2)
 
 	case *ast.PrefixExpression:
 		err := c.Compile(node.Right)
@@ -259,6 +589,9 @@ func (c *Compiler) Compile(node ast.Node) error {
 
 	case *ast.Ident:
 		symbol, ok := c.symbolTable.Resolve(node.Value)
+		if !ok && c.builtins.Index(node.Value) >= 0 {
+			return fmt.Errorf("builtin %s is not allowed in the sandbox", node.Value)
+		}
 		if !ok {
 			return fmt.Errorf("undefined variable %s", node.Value)
 		}
@@ -277,21 +610,229 @@ func (c *Compiler) Compile(node ast.Node) error {
 		integer := &object.Integer{Value: int(node.Value)}
 		c.emit(code.OpConstant, c.addConstant(integer))
 
+	case *ast.FloatLiteral:
+		float := &object.Float{Value: node.Value}
+		c.emit(code.OpConstant, c.addConstant(float))
+
 	case *ast.StrLiteral:
 		str := &object.String{Value: node.Value}
 		c.emit(code.OpConstant, c.addConstant(str))
 
+	case *ast.RegexLiteral:
+		re, ok := object.NewRegex(node.Value).(*object.Regex)
+		if !ok {
+			return fmt.Errorf("invalid regex /%s/", node.Value)
+		}
+		c.emit(code.OpConstant, c.addConstant(re))
+
 	case *ast.Boolean:
 		if node.Value {
 			c.emit(code.OpTrue)
 		} else {
 			c.emit(code.OpFalse)
 		}
+
+	default:
+		return fmt.Errorf("cant compile %T", node)
+	}
+
+	return nil
+}
+
+// bindPattern stores the value on top of the stack into the variables named by
+// pattern, going through hidden symbols for nested arrays and hashes.
+func (c *Compiler) bindPattern(pattern ast.Expression, constant bool) error {
+	switch pattern := pattern.(type) {
+	case *ast.Ident:
+		if err := c.checkRedeclare(pattern.Value, constant); err != nil {
+			return err
+		}
+		define := c.symbolTable.Define
+		if constant {
+			define = c.symbolTable.DefineConst
+		}
+		c.storeSymbol(define(pattern.Value))
+
+	case *ast.ArrayPattern:
+		c.emit(code.OpDestructure, code.DestructureArray)
+		value := c.hiddenSymbol()
+		c.storeSymbol(value)
+
+		for i, el := range pattern.Elements {
+			c.loadSymbol(value)
+			c.emit(code.OpConstant, c.addConstant(&object.Integer{Value: i}))
+			c.emit(code.OpIndex)
+			err := c.bindPattern(el, constant)
+			if err != nil {
+				return err
+			}
+		}
+		if pattern.Rest != nil {
+			c.loadSymbol(value)
+			c.emit(code.OpSlice, len(pattern.Elements))
+			return c.bindPattern(pattern.Rest, constant)
+		}
+
+	case *ast.HashPattern:
+		c.emit(code.OpDestructure, code.DestructureHash)
+		value := c.hiddenSymbol()
+		c.storeSymbol(value)
+
+		for i, key := range pattern.Keys {
+			c.loadSymbol(value)
+			c.emit(code.OpConstant, c.addConstant(&object.String{Value: key}))
+			c.emit(code.OpIndex)
+			err := c.bindPattern(pattern.Values[i], constant)
+			if err != nil {
+				return err
+			}
+		}
+
+	default:
+		return fmt.Errorf("cant destructure into %s", pattern.String())
 	}
 
 	return nil
 }
 
+// compileIndexChain compiles node and the index expressions it indexes into.
+// Optional indexes add their jump on a null receiver to jmpsNull, which skips
+// the rest of the chain, so a?[0][1] is null when a is.
+func (c *Compiler) compileIndexChain(node *ast.IndexExpression, jmpsNull *[]int) error {
+	var err error
+	if inner, ok := node.Left.(*ast.IndexExpression); ok {
+		err = c.compileIndexChain(inner, jmpsNull)
+	} else {
+		err = c.Compile(node.Left)
+	}
+	if err != nil {
+		return err
+	}
+
+	if node.Optional {
+		*jmpsNull = append(*jmpsNull, c.emit(code.OpJmpNull, 9999))
+	}
+
+	err = c.Compile(node.Index)
+	if err != nil {
+		return err
+	}
+	c.emit(code.OpIndex)
+	return nil
+}
+
+// checkRedeclare rejects declaring a constant of the current scope again and
+// turning a variable of the current scope into a constant, like the evaluator.
+func (c *Compiler) checkRedeclare(name string, constant bool) error {
+	s, ok := c.symbolTable.store[name]
+	if !ok || s.Scope == FreeScope || s.Scope == BuiltinScope {
+		return nil
+	}
+	if s.Constant {
+		return fmt.Errorf("cant redeclare constant %s", name)
+	}
+	if constant {
+		return fmt.Errorf("cant redeclare %s as constant", name)
+	}
+	return nil
+}
+
+func (c *Compiler) compileMatch(node *ast.MatchExpression) error {
+	var enum *object.Enum
+	covered := map[string]bool{}
+	wildcard := false
+	arms := make([]Symbol, len(node.Arms))
+
+	for i, arm := range node.Arms {
+		if arm.IsWildcard() {
+			wildcard = true
+			continue
+		}
+		symbol, ok := c.symbolTable.Resolve(arm.Variant.Value)
+		if !ok {
+			return fmt.Errorf("undefined variable %s", arm.Variant.Value)
+		}
+		e, name, ok := object.VariantOf(symbol.Variant)
+		if !ok {
+			return fmt.Errorf("%s is not an enum variant", arm.Variant.Value)
+		}
+		if enum != nil && e.Name != enum.Name {
+			return fmt.Errorf("cant match %s and %s in the same match", enum.Name, e.Name)
+		}
+		if len(arm.Bindings) != e.Arity[name] {
+			return fmt.Errorf("%s: want %d bindings got %d", arm.Variant.Value, e.Arity[name], len(arm.Bindings))
+		}
+		enum = e
+		covered[name] = true
+		arms[i] = symbol
+	}
+
+	if enum != nil && !wildcard {
+		if missing := enum.Missing(covered); len(missing) > 0 {
+			return fmt.Errorf("match on %s is not exhaustive missing %s", enum.Name, strings.Join(missing, ", "))
+		}
+	}
+
+	err := c.Compile(node.Subject)
+	if err != nil {
+		return err
+	}
+	subject := c.hiddenSymbol()
+	c.storeSymbol(subject)
+
+	jmpsToEnd := []int{}
+	for i, arm := range node.Arms {
+		jmpNotTrue := -1
+		if !arm.IsWildcard() {
+			c.loadSymbol(subject)
+			c.loadSymbol(arms[i])
+			c.emit(code.OpIsVariant)
+			jmpNotTrue = c.emit(code.OpJmpNotTrue, 9999)
+
+			for j, b := range arm.Bindings {
+				c.loadSymbol(subject)
+				c.emit(code.OpConstant, c.addConstant(&object.Integer{Value: j}))
+				c.emit(code.OpIndex)
+				c.storeSymbol(c.symbolTable.Define(b.Value))
+			}
+		}
+
+		err := c.Compile(arm.Body)
+		if err != nil {
+			return err
+		}
+		if c.lastInstructionIsPop() {
+			c.removeLastPop()
+		} else {
+			c.emit(code.OpNull)
+		}
+		jmpsToEnd = append(jmpsToEnd, c.emit(code.OpJmp, 9999))
+
+		if jmpNotTrue == -1 {
+			break
+		}
+		c.changeOperand(jmpNotTrue, len(c.currentInstructions()))
+	}
+
+	if !wildcard {
+		c.loadSymbol(subject)
+		c.emit(code.OpNoMatch)
+	}
+
+	for _, jmp := range jmpsToEnd {
+		c.changeOperand(jmp, len(c.currentInstructions()))
+	}
+
+	return nil
+}
+
+// hiddenSymbol reserves a slot that no identifier can resolve to.
+func (c *Compiler) hiddenSymbol() Symbol {
+	symbol := c.symbolTable.Define("")
+	delete(c.symbolTable.store, "")
+	return symbol
+}
+
 func (c *Compiler) replaceLastPopWithReturn() {
 	lastPos := c.scopes[c.scopeIdx].lastInstruction.Position
 	c.replaceInstruction(lastPos, code.Make(code.OpReturnValue))
@@ -392,6 +933,7 @@ func (c *Compiler) Bytecode() *Bytecode {
 	return &Bytecode{
 		Instructions: c.currentInstructions(),
 		Constants:    c.constants,
+		Builtins:     c.builtins,
 	}
 
 }
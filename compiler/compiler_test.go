@@ -24,7 +24,7 @@ func parse(input string) *ast.Program {
 }
 
 func TestCompilerScopes(t *testing.T) {
-	compiler := New()
+	compiler := New(nil)
 	if compiler.scopeIdx != 0 {
 		t.Errorf("scopeIdx wrong want 0 got %d", compiler.scopeIdx)
 	}
@@ -181,6 +181,67 @@ func TestFunctionLiterals(t *testing.T) {
 	}
 	runCompilerTest(t, tests)
 }
+
+func TestClosures(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input: `func(a) { func(b) { func(c) { a + b + c } } };`,
+			expectedConstants: []interface{}{
+				[]code.Instructions{
+					code.Make(code.OpGetFree, 0),
+					code.Make(code.OpGetFree, 1),
+					code.Make(code.OpAdd),
+					code.Make(code.OpGetLocal, 0),
+					code.Make(code.OpAdd),
+					code.Make(code.OpReturnValue),
+				},
+				[]code.Instructions{
+					code.Make(code.OpCaptureFree, 0),
+					code.Make(code.OpCaptureLocal, 0),
+					code.Make(code.OpClosure, 0, 2),
+					code.Make(code.OpReturnValue),
+				},
+				[]code.Instructions{
+					code.Make(code.OpCaptureLocal, 0),
+					code.Make(code.OpClosure, 1, 1),
+					code.Make(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 2),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input: `func() { var n = 0; func() { n = n + 1 } };`,
+			expectedConstants: []interface{}{
+				0,
+				1,
+				[]code.Instructions{
+					code.Make(code.OpGetFree, 0),
+					code.Make(code.OpConstant, 1),
+					code.Make(code.OpAdd),
+					code.Make(code.OpSetFree, 0),
+					code.Make(code.OpGetFree, 0),
+					code.Make(code.OpReturnValue),
+				},
+				[]code.Instructions{
+					code.Make(code.OpConstant, 0),
+					code.Make(code.OpSetLocal, 0),
+					code.Make(code.OpCaptureLocal, 0),
+					code.Make(code.OpClosure, 2, 1),
+					code.Make(code.OpReturnValue),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 3),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+	runCompilerTest(t, tests)
+}
+
 func TestArrayLiterals(t *testing.T) {
 	tests := []compilerTestCase{
 		{
@@ -260,6 +321,29 @@ func TestStringExpression(t *testing.T) {
 	runCompilerTest(t, tests)
 }
 
+func TestFloatLiterals(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             `var a = 2.5; a`,
+			expectedConstants: []interface{}{2.5},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConstant, 0),
+				code.Make(code.OpSetGlobal, 0),
+				code.Make(code.OpGetGlobal, 0),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+	runCompilerTest(t, tests)
+}
+
+func TestUnknownNode(t *testing.T) {
+	err := New(nil).Compile(&ast.ArrayPattern{})
+	if err == nil || err.Error() != "cant compile *ast.ArrayPattern" {
+		t.Errorf("expected an error for an unknown node got %v", err)
+	}
+}
+
 func TestVariableStatements(t *testing.T) {
 	tests := []compilerTestCase{
 		{
@@ -477,7 +561,7 @@ func runCompilerTest(t *testing.T, tests []compilerTestCase) {
 
 	for _, tt := range tests {
 		program := parse(tt.input)
-		compiler := New()
+		compiler := New(nil)
 		err := compiler.Compile(program)
 		if err != nil {
 			t.Fatalf("%s", err.Error())
@@ -516,6 +600,11 @@ func testConstants(t *testing.T, expected []interface{}, actual []object.Object)
 			if err != nil {
 				return fmt.Errorf("constant %d -testIntegerObject failed %s", i, err)
 			}
+		case float64:
+			result, ok := actual[i].(*object.Float)
+			if !ok || result.Value != constant {
+				return fmt.Errorf("constant %d is not the Float %f got %s", i, constant, actual[i].Inspect())
+			}
 		case string:
 			err := testStringObject(constant, actual[i])
 			if err != nil {
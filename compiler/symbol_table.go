@@ -1,23 +1,32 @@
 package compiler
 
+import "github.com/Arch-4ng3l/Monkey/object"
+
 type SymbolScope string
 
 const (
 	GlobalScope  SymbolScope = "GLOBAL"
 	BuiltinScope SymbolScope = "BUILTIN"
 	LocalScope   SymbolScope = "LOCAL"
+	FreeScope    SymbolScope = "FREE"
 )
 
 type Symbol struct {
-	Name  string
-	Scope SymbolScope
-	Index int
+	Name     string
+	Scope    SymbolScope
+	Index    int
+	Constant bool
+	Variant  object.Object
 }
 
 type SymbolTable struct {
 	Outer *SymbolTable
 	store map[string]Symbol
 	len   int
+
+	// FreeSymbols are the symbols of the enclosing functions this one
+	// captures, in the order of their FreeScope indexes.
+	FreeSymbols []Symbol
 }
 
 func NewSymbolTable() *SymbolTable {
@@ -45,15 +54,46 @@ func (s *SymbolTable) Define(name string) Symbol {
 	return symbol
 }
 
+func (s *SymbolTable) DefineConst(name string) Symbol {
+	symbol := s.Define(name)
+	symbol.Constant = true
+	s.store[name] = symbol
+
+	return symbol
+}
+
+func (s *SymbolTable) DefineVariant(name string, variant object.Object) Symbol {
+	symbol := s.Define(name)
+	symbol.Variant = variant
+	s.store[name] = symbol
+
+	return symbol
+}
+
 func (s *SymbolTable) Resolve(name string) (Symbol, bool) {
 	obj, ok := s.store[name]
 	if !ok && s.Outer != nil {
 		obj, ok = s.Outer.Resolve(name)
-		return obj, ok
+		if !ok || obj.Scope == GlobalScope || obj.Scope == BuiltinScope {
+			return obj, ok
+		}
+		return s.defineFree(obj), true
 	}
 	return obj, ok
 }
 
+// defineFree makes a local of an enclosing function available to this one.
+func (s *SymbolTable) defineFree(original Symbol) Symbol {
+	s.FreeSymbols = append(s.FreeSymbols, original)
+
+	symbol := original
+	symbol.Scope = FreeScope
+	symbol.Index = len(s.FreeSymbols) - 1
+	s.store[original.Name] = symbol
+
+	return symbol
+}
+
 func (s *SymbolTable) DefineBuiltin(idx int, name string) Symbol {
 	symbol := Symbol{Name: name, Index: idx, Scope: BuiltinScope}
 	s.store[name] = symbol
@@ -1,7 +1,9 @@
 package eval
 
 import (
+	"context"
 	"fmt"
+	"time"
 
 	"github.com/Arch-4ng3l/Monkey/ast"
 	"github.com/Arch-4ng3l/Monkey/object"
@@ -9,78 +11,157 @@ import (
 )
 
 var (
-	TRUE  = &object.Boolean{Value: true}
-	FALSE = &object.Boolean{Value: false}
+	TRUE  = object.TrueVal
+	FALSE = object.FalseVal
 	NULL  = object.NullVal
 )
-var builtins map[string]*object.BuiltIn
-
-func Init() {
-	builtins = map[string]*object.BuiltIn{
-		"len":   object.GetBuiltIntBuName("len"),
-		"print": object.GetBuiltIntBuName("print"),
-
-		// =============================================================//
-		"sin":  object.GetBuiltIntBuName("sin"),
-		"cos":  object.GetBuiltIntBuName("cos"),
-		"tan":  object.GetBuiltIntBuName("tan"),
-		"cot":  object.GetBuiltIntBuName("cot"),
-		"sec":  object.GetBuiltIntBuName("sec"),
-		"csc":  object.GetBuiltIntBuName("csc"),
-		"ln":   object.GetBuiltIntBuName("ln"),
-		"log":  object.GetBuiltIntBuName("log"),
-		"atan": object.GetBuiltIntBuName("atan"),
-		"asin": object.GetBuiltIntBuName("asin"),
-		"acos": object.GetBuiltIntBuName("acos"),
-		"acot": object.GetBuiltIntBuName("acot"),
-		"asec": object.GetBuiltIntBuName("asec"),
-		"acsc": object.GetBuiltIntBuName("acsc"),
-		// =============================================================//
-
-		"push":         object.GetBuiltIntBuName("push"),
-		"sort":         object.GetBuiltIntBuName("sort"),
-		"typeof":       object.GetBuiltIntBuName("typeof"),
-		"randInt":      object.GetBuiltIntBuName("randInt"),
-		"randIntArray": object.GetBuiltIntBuName("randIntArray"),
-		"toStr":        object.GetBuiltIntBuName("toStr"),
-		"toInt":        object.GetBuiltIntBuName("toInt"),
+
+// MaxCallDepth limits how many user functions can be active at once before a
+// call fails with a stack overflow error instead of exhausting the Go stack.
+var MaxCallDepth = 10000
+
+// MaxSteps and Timeout bound a run started with EvalContext. Zero means no
+// limit.
+var (
+	MaxSteps int
+	Timeout  time.Duration
+)
+
+// Evaluator is one tree-walking interpreter. Evaluators never share their
+// builtins or the state of a run, so several can be used at the same time.
+type Evaluator struct {
+	Builtins *object.Registry
+
+	// MaxCallDepth, MaxSteps and Timeout start out as the package defaults.
+	MaxCallDepth int
+	MaxSteps     int
+	Timeout      time.Duration
+
+	sandbox   *object.Policy
+	callChain []string
+	budget    *object.Budget
+}
+
+// New returns an Evaluator for r, or for a new default Registry when r is
+// nil.
+func New(r *object.Registry) *Evaluator {
+	if r == nil {
+		r = object.NewDefaultRegistry()
 	}
+	return &Evaluator{
+		Builtins:     r,
+		MaxCallDepth: MaxCallDepth,
+		MaxSteps:     MaxSteps,
+		Timeout:      Timeout,
+	}
+}
+
+// NewSandboxed returns an Evaluator that only sees the builtins of r that p
+// allows and fails once a value or the output grows past the limits of p.
+func NewSandboxed(r *object.Registry, p *object.Policy) *Evaluator {
+	if r == nil {
+		r = object.NewDefaultRegistry()
+	}
+	e := New(p.Apply(r))
+	e.sandbox = p
+	return e
 }
 
+// Eval evaluates node with a new Evaluator.
 func Eval(node ast.Node, env *object.Env) object.Object {
+	return New(nil).Eval(node, env)
+}
+
+// EvalContext evaluates node with a new Evaluator, see Evaluator.EvalContext.
+func EvalContext(ctx context.Context, node ast.Node, env *object.Env) object.Object {
+	return New(nil).EvalContext(ctx, node, env)
+}
+
+// EvalContext is like Eval but stops with a *object.LimitError as the Cause
+// of the returned error once ctx is done or MaxSteps or Timeout is exceeded.
+func (e *Evaluator) EvalContext(ctx context.Context, node ast.Node, env *object.Env) object.Object {
+	defer func(outer *object.Budget) { e.budget = outer }(e.budget)
+	steps := e.MaxSteps
+	if e.sandbox != nil {
+		steps = e.sandbox.Steps(steps)
+	}
+	e.budget = object.NewBudget(ctx, steps, e.Timeout)
+	if err := e.budget.Check(); err != nil {
+		return errorFrom(err)
+	}
+	return e.Eval(node, env)
+}
+
+func (e *Evaluator) Eval(node ast.Node, env *object.Env) object.Object {
+	if e.budget != nil {
+		if err := e.budget.Step(); err != nil {
+			return errorFrom(err)
+		}
+	}
+
+	res := e.evalNode(node, env)
+	if e.sandbox != nil && res != nil {
+		if err := e.sandbox.CheckSize(res); err != nil {
+			return errorFrom(err)
+		}
+	}
+	return res
+}
+
+// Apply calls fn with args the way a call in a program would.
+func (e *Evaluator) Apply(fn object.Object, args ...object.Object) object.Object {
+	return unwrapReturnValue(e.applyFunction(fn, args))
+}
+
+// Call implements object.Caller, a failed call returns its *object.Error.
+func (e *Evaluator) Call(fn object.Object, args ...object.Object) (object.Object, error) {
+	res := e.Apply(fn, args...)
+	if err, ok := res.(*object.Error); ok {
+		return nil, err
+	}
+	return res, nil
+}
 
+func (e *Evaluator) evalNode(node ast.Node, env *object.Env) object.Object {
 	switch node := node.(type) {
 
 	case *ast.Program:
-		Init()
-		return evalProgram(node, env)
+		return e.evalProgram(node, env)
 
 	case *ast.ExpresssionStatement:
 
-		return Eval(node.Expression, env)
+		return e.Eval(node.Expression, env)
 
 	case *ast.BlockStatement:
 
-		return evalBlockStatement(node, env)
+		return e.evalBlockStatement(node, env)
 
 	case *ast.ForLoop:
-		return evalForLoop(node, env)
+		return e.evalForLoop(node, env)
+
+	case *ast.ForInLoop:
+		return e.evalForInLoop(node, env)
 
 	case *ast.WhileLoop:
-		return evalWhileLoop(node, env)
+		return e.evalWhileLoop(node, env)
 
 	case *ast.LetStatement:
-		val := Eval(node.Value, env)
+		val := e.Eval(node.Value, env)
 		if isError(val) {
 
 			return val
 		}
-		env.Set(node.Name.Value, val)
+		if node.Pattern != nil {
+			return e.bindPattern(node.Pattern, val, env, node.IsConst())
+		}
+		if node.IsConst() {
+			return env.SetConst(node.Name.Value, val)
+		}
 
-		return val
+		return env.Set(node.Name.Value, val)
 
 	case *ast.ReturnStatement:
-		val := Eval(node.Value, env)
+		val := e.Eval(node.Value, env)
 
 		if isError(val) {
 
@@ -91,54 +172,91 @@ func Eval(node ast.Node, env *object.Env) object.Object {
 			Value: val,
 		}
 	case *ast.ReasignExpression:
-		return evalReasignExpression(node, env)
+		return e.evalReasignExpression(node, env)
+
+	case *ast.EnumStatement:
+		return e.evalEnumStatement(node, env)
+
+	case *ast.MatchExpression:
+		return e.evalMatchExpression(node, env)
+
+	case *ast.DeferStatement:
+		if env.IsTopLevel() {
+			return newError("Cant defer outside of a Function")
+		}
+		env.Defer(node.Call)
+		return NULL
 
 	case *ast.IfExpression:
-		return evalIfExpression(node, env)
+		return e.evalIfExpression(node, env)
 
 	case *ast.CallExpression:
-		function := Eval(node.Function, env)
+		if node.Function.TokenLiteral() == "quote" && len(node.Args) == 1 {
+			return e.quote(node.Args[0], env)
+		}
+		function := e.Eval(node.Function, env)
 		if isError(function) {
 
 			return function
 		}
 
-		args := evalExpressions(node.Args, env)
+		args := e.evalExpressions(node.Args, env)
 		if len(args) == 1 && isError(args[0]) {
 
 			return args[0]
 		}
 
-		return applyFunction(function, args)
+		if node.Tail {
+			return &object.TailCall{Fn: function, Args: args}
+		}
+
+		return e.applyFunction(function, args)
 
 	case *ast.FunctionLiteral:
 		params := node.Params
 		body := node.Body
 
 		return &object.Function{
+			Name:     node.Name,
+			Env:      env,
+			Params:   params,
+			Patterns: node.ParamPatterns,
+			Body:     body,
+		}
+
+	case *ast.MacroLiteral:
+		return &object.Macro{
 			Env:    env,
-			Params: params,
-			Body:   body,
+			Params: node.Params,
+			Body:   node.Body,
 		}
 
-	case *ast.IndexExpression:
-		left := Eval(node.Left, env)
-		if isError(left) {
-			return left
+	case *ast.TernaryExpression:
+		condition := e.Eval(node.Condition, env)
+		if isError(condition) {
+			return condition
 		}
-		index := Eval(node.Index, env)
-		if isError(index) {
-			return index
+		if isTruthy(condition) {
+			return e.Eval(node.Consequence, env)
 		}
+		return e.Eval(node.Alternative, env)
+
+	case *ast.IndexExpression:
+		res, _ := e.evalIndexChain(node, env)
+		return res
+
+	case *ast.IndexAssignExpression:
+		return e.evalIndexAssignExpression(node, env)
 
-		return evalIndexExpression(left, index)
+	case *ast.HashLiteral:
+		return e.evalHashLiteral(node, env)
 
 	case *ast.Ident:
 
-		return evalIdent(node, env)
+		return e.evalIdent(node, env)
 
 	case *ast.ArrayLiteral:
-		elements := evalExpressions(node.Elements, env)
+		elements := e.evalExpressions(node.Elements, env)
 		if len(elements) == 1 && isError(elements[0]) {
 			return elements[0]
 		}
@@ -151,6 +269,8 @@ func Eval(node ast.Node, env *object.Env) object.Object {
 		return &object.String{
 			Value: node.Value,
 		}
+	case *ast.RegexLiteral:
+		return object.NewRegex(node.Value)
 	case *ast.FloatLiteral:
 		return &object.Float{
 			Value: node.Value,
@@ -166,72 +286,274 @@ func Eval(node ast.Node, env *object.Env) object.Object {
 		return boolToBoolObj(node.Value)
 
 	case *ast.PrefixExpression:
-		right := Eval(node.Right, env)
+		right := e.Eval(node.Right, env)
 		if isError(right) {
 
 			return right
 		}
 
-		return evalPrefixExpression(right, node.Operator)
+		return e.evalPrefixExpression(right, node.Operator)
 
 	case *ast.InfixExpression:
-		left := Eval(node.Left, env)
+		left := e.Eval(node.Left, env)
 		if isError(left) {
 
 			return left
 		}
 
-		right := Eval(node.Right, env)
+		if node.Operator == token.COALESCE {
+			if left != NULL {
+				return left
+			}
+			return e.Eval(node.Right, env)
+		}
+
+		right := e.Eval(node.Right, env)
 		if isError(right) {
 
 			return right
 		}
 
-		return evalInfixExpression(node.Operator, left, right)
+		return e.evalInfixExpression(node.Operator, left, right)
 
 	}
 
 	return nil
 }
 
-func evalWhileLoop(node *ast.WhileLoop, env *object.Env) object.Object {
-	cond, ok := Eval(node.LoopCond, env).(*object.Boolean)
-	if !ok {
-		return newError("")
+func (e *Evaluator) evalWhileLoop(node *ast.WhileLoop, env *object.Env) object.Object {
+	for {
+		cond := e.Eval(node.LoopCond, env)
+		if isError(cond) {
+			return cond
+		}
+		if !isTruthy(cond) {
+			return NULL
+		}
+
+		res := e.Eval(node.Body, env)
+		if res != nil && (res.Type() == object.RETURN_OBJ || res.Type() == object.ERROR_OBJ) {
+			return res
+		}
 	}
+}
 
-	for cond.Value {
-		Eval(node.Body, env)
-		cond = Eval(node.LoopCond, env).(*object.Boolean)
+func (e *Evaluator) evalForLoop(node *ast.ForLoop, env *object.Env) object.Object {
+	if node.LoopVar != nil {
+		if res := e.Eval(node.LoopVar, env); isError(res) {
+			return res
+		}
 	}
 
-	return NULL
+	for {
+		cond := e.Eval(node.LoopCond, env)
+		if isError(cond) {
+			return cond
+		}
+		if !isTruthy(cond) {
+			return NULL
+		}
+
+		res := e.Eval(node.Body, env)
+		if res != nil && (res.Type() == object.RETURN_OBJ || res.Type() == object.ERROR_OBJ) {
+			return res
+		}
+		if res := e.Eval(node.PostLoop, env); isError(res) {
+			return res
+		}
+	}
 }
 
-func evalForLoop(node *ast.ForLoop, env *object.Env) object.Object {
-	Eval(node.LoopVar, env)
+func (e *Evaluator) evalForInLoop(node *ast.ForInLoop, env *object.Env) object.Object {
+	iterable := e.Eval(node.Iterable, env)
+	if isError(iterable) {
+		return iterable
+	}
 
-	cond, ok := Eval(node.LoopCond, env).(*object.Boolean)
+	items, ok := object.Iterate(iterable)
 	if !ok {
-		return newError("")
+		return newError("Cant iterate over %s", iterable.Type())
 	}
 
-	for cond.Value {
-		Eval(node.Body, env)
-		Eval(node.PostLoop, env)
-		cond = Eval(node.LoopCond, env).(*object.Boolean)
+	for _, item := range items {
+		if res := e.bindPattern(node.Pattern, item, env, false); isError(res) {
+			return res
+		}
+		res := e.Eval(node.Body, env)
+		if res != nil && (res.Type() == object.RETURN_OBJ || res.Type() == object.ERROR_OBJ) {
+			return res
+		}
 	}
 
 	return NULL
 }
 
-func evalIndexExpression(left, index object.Object) object.Object {
+func (e *Evaluator) bindPattern(pattern ast.Expression, val object.Object, env *object.Env, isConst bool) object.Object {
+	switch pattern := pattern.(type) {
+	case *ast.Ident:
+		if isConst {
+			return env.SetConst(pattern.Value, val)
+		}
+		return env.Set(pattern.Value, val)
+
+	case *ast.ArrayPattern:
+		arr, ok := val.(*object.Array)
+		if !ok {
+			return newError("Cant destructure %s as %s", val.Type(), object.ARR_OBJ)
+		}
+		for i, el := range pattern.Elements {
+			item := evalArrIndexExpression(arr, &object.Integer{Value: i})
+			if res := e.bindPattern(el, item, env, isConst); isError(res) {
+				return res
+			}
+		}
+		if pattern.Rest != nil {
+			rest := []object.Object{}
+			if len(pattern.Elements) < len(arr.Elements) {
+				rest = append(rest, arr.Elements[len(pattern.Elements):]...)
+			}
+			if res := e.bindPattern(pattern.Rest, &object.Array{Elements: rest}, env, isConst); isError(res) {
+				return res
+			}
+		}
+
+	case *ast.HashPattern:
+		hash, ok := val.(*object.Hash)
+		if !ok {
+			return newError("Cant destructure %s as %s", val.Type(), object.HASH_OBJ)
+		}
+		for i, key := range pattern.Keys {
+			item := e.evalHashIndexExpression(hash, &object.String{Value: key})
+			if isError(item) {
+				return item
+			}
+			if res := e.bindPattern(pattern.Values[i], item, env, isConst); isError(res) {
+				return res
+			}
+		}
+
+	default:
+		return newError("Cant destructure into %s", pattern.String())
+	}
+
+	return val
+}
+
+func (e *Evaluator) evalEnumStatement(node *ast.EnumStatement, env *object.Env) object.Object {
+	enum := &object.Enum{
+		Name:  node.Name.Value,
+		Arity: make(map[string]int),
+	}
+	for _, v := range node.Variants {
+		if enum.HasVariant(v.Name.Value) {
+			return newError("Variant %s declared twice in %s", v.Name.Value, enum.Name)
+		}
+		enum.Variants = append(enum.Variants, v.Name.Value)
+		enum.Arity[v.Name.Value] = len(v.Params)
+	}
+
+	for i, member := range object.NewEnumMembers(enum) {
+		if res := env.Set(enum.Variants[i], member); isError(res) {
+			return res
+		}
+	}
+
+	return env.Set(enum.Name, enum)
+}
+
+func (e *Evaluator) evalMatchExpression(node *ast.MatchExpression, env *object.Env) object.Object {
+	subject := e.Eval(node.Subject, env)
+	if isError(subject) {
+		return subject
+	}
+
+	arms := make([]object.Object, len(node.Arms))
+	for i, arm := range node.Arms {
+		if arm.IsWildcard() {
+			continue
+		}
+		arms[i] = e.Eval(arm.Variant, env)
+		if isError(arms[i]) {
+			return arms[i]
+		}
+	}
+	// checkMatches already rejected the matches it could resolve before the
+	// program ran, this catches arms bound to something else at runtime.
+	if err := checkArms(node, func(i int) (object.Object, bool) { return arms[i], true }); err != nil {
+		return err
+	}
+
+	for i, arm := range node.Arms {
+		if !arm.IsWildcard() && !object.IsVariant(subject, arms[i]) {
+			continue
+		}
+		if variant, ok := subject.(*object.EnumVariant); ok {
+			for j, b := range arm.Bindings {
+				if res := env.Set(b.Value, variant.Values[j]); isError(res) {
+					return res
+				}
+			}
+		}
+		return e.evalBlockStatement(arm.Body, env)
+	}
+
+	return newError("No match arm for %s", subject.Inspect())
+}
+
+// evalIndexChain evaluates node and reports whether an optional index on a
+// null receiver skipped the rest of the chain, so a?[0][1] is null when a is.
+func (e *Evaluator) evalIndexChain(node *ast.IndexExpression, env *object.Env) (object.Object, bool) {
+	var left object.Object
+	if inner, ok := node.Left.(*ast.IndexExpression); ok {
+		var skipped bool
+		left, skipped = e.evalIndexChain(inner, env)
+		if skipped {
+			return NULL, true
+		}
+	} else {
+		left = e.Eval(node.Left, env)
+	}
+	if isError(left) {
+		return left, false
+	}
+	if node.Optional && left == NULL {
+		return NULL, true
+	}
+	index := e.Eval(node.Index, env)
+	if isError(index) {
+		return index, false
+	}
+
+	return e.evalIndexExpression(left, index), false
+}
+
+func (e *Evaluator) evalIndexExpression(left, index object.Object) object.Object {
 	switch {
 	case left.Type() == object.ARR_OBJ && index.Type() == object.INTEGER_OBJ:
 		return evalArrIndexExpression(left, index)
+	case left.Type() == object.HASH_OBJ:
+		return e.evalHashIndexExpression(left, index)
+	case left.Type() == object.STR_OBJ && index.Type() == object.INTEGER_OBJ:
+		if char, ok := object.CharAt(left.(*object.String).Value, index.(*object.Integer).Value); ok {
+			return char
+		}
+		return NULL
+	case index.Type() == object.INTEGER_OBJ:
+		if variant, ok := left.(*object.EnumVariant); ok {
+			return evalArrIndexExpression(&object.Array{Elements: variant.Values}, index)
+		}
+		return newError("Index Operator Not Supported %s", left.Type())
 	default:
 		return newError("Index Operator Not Supported %s", left.Type())
 	}
+}
+
+func (e *Evaluator) evalIndexMethod(left, index object.Object) (object.Object, bool) {
+	fn, ok := object.Method(left, object.INDEX_METHOD)
+	if !ok {
+		return nil, false
+	}
+	return e.applyFunction(fn, []object.Object{left, index}), true
 
 }
 func evalArrIndexExpression(arr, index object.Object) object.Object {
@@ -246,55 +568,213 @@ func evalArrIndexExpression(arr, index object.Object) object.Object {
 	return arrObj.Elements[idx]
 }
 
-func evalReasignExpression(node *ast.ReasignExpression, env *object.Env) object.Object {
-	val := Eval(node.Value, env)
+func (e *Evaluator) evalHashIndexExpression(hash, index object.Object) object.Object {
+	hashObj := hash.(*object.Hash)
+
+	key, ok := index.(object.Hashable)
+	if !ok {
+		return newError("Unusable as Hash Key: %s", index.Type())
+	}
+
+	pair, ok := hashObj.Pairs[key.HashKey()]
+	if !ok {
+		if res, ok := e.evalIndexMethod(hash, index); ok {
+			return res
+		}
+		return NULL
+	}
+
+	return pair.Value
+}
+
+func (e *Evaluator) evalHashLiteral(node *ast.HashLiteral, env *object.Env) object.Object {
+	pairs := make(map[object.HashKey]object.HashPair)
+
+	for i, keyNode := range node.Keys {
+		key := e.Eval(keyNode, env)
+		if isError(key) {
+			return key
+		}
+
+		hashKey, ok := key.(object.Hashable)
+		if !ok {
+			return newError("Unusable as Hash Key: %s", key.Type())
+		}
+
+		val := e.Eval(node.Values[i], env)
+		if isError(val) {
+			return val
+		}
+
+		pairs[hashKey.HashKey()] = object.HashPair{Key: key, Value: val}
+	}
+
+	return &object.Hash{Pairs: pairs}
+}
+
+func (e *Evaluator) evalIndexAssignExpression(node *ast.IndexAssignExpression, env *object.Env) object.Object {
+	left := e.Eval(node.Left, env)
+	if isError(left) {
+		return left
+	}
+	index := e.Eval(node.Index, env)
+	if isError(index) {
+		return index
+	}
+	val := e.Eval(node.Value, env)
+	if isError(val) {
+		return val
+	}
+
+	switch left := left.(type) {
+	case *object.Array:
+		if left.Frozen {
+			return newError("Cant modify frozen %s", left.Type())
+		}
+		idx, ok := index.(*object.Integer)
+		if !ok {
+			return newError("Cant use %s As Index for %s", index.Type(), left.Type())
+		}
+		if idx.Value < 0 || idx.Value >= len(left.Elements) {
+			return newError("Index %d out of Range", idx.Value)
+		}
+		left.Elements[idx.Value] = val
+
+	case *object.Hash:
+		if left.Frozen {
+			return newError("Cant modify frozen %s", left.Type())
+		}
+		key, ok := index.(object.Hashable)
+		if !ok {
+			return newError("Unusable as Hash Key: %s", index.Type())
+		}
+		left.Pairs[key.HashKey()] = object.HashPair{Key: index, Value: val}
+
+	default:
+		return newError("Index Assignment Not Supported %s", left.Type())
+	}
+
+	return val
+}
+
+func (e *Evaluator) evalReasignExpression(node *ast.ReasignExpression, env *object.Env) object.Object {
+	val := e.Eval(node.Value, env)
 	if isError(val) {
 		return val
 	}
 	curVal, ok := env.Get(node.Var.Value)
 
 	if !ok {
+		if _, ok := e.Builtins.Lookup(node.Var.Value); ok {
+			return newError("Cant assign to Builtin %s", node.Var.Value)
+		}
 		return newError("Unkown Identefier %s", node.Var.Value)
 	}
 
+	if env.IsConst(node.Var.Value) {
+		return newError("Cant assign to Constant %s", node.Var.Value)
+	}
+
 	var newVal object.Object
 
 	switch node.Operator {
 	case "=":
 		newVal = val
 	case "+=":
-		newVal = evalInfixExpression("+", curVal, val)
+		newVal = e.evalInfixExpression("+", curVal, val)
 
 	case "-=":
-		newVal = evalInfixExpression("-", curVal, val)
+		newVal = e.evalInfixExpression("-", curVal, val)
 
 	case "*=":
-		newVal = evalInfixExpression("*", curVal, val)
+		newVal = e.evalInfixExpression("*", curVal, val)
 
 	case "/=":
-		newVal = evalInfixExpression("/", curVal, val)
+		newVal = e.evalInfixExpression("/", curVal, val)
+	}
+	if isError(newVal) {
+		return newVal
 	}
 
-	env.Set(node.Var.Value, newVal)
-	return newVal
+	return env.Assign(node.Var.Value, newVal)
 }
 
-func applyFunction(fn object.Object, args []object.Object) object.Object {
+func (e *Evaluator) applyFunction(fn object.Object, args []object.Object) object.Object {
 
 	switch fn := fn.(type) {
 	case *object.Function:
-		extEnv := extendFunctionEnv(fn, args)
-		evaluated := Eval(fn.Body, extEnv)
-		return unwrapReturnValue(evaluated)
+		if len(e.callChain) >= e.MaxCallDepth {
+			chain := append(append([]string{}, e.callChain...), object.FunctionName(fn.Name))
+			return errorFrom(&object.StackOverflowError{Kind: "call depth", Limit: e.MaxCallDepth, Chain: chain})
+		}
+		e.callChain = append(e.callChain, object.FunctionName(fn.Name))
+		defer func() { e.callChain = e.callChain[:len(e.callChain)-1] }()
+
+		for {
+			extEnv, err := e.extendFunctionEnv(fn, args)
+			if err != nil {
+				return err
+			}
+			evaluated := unwrapReturnValue(e.Eval(fn.Body, extEnv))
+
+			tc, ok := evaluated.(*object.TailCall)
+			if !ok {
+				return e.runDeferred(extEnv, evaluated)
+			}
+			next, ok := tc.Fn.(*object.Function)
+			if !ok || extEnv.HasDeferred() {
+				return e.runDeferred(extEnv, e.applyFunction(tc.Fn, tc.Args))
+			}
+			fn, args = next, tc.Args
+			e.callChain[len(e.callChain)-1] = object.FunctionName(fn.Name)
+		}
 
 	case *object.BuiltIn:
+		if fn.Stringify {
+			strArgs := make([]object.Object, len(args))
+			for i, arg := range args {
+				strArgs[i] = e.stringify(arg)
+				if isError(strArgs[i]) {
+					return strArgs[i]
+				}
+			}
+			args = strArgs
+		}
+		if fn.CallFn != nil {
+			return fn.CallFn(e, args...)
+		}
 		return fn.Fn(args...)
 
+	case *object.ComposedFunction:
+		res := e.applyFunction(fn.First, args)
+		if isError(res) {
+			return res
+		}
+		return e.applyFunction(fn.Second, []object.Object{res})
+
+	case *object.EnumConstructor:
+		return fn.Construct(args)
+
 	default:
 		return newError("not a function %s", fn.Type())
 	}
 }
 
+// runDeferred evaluates the deferred expressions of a finished call in reverse
+// order. An error raised by one of them replaces a successful result.
+func (e *Evaluator) runDeferred(env *object.Env, res object.Object) object.Object {
+	for {
+		exp, ok := env.PopDeferred()
+		if !ok {
+			return res
+		}
+		evaluated := e.Eval(exp, env)
+		if isError(evaluated) && !isError(res) {
+			res = evaluated
+		}
+	}
+}
+
 func unwrapReturnValue(obj object.Object) object.Object {
 	if returnValue, ok := obj.(*object.ReturnValue); ok {
 
@@ -304,21 +784,30 @@ func unwrapReturnValue(obj object.Object) object.Object {
 	return obj
 }
 
-func extendFunctionEnv(fn *object.Function, args []object.Object) *object.Env {
+func (e *Evaluator) extendFunctionEnv(fn *object.Function, args []object.Object) (*object.Env, object.Object) {
 	env := object.NewEnclosedEnv(fn.Env)
+	if len(args) < len(fn.Params) {
+		return nil, newError("Wrong Number Of Arguments Want %d Got %d", len(fn.Params), len(args))
+	}
 
 	for i, p := range fn.Params {
+		if i < len(fn.Patterns) && fn.Patterns[i] != nil {
+			if res := e.bindPattern(fn.Patterns[i], args[i], env, false); isError(res) {
+				return nil, res
+			}
+			continue
+		}
 		env.Set(p.Value, args[i])
 	}
 
-	return env
+	return env, nil
 }
 
-func evalExpressions(exps []ast.Expression, env *object.Env) []object.Object {
+func (e *Evaluator) evalExpressions(exps []ast.Expression, env *object.Env) []object.Object {
 	var res []object.Object
 
 	for _, exp := range exps {
-		evaluated := Eval(exp, env)
+		evaluated := e.Eval(exp, env)
 		if isError(evaluated) {
 
 			return []object.Object{evaluated}
@@ -329,23 +818,26 @@ func evalExpressions(exps []ast.Expression, env *object.Env) []object.Object {
 	return res
 }
 
-func evalIdent(ident *ast.Ident, env *object.Env) object.Object {
+func (e *Evaluator) evalIdent(ident *ast.Ident, env *object.Env) object.Object {
 
 	if val, ok := env.Get(ident.Value); ok {
 		return val
 	}
-	if builtin, ok := builtins[ident.Value]; ok {
+	if builtin, ok := e.Builtins.Lookup(ident.Value); ok {
 		return builtin
 	}
+	if e.sandbox != nil && e.Builtins.Index(ident.Value) >= 0 {
+		return errorFrom(&object.SandboxError{Name: ident.Value})
+	}
 
 	return newError("Identifier Not Found: %s", ident.Value)
 }
 
-func evalBlockStatement(block *ast.BlockStatement, env *object.Env) object.Object {
+func (e *Evaluator) evalBlockStatement(block *ast.BlockStatement, env *object.Env) object.Object {
 	var res object.Object
 
 	for _, stmt := range block.Statements {
-		res = Eval(stmt, env)
+		res = e.Eval(stmt, env)
 
 		if res != nil {
 			resType := res.Type()
@@ -359,8 +851,8 @@ func evalBlockStatement(block *ast.BlockStatement, env *object.Env) object.Objec
 	return res
 }
 
-func evalIfExpression(ie *ast.IfExpression, env *object.Env) object.Object {
-	condition := Eval(ie.Condition, env)
+func (e *Evaluator) evalIfExpression(ie *ast.IfExpression, env *object.Env) object.Object {
+	condition := e.Eval(ie.Condition, env)
 
 	if isError(condition) {
 
@@ -369,10 +861,10 @@ func evalIfExpression(ie *ast.IfExpression, env *object.Env) object.Object {
 
 	if isTruthy(condition) {
 
-		return Eval(ie.If, env)
+		return e.Eval(ie.If, env)
 	} else if ie.Else != nil {
 
-		return Eval(ie.Else, env)
+		return e.Eval(ie.Else, env)
 	} else {
 
 		return NULL
@@ -393,8 +885,16 @@ func isTruthy(obj object.Object) bool {
 	}
 }
 
-func evalInfixExpression(operator string, left, right object.Object) object.Object {
+func (e *Evaluator) evalInfixExpression(operator string, left, right object.Object) object.Object {
+	if variant, ok := left.(*object.EnumVariant); ok && (operator == "==" || operator == "!=") {
+		return boolToBoolObj(variant.Equals(right) == (operator == "=="))
+	}
+
 	switch {
+	case operator == token.COMPOSE:
+
+		return evalComposition(left, right)
+
 	case left.Type() == object.INTEGER_OBJ && right.Type() == object.INTEGER_OBJ:
 
 		return evalIntegerInfix(operator, left, right)
@@ -418,7 +918,14 @@ func evalInfixExpression(operator string, left, right object.Object) object.Obje
 	case left.Type() == object.STR_OBJ && right.Type() == object.STR_OBJ:
 
 		return evalStrInfix(operator, left, right)
+	}
+
+	if res, ok := e.evalOperatorMethod(operator, left, right); ok {
+
+		return res
+	}
 
+	switch {
 	case left.Type() != right.Type():
 
 		return newError("Type Mismatch %s %s %s", left.Type(), operator, right.Type())
@@ -428,6 +935,75 @@ func evalInfixExpression(operator string, left, right object.Object) object.Obje
 	}
 
 }
+func evalComposition(first, second object.Object) object.Object {
+	if !object.IsCallable(first) || !object.IsCallable(second) {
+		return newError("Cant compose %s and %s", first.Type(), second.Type())
+	}
+
+	return &object.ComposedFunction{First: first, Second: second}
+}
+
+func (e *Evaluator) evalOperatorMethod(operator string, left, right object.Object) (object.Object, bool) {
+	switch operator {
+	case ">":
+		return e.evalOperatorMethod("<", right, left)
+	case "<=", ">=":
+		if operator == "<=" {
+			left, right = right, left
+		}
+		res, ok := e.evalOperatorMethod("<", left, right)
+		if !ok || isError(res) {
+			return res, ok
+		}
+		return boolToBoolObj(!isTruthy(res)), true
+	case "!=":
+		if _, ok := object.FindMethod(object.NE_METHOD, left, right); ok {
+			break
+		}
+		res, ok := e.evalOperatorMethod("==", left, right)
+		if !ok || isError(res) {
+			return res, ok
+		}
+		return boolToBoolObj(!isTruthy(res)), true
+	}
+
+	if operator == "+" && (left.Type() == object.STR_OBJ || right.Type() == object.STR_OBJ) {
+		if _, ok := object.FindMethod(object.STR_METHOD, left, right); ok {
+			leftStr := e.stringify(left)
+			if isError(leftStr) {
+				return leftStr, true
+			}
+			rightStr := e.stringify(right)
+			if isError(rightStr) {
+				return rightStr, true
+			}
+			return e.evalInfixExpression(operator, leftStr, rightStr), true
+		}
+	}
+
+	if fn, ok := object.FindMethod(object.OperatorMethods[operator], left, right); ok {
+		return e.applyFunction(fn, []object.Object{left, right}), true
+	}
+
+	return nil, false
+}
+
+func (e *Evaluator) stringify(obj object.Object) object.Object {
+	fn, ok := object.Method(obj, object.STR_METHOD)
+	if !ok {
+		return obj
+	}
+
+	res := e.applyFunction(fn, []object.Object{obj})
+	if isError(res) {
+		return res
+	}
+	if res.Type() != object.STR_OBJ {
+		return newError("%s has to return a STRING got %s", object.STR_METHOD, res.Type())
+	}
+	return res
+}
+
 func evalIntFloatInfix(operator string, left, right object.Object, pos int) object.Object {
 	var leftVal, rightVal float64
 	switch pos {
@@ -587,21 +1163,26 @@ func evalIntegerInfix(operator string, left, right object.Object) object.Object
 	}
 }
 
-func evalPrefixExpression(right object.Object, operator string) object.Object {
+func (e *Evaluator) evalPrefixExpression(right object.Object, operator string) object.Object {
 	switch operator {
 	case "!":
 
 		return evalBangOperator(right)
 	case "-":
 
-		return evalMinusOperator(right)
+		return e.evalMinusOperator(right)
 	default:
 
 		return newError("Unkown Operator %s", operator)
 	}
 }
 
-func evalMinusOperator(right object.Object) object.Object {
+func (e *Evaluator) evalMinusOperator(right object.Object) object.Object {
+
+	if fn, ok := object.Method(right, object.NEG_METHOD); ok {
+
+		return e.applyFunction(fn, []object.Object{right})
+	}
 
 	if right.Type() != object.INTEGER_OBJ && right.Type() != object.FLOAT_OBJ {
 
@@ -649,19 +1230,21 @@ func boolToBoolObj(b bool) *object.Boolean {
 	return FALSE
 }
 
-func evalProgram(program *ast.Program, env *object.Env) object.Object {
+func (e *Evaluator) evalProgram(program *ast.Program, env *object.Env) object.Object {
+	if err := checkMatches(program, env); err != nil {
+		return err
+	}
 	var res object.Object
 
 	for _, stmt := range program.Statements {
 
-		res = Eval(stmt, env)
+		res = e.Eval(stmt, env)
 		switch res := res.(type) {
 
 		case *object.ReturnValue:
 
 			return res.Value
 		case *object.Error:
-			fmt.Println(res.Message)
 			return res
 		}
 
@@ -676,6 +1259,10 @@ func newError(format string, a ...interface{}) *object.Error {
 	}
 }
 
+func errorFrom(err error) *object.Error {
+	return object.ErrorFrom(err)
+}
+
 func isError(obj object.Object) bool {
 	if obj != nil {
 
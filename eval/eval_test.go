@@ -1,8 +1,14 @@
 package eval
 
 import (
+	"bytes"
+	"context"
+	"errors"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/Arch-4ng3l/Monkey/ast"
 	"github.com/Arch-4ng3l/Monkey/lexer"
 	"github.com/Arch-4ng3l/Monkey/object"
 	"github.com/Arch-4ng3l/Monkey/parser"
@@ -143,6 +149,9 @@ func TestEvalBoolExpression(t *testing.T) {
 		{"true == true", true},
 		{"true != false", true},
 		{"true == false", false},
+		{"3 >= 4", false},
+		{"4 >= 4", true},
+		{"3 <= 4", true},
 	}
 
 	for _, tt := range tests {
@@ -209,3 +218,847 @@ func testIngegerObject(t *testing.T, obj object.Object, expected int) bool {
 	}
 	return true
 }
+
+func TestConstants(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"const x = 1; x = 2;", "Cant assign to Constant x"},
+		{"const x = 1; x += 2;", "Cant assign to Constant x"},
+		{"const x = 1; var x = 2;", "Cant assign to Constant x"},
+		{"const x = 1; const x = 2;", "Cant redeclare x as Constant"},
+		{"var x = 1; const x = 2;", "Cant redeclare x as Constant"},
+		{"const x = 1; var f = func() { x = 2; }; f();", "Cant assign to Constant x"},
+		{"len = 1;", "Cant assign to Builtin len"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testErrorObject(t, evaluated, tt.expected)
+	}
+
+	evaluated := testEval("const x = 1; var f = func() { var x = 2; x }; f() + x;")
+	testIngegerObject(t, evaluated, 3)
+}
+
+func TestHashIndexAssignment(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int
+	}{
+		{`var h = {"a": 1, 2: 3}; h["a"] + h[2];`, 4},
+		{`var h = {"a": 1}; h["b"] = 2; h["a"] + h["b"];`, 3},
+		{`var a = [1, 2, 3]; a[1] = 5; a[1];`, 5},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testIngegerObject(t, evaluated, tt.expected)
+	}
+}
+
+func TestFreeze(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"var a = freeze([1, 2]); a[0] = 3;", "Cant modify frozen ARRAY"},
+		{`var h = freeze({"a": [1]}); h["b"] = 2;`, "Cant modify frozen HASH"},
+		{`var h = freeze({"a": [1]}); var a = h["a"]; a[0] = 2;`, "Cant modify frozen ARRAY"},
+		{"freeze(1);", "Object of Type INTEGER cant be frozen"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testErrorObject(t, evaluated, tt.expected)
+	}
+}
+
+func testErrorObject(t *testing.T, obj object.Object, expected string) bool {
+	err, ok := obj.(*object.Error)
+	if !ok {
+		t.Errorf("Object is not Error got %T", obj)
+		return false
+	}
+
+	if err.Message != expected {
+		t.Errorf("Error has wrong message want %q got %q", expected, err.Message)
+		return false
+	}
+	return true
+}
+
+func TestTypeAnnotationsIgnored(t *testing.T) {
+	evaluated := testEval("var f = func(a: int, b: [int]) -> int { a * b[0] }; var x: int = f(2, [3]); x;")
+	testIngegerObject(t, evaluated, 6)
+}
+
+func TestQuoteUnquote(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`quote(5)`, `5`},
+		{`quote(foobar + barfoo)`, `(foobar + barfoo)`},
+		{`quote(unquote(4 + 4))`, `8`},
+		{`var x = 8; quote(unquote(x) + 1)`, `(8 + 1)`},
+		{`var q = quote(4 + 4); quote(unquote(q) * 2)`, `((4 + 4) * 2)`},
+		{`quote(unquote(true == false))`, `false`},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		quote, ok := evaluated.(*object.Quote)
+		if !ok {
+			t.Fatalf("expected *object.Quote got %T (%+v)", evaluated, evaluated)
+		}
+
+		if quote.Node.String() != tt.expected {
+			t.Errorf("wrong quoted node want %q got %q", tt.expected, quote.Node.String())
+		}
+	}
+}
+
+func TestMacroExpansion(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			`var infix = macro() { quote(1 + 2) }; infix();`,
+			`(1 + 2)`,
+		},
+		{
+			`var reverse = macro(a, b) { quote(unquote(b) - unquote(a)) }; reverse(2 + 2, 10 - 5);`,
+			`((10 - 5) - (2 + 2))`,
+		},
+	}
+
+	for _, tt := range tests {
+		program := testParseProgram(tt.input)
+		env := object.NewEnv()
+		DefineMacros(program, env)
+
+		expanded, err := ExpandMacros(program, env)
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		if len(expanded.Statements) != 1 || expanded.Statements[0].String() != tt.expected {
+			t.Errorf("wrong expansion want %q got %q", tt.expected, expanded.String())
+		}
+	}
+}
+
+func TestMacroHygiene(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int
+	}{
+		{`var unless = macro(cond, cons, alt) { quote(if (!(unquote(cond))) { unquote(cons) } else { unquote(alt) }) };
+		  unless(10 > 5, 1, 2);`, 2},
+		{`var tmp = 10;
+		  var addTmp = macro(x) { quote(func() { var tmp = 1; unquote(x) + tmp }()) };
+		  addTmp(tmp);`, 11},
+		{`var twice = macro(x) { quote(func() { var t = unquote(x); t + t }()) };
+		  twice(3) + twice(4);`, 14},
+		{`var x = 10;
+		  var addX = macro(a) { quote(func(x) { x + unquote(a) }(1)) };
+		  addX(x);`, 11},
+		{`var x = 10;
+		  var sumX = macro(a) { quote(func([x, y]) { x + y + unquote(a) }([1, 2])) };
+		  sumX(x);`, 13},
+	}
+
+	for _, tt := range tests {
+		program := testParseProgram(tt.input)
+		macroEnv := object.NewEnv()
+		DefineMacros(program, macroEnv)
+
+		expanded, err := ExpandMacros(program, macroEnv)
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		testIngegerObject(t, Eval(expanded, object.NewEnv()), tt.expected)
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			program := testParseProgram(`var m = macro(a) { quote(func() { var t = unquote(a); t }()) }; m(1);`)
+			macroEnv := object.NewEnv()
+			DefineMacros(program, macroEnv)
+			expanded, err := ExpandMacros(program, macroEnv)
+			if err != nil {
+				t.Errorf("%s", err)
+				return
+			}
+			ast.Walk(expanded, func(n ast.Node) {
+				if let, ok := n.(*ast.LetStatement); ok && let.Name.Value != "t#1" {
+					t.Errorf("expected names numbered per macro Env got %s", let.Name.Value)
+				}
+			})
+		}()
+	}
+	wg.Wait()
+
+	program := testParseProgram(`var bad = macro() { 1 }; bad();`)
+	macroEnv := object.NewEnv()
+	DefineMacros(program, macroEnv)
+	if _, err := ExpandMacros(program, macroEnv); err == nil {
+		t.Errorf("expected error for macro not returning a quote")
+	}
+}
+
+func testParseProgram(input string) *ast.Program {
+	l := lexer.NewLexer(input)
+	p := parser.NewParser(l)
+	return p.ParseProgram()
+}
+
+const vecDefinition = `
+var vec = func(x, y) {
+	{
+		"x": x,
+		"y": y,
+		"__add__": func(a, b) { vec(a["x"] + b["x"], a["y"] + b["y"]) },
+		"__mul__": func(a, k) { vec(a["x"] * k, a["y"] * k) },
+		"__eq__": func(a, b) { a["x"] == b["x"] },
+		"__lt__": func(a, b) { a["x"] < b["x"] },
+		"__pow__": func(a, k) { a["x"] * k },
+		"__neg__": func(a) { vec(-a["x"], -a["y"]) },
+		"__index__": func(a, i) { if (i == 0) { a["x"] } else { a["y"] } },
+		"__str__": func(a) { "(" + toStr(a["x"]) + ", " + toStr(a["y"]) + ")" }
+	}
+};
+`
+
+func TestOperatorOverloading(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`(vec(1, 2) + vec(3, 4))["y"]`, 6},
+		{`(vec(1, 2) * 3)["x"]`, 3},
+		{`vec(1, 2) == vec(1, 5)`, true},
+		{`vec(1, 2) != vec(1, 5)`, false},
+		{`vec(1, 2) < vec(2, 0)`, true},
+		{`vec(1, 2) > vec(2, 0)`, false},
+		{`vec(1, 2) >= vec(1, 0)`, true},
+		{`vec(1, 2) <= vec(0, 0)`, false},
+		{`vec(1, 2) <= vec(1, 0)`, true},
+		{`vec(3, 2) ^ 2`, 6},
+		{`(-vec(1, 2))["x"]`, -1},
+		{`vec(1, 2)[1]`, 2},
+		{`"v=" + vec(1, 2)`, "v=(1, 2)"},
+		{`toStr(vec(7, 8))`, "(7, 8)"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(vecDefinition + tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIngegerObject(t, evaluated, expected)
+		case bool:
+			testBoolObject(t, evaluated, expected, tt.input)
+		case string:
+			str, ok := evaluated.(*object.String)
+			if !ok || str.Value != expected {
+				t.Errorf("%s: want %q got %s", tt.input, expected, evaluated.Inspect())
+			}
+		}
+	}
+
+	testErrorObject(t, testEval(`{"a": 1} - {"a": 2}`), "Unkown Operator HASH - HASH")
+}
+
+func TestConditionalOperators(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"true ? 1 : 2", 1},
+		{"1 > 2 ? 1 : 2", 2},
+		{"var x = 5; x > 3 ? x > 4 ? 1 : 2 : 3", 1},
+		{"[][0] ?? 7", 7},
+		{"3 ?? 7", 3},
+		{"var n = 0; var f = func() { n = 1; 2 }; 3 ?? f(); n", 0},
+		{"var a = [[1, 2]]; a?[0]?[1]", 2},
+		{"var a = [[1, 2]]; a?[3]?[1]", nil},
+		{`var h = {"user": {"name": 1}}; h?.user?.name`, 1},
+		{`var h = {}; h?.user?.name ?? 9`, 9},
+		{"var n = [][0]; n?[0][1]", nil},
+		{"var n = [][0]; n?[0][1][2] ?? 5", 5},
+		{`var h = {}; h?.user?.name[0]["first"]`, nil},
+		{"var c = 0; var f = func() { c += 1; 0 }; var n = [][0]; n?[f()][f()]; c", 0},
+		{"var a = [[1, 2]]; a?[0][1]", 2},
+		{"var a = [][0]; [a?[0][1], 3][1]", 3},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if i, ok := tt.expected.(int); ok {
+			testIngegerObject(t, evaluated, i)
+		} else {
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestDestructuring(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"var [a, b] = [1, 2]; a * 10 + b", 12},
+		{"var [a, [b, c]] = [1, [2, 3]]; a + b + c", 6},
+		{"var [a, b] = [1]; b", nil},
+		{"var [a, ...rest] = [1, 2, 3]; len(rest) * 10 + rest[1]", 23},
+		{"var [a, b, ...rest] = [1]; len(rest)", 0},
+		{`var {x, y: [first]} = {"x": 1, "y": [5]}; x + first`, 6},
+		{`var {x, z} = {"x": 1}; z`, nil},
+		{`var f = func([a, b], {c}) { a + b + c }; f([1, 2], {"c": 3})`, 6},
+		{"var s = 0; for (x in [1, 2, 3]) { s += x }; s", 6},
+		{"var s = 0; for (var [a, b] in [[1, 2], [3, 4]]) { s += a * b }; s", 14},
+		{`var s = 0; for ([k, v] in {"a": 1, "b": 2}) { s += v }; s`, 3},
+		{`var f = func() { for (x in [1, 2, 3]) { if (x == 2) { return x; } } }; f()`, 2},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if i, ok := tt.expected.(int); ok {
+			testIngegerObject(t, evaluated, i)
+		} else {
+			testNullObject(t, evaluated)
+		}
+	}
+
+	errors := []struct {
+		input    string
+		expected string
+	}{
+		{"var [a] = 1;", "Cant destructure INTEGER as ARRAY"},
+		{"var {a} = [1];", "Cant destructure ARRAY as HASH"},
+		{"const [a] = [1]; a = 2;", "Cant assign to Constant a"},
+		{"for (x in 1) { x }", "Cant iterate over INTEGER"},
+	}
+
+	for _, tt := range errors {
+		evaluated := testEval(tt.input)
+		testErrorObject(t, evaluated, tt.expected)
+	}
+}
+
+func TestPipeAndCompose(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int
+	}{
+		{"var add = func(a, b) { a + b }; 1 |> add(2)", 3},
+		{"var add = func(a, b) { a + b }; 1 |> add(2) |> add(3)", 6},
+		{"[3, 1, 2] |> sort |> len", 3},
+		{"var inc = func(x) { x + 1 }; var dbl = func(x) { x * 2 }; (inc >> dbl)(3)", 8},
+		{"var inc = func(x) { x + 1 }; var f = inc >> inc >> inc; 1 |> f", 4},
+		{"var adder = func(n) { func(x) { x + n } }; var f = adder(2) >> adder(3); f(1)", 6},
+		{"var first = func(a) { a[0] }; var f = sort >> first; f([3, 1, 2])", 1},
+		{"var f = push >> len; f([1], 2)", 2},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testIngegerObject(t, evaluated, tt.expected)
+	}
+
+	evaluated := testEval("var f = 1 >> len;")
+	testErrorObject(t, evaluated, "Cant compose INTEGER and BUILTIN_FUNCTION")
+}
+
+func TestClosures(t *testing.T) {
+	mk := "var mk = func(n) { func(x) { x + n } }; "
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{mk + "mk(3)(1)", "4"},
+		{mk + "(mk(10) >> mk(100))(1)", "111"},
+		{mk + "map([1, 2], mk(3))", "[4, 5]"},
+		{mk + "var two = mk(2); var three = mk(3); 1 |> two |> three", "6"},
+		{"var g = func(a) { func(b) { a + b } }; g(1)(2)", "3"},
+		{"var f = func(a) { func(b) { func(c) { a + b + c } } }; f(1)(2)(3)", "6"},
+		{"var counter = func() { var n = 0; func() { n += 1; n } }; var c = counter(); c(); c(); c()", "3"},
+		{"var f = func() { var x = 1; var g = func() { x }; x = 2; g() }; f()", "2"},
+		{"var f = func() { var x = 1; var set = func() { x = 5 }; set(); x }; f()", "5"},
+		{"var pair = func() { var n = 0; [func() { n += 1 }, func() { n }] }; var p = pair(); p[0](); p[0](); p[1]()", "2"},
+		{"var f = func() { var fact = func(n) { n < 2 ? 1 : n * fact(n - 1) }; fact(5) }; f()", "120"},
+		{"var f = func(n) { var loop = func(i, acc) { i == 0 ? acc : loop(i - 1, acc + n) }; loop(10000, 0) }; f(2)", "20000"},
+		{"var f = func() { var fns = []; for (x in [1, 2, 3]) { fns = push(fns, func() { x }) }; fns[0]() }; f()", "3"},
+		{"var x = 0; var f = func() { x = 5 }; f(); x", "5"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("%s: want %s got %s", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestDefer(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`var log = {"s": ""}; var f = func() { defer log["s"] = log["s"] + "1"; defer log["s"] = log["s"] + "2"; log["s"] = "0"; }; f(); log["s"]`, "021"},
+		{`var log = {"s": ""}; var f = func(x) { defer log["s"] = log["s"] + "d"; if (x) { return "r"; } log["s"] = "n"; }; f(true) + log["s"]`, "rd"},
+		{`var log = {"s": ""}; var f = func(x) { if (x) { defer log["s"] = "inner"; } "done" }; f(true) + log["s"]`, "doneinner"},
+		{`var log = {"s": "a"}; var f = func() { var x = "b"; defer log["s"] = log["s"] + x; x = "c"; }; f(); log["s"]`, "ac"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		str, ok := evaluated.(*object.String)
+		if !ok {
+			t.Errorf("Object is not String got %T (%+v)", evaluated, evaluated)
+			continue
+		}
+		if str.Value != tt.expected {
+			t.Errorf("wrong value want %q got %q", tt.expected, str.Value)
+		}
+	}
+
+	evaluated := testEval(`var f = func() { defer 1 + "a"; 2 }; f()`)
+	testErrorObject(t, evaluated, "Type Mismatch INTEGER + STRING")
+
+	env := object.NewEnv()
+	evaluated = Eval(testParseProgram(`var log = {"s": ""}; var f = func() { defer log["s"] = "cleaned"; 1 + "a"; }; f();`), env)
+	testErrorObject(t, evaluated, "Type Mismatch INTEGER + STRING")
+	log, _ := env.Get("log")
+	if log.Inspect() != `{s: cleaned}` {
+		t.Errorf("deferred call did not run on error got %s", log.Inspect())
+	}
+
+	evaluated = testEval(`defer len([]);`)
+	testErrorObject(t, evaluated, "Cant defer outside of a Function")
+}
+
+const colorDefinition = `enum Color { Red, Green, Blue(r, g) };
+var name = func(c) { match (c) { Red => "red", Green => "green", Blue(r, g) => "blue" + toStr(r + g) } };
+`
+
+func TestEnums(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"name(Red)", "red"},
+		{"name(Blue(1, 2))", "blue3"},
+		{"typeof(Green)", "Color"},
+		{"typeof(Blue(1, 2))", "Color"},
+		{"toStr(Blue(1, [2]))", "Color.Blue(1, [2])"},
+		{"toStr(Red == Red) + toStr(Red == Green) + toStr(Blue(1, 2) == Blue(1, 2)) + toStr(Blue(1, 2) != Blue(2, 1))", "truefalsetruetrue"},
+		{`match (Green) { Red => "r", _ => "other" }`, "other"},
+		{`var c = Blue(5, 6); c[1] |> toStr`, "6"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(colorDefinition + tt.input)
+		str, ok := evaluated.(*object.String)
+		if !ok {
+			t.Errorf("Object is not String got %T (%+v)", evaluated, evaluated)
+			continue
+		}
+		if str.Value != tt.expected {
+			t.Errorf("wrong value want %q got %q", tt.expected, str.Value)
+		}
+	}
+
+	errors := []struct {
+		input    string
+		expected string
+	}{
+		{`match (Red) { Red => 1, Green => 2 }`, "Match on Color is not exhaustive missing Blue"},
+		{`match (Red) { Red => 1, Blue(r) => 2, _ => 3 }`, "Blue: Want 2 Bindings got 1"},
+		{`match (1) { Red => 1, _ => 2 }; match (1) { Red => 1, Green => 2, Blue(r, g) => 3 }`, "No match arm for 1"},
+		{`Blue(1)`, "Color.Blue: Want 2 Arguments got 1"},
+		{`var x = 1; match (Red) { x => 1 }`, "x is not an Enum Variant"},
+		{`var f = func(c) { match (c) { Red => 1 } }; 1`, "Match on Color is not exhaustive missing Green, Blue"},
+		{`if (false) { match (Red) { Red => 1, Green => 2 } }`, "Match on Color is not exhaustive missing Blue"},
+	}
+
+	for _, tt := range errors {
+		evaluated := testEval(colorDefinition + tt.input)
+		testErrorObject(t, evaluated, tt.expected)
+	}
+
+	env := object.NewEnv()
+	Eval(parser.NewParser(lexer.NewLexer(colorDefinition)).ParseProgram(), env)
+	evaluated := Eval(parser.NewParser(lexer.NewLexer(`var ran = 1; if (false) { match (Red) { Green => 1 } }`)).ParseProgram(), env)
+	testErrorObject(t, evaluated, "Match on Color is not exhaustive missing Red, Blue")
+	if _, ok := env.Get("ran"); ok {
+		t.Errorf("program ran before its match was rejected")
+	}
+}
+
+func TestTailCalls(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int
+	}{
+		{"var count = func(n, acc) { if (n == 0) { acc } else { count(n - 1, acc + 1) } }; count(100000, 0)", 100000},
+		{"var count = func(n, acc) { if (n == 0) { return acc; } return count(n - 1, acc + 2); }; count(100000, 0)", 200000},
+		{"var even = func(n) { n == 0 ? 1 : odd(n - 1) }; var odd = func(n) { n == 0 ? 0 : even(n - 1) }; even(100001)", 0},
+		{"var fib = func(n) { if (n < 2) { n } else { fib(n - 1) + fib(n - 2) } }; fib(15)", 610},
+		{"var inc = func(x) { x + 1 }; var f = func(x) { (inc >> inc)(x) }; f(1)", 3},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testIngegerObject(t, evaluated, tt.expected)
+	}
+}
+
+func TestStackOverflow(t *testing.T) {
+	e := New(nil)
+	e.MaxCallDepth = 100
+
+	evaluated := e.Eval(testParseProgram("var down = func(n) { 1 + down(n + 1) }; var start = func() { 1 + down(0) }; start()"), object.NewEnv())
+	err, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("Object is not Error got %T", evaluated)
+	}
+	want := "Stack Overflow: call depth limit of 100 exceeded in start -> down -> down -> down -> down -> ... 91 more -> down -> down -> down -> down -> down"
+	if err.Message != want {
+		t.Errorf("wrong message want %q got %q", want, err.Message)
+	}
+	if len(e.callChain) != 0 {
+		t.Errorf("call chain not unwound got %d entries", len(e.callChain))
+	}
+
+	evaluated = e.Eval(testParseProgram("var f = func(n) { if (n == 0) { 0 } else { 1 + f(n - 1) } }; f(99)"), object.NewEnv())
+	testIngegerObject(t, evaluated, 99)
+
+	evaluated = testEval("var f = func(a, b) { a }; f(1)")
+	testErrorObject(t, evaluated, "Wrong Number Of Arguments Want 2 Got 1")
+}
+
+func TestExecutionLimits(t *testing.T) {
+	defer func(steps int, timeout time.Duration) { MaxSteps, Timeout = steps, timeout }(MaxSteps, Timeout)
+
+	evalContext := func(ctx context.Context, input string) object.Object {
+		return EvalContext(ctx, testParseProgram(input), object.NewEnv())
+	}
+	testLimit := func(evaluated object.Object, kind string) {
+		t.Helper()
+		err, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Fatalf("Object is not Error got %T", evaluated)
+		}
+		var limit *object.LimitError
+		if !errors.As(err.Cause, &limit) {
+			t.Fatalf("Cause is not a LimitError got %v", err.Cause)
+		}
+		if limit.Kind != kind {
+			t.Errorf("wrong limit want %q got %q", kind, limit.Kind)
+		}
+	}
+
+	MaxSteps, Timeout = 10000, 0
+	testLimit(evalContext(context.Background(), "while (true) {}"), object.LimitSteps)
+	testLimit(evalContext(context.Background(), "var f = func() { f() }; f()"), object.LimitSteps)
+	testIngegerObject(t, evalContext(context.Background(), "var i = 0; while (i < 10) { i += 1 }; i"), 10)
+	testIngegerObject(t, evalContext(context.Background(), "var s = 0; for (var i = 0; i < 5; i += 1) { s += i }; s"), 10)
+
+	MaxSteps, Timeout = 0, 20*time.Millisecond
+	testLimit(evalContext(context.Background(), "while (true) {}"), object.LimitDeadline)
+
+	MaxSteps, Timeout = 0, 0
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	evaluated := evalContext(ctx, "while (true) {}")
+	testLimit(evaluated, object.LimitDeadline)
+	if !errors.Is(evaluated.(*object.Error).Cause, context.DeadlineExceeded) {
+		t.Errorf("Cause does not wrap context.DeadlineExceeded")
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	testLimit(evalContext(ctx, "while (true) {}"), object.LimitCanceled)
+}
+
+func TestSandbox(t *testing.T) {
+	var out bytes.Buffer
+	r := object.NewDefaultRegistry()
+	r.Output = &out
+	e := NewSandboxed(r, &object.Policy{
+		Modules:        []string{"core", "io"},
+		Capabilities:   []object.Capability{object.CapOutput},
+		MaxArrayLen:    3,
+		MaxStringLen:   5,
+		MaxOutputBytes: 8,
+		MaxSteps:       1000,
+	})
+	run := func(input string) object.Object {
+		return e.Eval(testParseProgram(input), object.NewEnv())
+	}
+	testSandboxError := func(evaluated object.Object, want string) {
+		t.Helper()
+		err, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Fatalf("Object is not Error got %T", evaluated)
+		}
+		var sandboxErr *object.SandboxError
+		if !errors.As(err.Cause, &sandboxErr) {
+			t.Fatalf("Cause is not a SandboxError got %v", err.Cause)
+		}
+		if err.Message != want {
+			t.Errorf("wrong message want %q got %q", want, err.Message)
+		}
+	}
+
+	testIngegerObject(t, run("len([1, 2, 3])"), 3)
+	testSandboxError(run("sin(1)"), "Sandbox: sin is not allowed")
+	testSandboxError(run("[1, 2, 3, 4]"), "Sandbox: array size limit of 3 exceeded")
+	testSandboxError(run("push([1, 2, 3], 4)"), "Sandbox: array size limit of 3 exceeded")
+	testSandboxError(run(`"abc" + "def"`), "Sandbox: string size limit of 5 exceeded")
+
+	testSandboxError(run(`print("abc"); print("def"); print("ghi")`), "Sandbox: output limit of 8 exceeded")
+	if out.String() != "abc\ndef\n" {
+		t.Errorf("wrong output got %q", out.String())
+	}
+
+	evaluated := e.EvalContext(context.Background(), testParseProgram("while (true) {}"), object.NewEnv())
+	var limit *object.LimitError
+	if err, ok := evaluated.(*object.Error); !ok || !errors.As(err.Cause, &limit) {
+		t.Errorf("expected the step limit of the sandbox got %v", evaluated)
+	}
+
+	e = NewSandboxed(r, &object.Policy{Builtins: []string{"print"}})
+	testSandboxError(run(`print("abc")`), "Sandbox: print is not allowed")
+}
+
+func TestRegistry(t *testing.T) {
+	var out1, out2 bytes.Buffer
+	r1 := object.NewDefaultRegistry()
+	r1.Output = &out1
+	r1.RegisterFunc("double", func(args ...object.Object) object.Object {
+		return &object.Integer{Value: 2 * args[0].(*object.Integer).Value}
+	})
+	r2 := object.NewDefaultRegistry()
+	r2.Output = &out2
+
+	e1, e2 := New(r1), New(r2)
+	testIngegerObject(t, e1.Eval(testParseProgram("double(21)"), object.NewEnv()), 42)
+	testErrorObject(t, e2.Eval(testParseProgram("double(21)"), object.NewEnv()), "Identifier Not Found: double")
+
+	e1.Eval(testParseProgram(`print("one"); printf("%d-%s", 2, "x")`), object.NewEnv())
+	e2.Eval(testParseProgram(`print(toFloat("1.5"))`), object.NewEnv())
+	if out1.String() != "one\n2-x\n" {
+		t.Errorf("wrong output of the first registry got %q", out1.String())
+	}
+	if out2.String() != "1.500000\n" {
+		t.Errorf("wrong output of the second registry got %q", out2.String())
+	}
+}
+
+func TestCaller(t *testing.T) {
+	r := object.NewDefaultRegistry()
+	r.Register("twice", &object.BuiltIn{CallFn: func(c object.Caller, args ...object.Object) object.Object {
+		res, err := c.Call(args[0], args[1])
+		if err != nil {
+			return object.ErrorFrom(err)
+		}
+		res, err = c.Call(args[0], res)
+		if err != nil {
+			return object.ErrorFrom(err)
+		}
+		return res
+	}})
+	r.Bind("apply", func(f func(int) int, x int) int { return f(x) })
+
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"twice(func(x) { x * 3 }, 2)", 18},
+		{"apply(func(x) { x + 1 }, 41)", 42},
+		{"twice(func(x) { apply(func(y) { y * 2 }, x) }, 5)", 20},
+		{"twice(func(x) { x + missing }, 1)", "Identifier Not Found: missing"},
+		{`apply(func(x) { x + "a" }, 1)`, "Type Mismatch INTEGER + STRING"},
+	}
+	for _, tt := range tests {
+		e := New(r)
+		evaluated := e.Eval(testParseProgram(tt.input), object.NewEnv())
+		switch expected := tt.expected.(type) {
+		case int:
+			testIngegerObject(t, evaluated, expected)
+		case string:
+			testErrorObject(t, evaluated, expected)
+		}
+	}
+
+	e := New(nil)
+	env := object.NewEnv()
+	fn := e.Eval(testParseProgram("func(a, b) { a * b }"), env)
+	res, err := e.Call(fn, &object.Integer{Value: 6}, &object.Integer{Value: 7})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	testIngegerObject(t, res, 42)
+}
+
+func TestCollections(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"map([1, 2, 3], func(x) { x * 2 })", "[2, 4, 6]"},
+		{"filter([1, 2, 3, 4], func(x) { x > 2 })", "[3, 4]"},
+		{"reduce([1, 2, 3, 4], func(acc, x) { acc + x })", "10"},
+		{"reduce([], func(acc, x) { acc + x }, 5)", "5"},
+		{"reduce([], func(acc, x) { acc + x })", "ERROR : Cant reduce an empty ARRAY without an initial Value"},
+		{"find([1, 2, 3], func(x) { x > 1 })", "2"},
+		{"find([1, 2, 3], func(x) { x > 5 })", "null"},
+		{"any([1, 2], func(x) { x > 1 })", "true"},
+		{"all([1, 2], func(x) { x > 1 })", "false"},
+		{"all([], func(x) { false })", "true"},
+		{`zip([1, 2, 3], ["a", "b"])`, "[[1, a], [2, b]]"},
+		{`enumerate(["a", "b"])`, "[[0, a], [1, b]]"},
+		{"flatten([[1, [2]], [3], 4])", "[1, [2], 3, 4]"},
+		{"flatten([[1, [2]], [3], 4], 2)", "[1, 2, 3, 4]"},
+		{`unique([1, 2, 1, "a", "a", 1.5, 1.5, true])`, "[1, 2, a, 1.500000, true]"},
+		{`groupBy(["ab", "c", "de"], func(s) { len(s) })[2]`, "[ab, de]"},
+		{`groupBy(["ab", "c", "de", "fgh"], func(s) { len(s) })`, "{1: [c], 2: [ab, de], 3: [fgh]}"},
+		{`{"b": 1, "a": 2, "c": {"z": 1, "y": 2}}`, "{a: 2, b: 1, c: {y: 2, z: 1}}"},
+		{"sort([3, 1, 2])", "[1, 2, 3]"},
+		{`sort(["b", "c", "a"])`, "[a, b, c]"},
+		{`sort([2.5, 1, 1.5])`, "[1, 1.500000, 2.500000]"},
+		{"sort([1, 3, 2], func(a, b) { b - a })", "[3, 2, 1]"},
+		{`sort([[2, "a"], [1, "b"], [2, "c"], [1, "d"]], func(a, b) { a[0] < b[0] })`, "[[1, b], [1, d], [2, a], [2, c]]"},
+		{`sort([1, "a"])`, "ERROR : Cant compare STRING and INTEGER"},
+		{`sort([1, 2], func(a, b) { "a" })`, "ERROR : Comparator has to return an INTEGER or BOOLEAN got STRING"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("%s: want %s got %s", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+
+	testErrorObject(t, testEval(`map([1], func(x) { x + "a" })`), "Type Mismatch INTEGER + STRING")
+}
+
+func TestStringBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`split("a,b,,c", ",")`, "[a, b, , c]"},
+		{`join(["a", "b", "c"], "-")`, "a-b-c"},
+		{`join(["a", 1], "-")`, "ERROR : Element on Position 1 is Not a STRING"},
+		{`replace("banana", "an", "AN")`, "bANANa"},
+		{`contains("grüße", "üß")`, "true"},
+		{`startsWith("monkey", "mon")`, "true"},
+		{`endsWith("monkey", "mon")`, "false"},
+		{`indexOf("grüße", "ß")`, "3"},
+		{`indexOf("grüße", "x")`, "-1"},
+		{`len("héllo")`, "5"},
+		{`var s = "héllo"; s[indexOf(s, "l")]`, "l"},
+		{`var s = "héllo"; s[1] + s[len(s) - 1]`, "éo"},
+		{`"héllo"[5]`, "null"},
+		{`"héllo"[-1]`, "null"},
+		{`trim("  hi  ")`, "hi"},
+		{`trim("xxhixx", "x")`, "hi"},
+		{`upper("héllo")`, "HÉLLO"},
+		{`lower("ÄÖÜ")`, "äöü"},
+		{`repeat("ab", 3)`, "ababab"},
+		{`repeat("ab", -1)`, "ERROR : Cant repeat a STRING -1 times"},
+		{`repeat("ab", 9223372036854775807)`, "ERROR : Cant repeat a STRING 9223372036854775807 times"},
+		{`repeat("", 9223372036854775807)`, ""},
+		{`padLeft("7", 3, "0")`, "007"},
+		{`padLeft("ü", 3)`, "  ü"},
+		{`padRight("ab", 5, "xy")`, "abxyx"},
+		{`padRight("abc", 2)`, "abc"},
+		{`padLeft("ab", 9223372036854775807, "x")`, "ERROR : Cant pad a STRING to 9223372036854775807 Characters"},
+		{`padRight("ab", 9223372036854775807)`, "ERROR : Cant pad a STRING to 9223372036854775807 Characters"},
+		{`chars("héllo")`, "[h, é, l, l, o]"},
+		{`ord("€")`, "8364"},
+		{`ord("ab")`, `ERROR : ord expects a single Character got "ab"`},
+		{`chr(8364)`, "€"},
+		{`chr(-1)`, "ERROR : -1 is Not a valid Character"},
+		{`format("%s is %d", "x", 4)`, "x is 4"},
+		{`format("%v", [1])`, "ERROR : Object of Type ARRAY cant be Formated"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("%s: want %s got %s", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestRegex(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`match(/(\d+)-(\d+)/, "on 2024-05 ok")`, "[2024-05, 2024, 05]"},
+		{`match(/x/, "abc")`, "null"},
+		{`match(/(a)|(b)/, "b")`, "[b, null, b]"},
+		{`match(/(?P<year>\d{4})-(?P<month>\d\d)/, "2024-05")["month"]`, "05"},
+		{`matchAll(/\d+/, "a1b22c333")`, "[[1], [22], [333]]"},
+		{`matchAll(/(?P<k>\w+)=(?P<v>\w+)/, "a=1 b=2")[1]["v"]`, "2"},
+		{`replace("a1b22", /\d+/, "#")`, "a#b#"},
+		{`replace("john smith", /(?P<first>\w+) (?P<last>\w+)/, "${last} ${first}")`, "smith john"},
+		{`split("a, b,c", /,\s*/)`, "[a, b, c]"},
+		{`match(regex("^AB"), "abc")`, "null"},
+		{`match(/^ab/i, "ABc")`, "[AB]"},
+		{`typeof(/a/)`, "REGEX"},
+		{`/a+/`, "/a+/"},
+		{`100 / 2 / 5`, "10"},
+		{`regex("(")`, "ERROR : Invalid Regex /(/: error parsing regexp: missing closing ): `(`"},
+		{`match(1, "a")`, "ERROR : Argument 1 has to be of Type REGEX got INTEGER"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("%s: want %s got %s", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestJSON(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`jsonParse("[1, 2.5, null, {}]")`, "[1, 2.500000, null, {}]"},
+		{`jsonStringify({"b": [1, "x"], "a": true})`, `{"a":true,"b":[1,"x"]}`},
+		{`jsonStringify(jsonParse(jsonStringify({"n": {"m": [1]}})))`, `{"n":{"m":[1]}}`},
+		{`jsonStringify([func(x) { x }])`, "ERROR : Object of Type FUNCTION_OBJ cant be converted to JSON"},
+		{`jsonParse("{")`, "ERROR : Invalid JSON: unexpected EOF"},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("%s: want %s got %s", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestCSV(t *testing.T) {
+	input := `var rows = csvParse("name,n
+a,1
+b,2.5
+", {"header": true});
+rows[1]["n"] + rows[0]["n"]`
+	evaluated := testEval(input)
+	if evaluated.Inspect() != "3.500000" {
+		t.Errorf("wrong sum of the csv column got %s", evaluated.Inspect())
+	}
+
+	evaluated = testEval(`csvStringify([["a", 1], ["b;c", 2]], {"delimiter": ";"})`)
+	if evaluated.Inspect() != "a;1\n\"b;c\";2\n" {
+		t.Errorf("wrong csv got %q", evaluated.Inspect())
+	}
+}
@@ -0,0 +1,195 @@
+package eval
+
+import (
+	"fmt"
+
+	"github.com/Arch-4ng3l/Monkey/ast"
+	"github.com/Arch-4ng3l/Monkey/object"
+)
+
+func DefineMacros(program *ast.Program, env *object.Env) {
+	definitions := []int{}
+
+	for i, stmt := range program.Statements {
+		if isMacroDefinition(stmt) {
+			addMacro(stmt, env)
+			definitions = append(definitions, i)
+		}
+	}
+
+	for i := len(definitions) - 1; i >= 0; i-- {
+		idx := definitions[i]
+		program.Statements = append(program.Statements[:idx], program.Statements[idx+1:]...)
+	}
+}
+
+func isMacroDefinition(node ast.Statement) bool {
+	let, ok := node.(*ast.LetStatement)
+	if !ok || let.Name == nil {
+		return false
+	}
+
+	_, ok = let.Value.(*ast.MacroLiteral)
+	return ok
+}
+
+func addMacro(stmt ast.Statement, env *object.Env) {
+	let := stmt.(*ast.LetStatement)
+	lit := let.Value.(*ast.MacroLiteral)
+
+	env.Set(let.Name.Value, &object.Macro{
+		Params: lit.Params,
+		Env:    env,
+		Body:   lit.Body,
+	})
+}
+
+func ExpandMacros(program *ast.Program, env *object.Env) (*ast.Program, error) {
+	var err error
+
+	expanded := ast.Modify(program, func(node ast.Node) ast.Node {
+		call, ok := node.(*ast.CallExpression)
+		if !ok || err != nil {
+			return node
+		}
+
+		macro, ok := isMacroCall(call, env)
+		if !ok {
+			return node
+		}
+
+		args := quoteArgs(call)
+		evalEnv := extendMacroEnv(macro, args)
+
+		evaluated := Eval(macro.Body, evalEnv)
+
+		quote, ok := evaluated.(*object.Quote)
+		if !ok {
+			err = fmt.Errorf("Macro %s has to return a QUOTE got %s", call.Function.String(), typeName(evaluated))
+			return node
+		}
+
+		return hygienic(quote.Node, call.Args, env)
+	})
+
+	return expanded.(*ast.Program), err
+}
+
+func typeName(obj object.Object) object.ObjectType {
+	if obj == nil {
+		return object.NULL
+	}
+	return obj.Type()
+}
+
+func isMacroCall(call *ast.CallExpression, env *object.Env) (*object.Macro, bool) {
+	ident, ok := call.Function.(*ast.Ident)
+	if !ok {
+		return nil, false
+	}
+
+	obj, ok := env.Get(ident.Value)
+	if !ok {
+		return nil, false
+	}
+
+	macro, ok := obj.(*object.Macro)
+	return macro, ok
+}
+
+func quoteArgs(call *ast.CallExpression) []*object.Quote {
+	args := []*object.Quote{}
+
+	for _, a := range call.Args {
+		args = append(args, &object.Quote{Node: a})
+	}
+
+	return args
+}
+
+func extendMacroEnv(macro *object.Macro, args []*object.Quote) *object.Env {
+	extended := object.NewEnclosedEnv(macro.Env)
+
+	for i, param := range macro.Params {
+		if i < len(args) {
+			extended.Set(param.Value, args[i])
+		}
+	}
+
+	return extended
+}
+
+// hygienic renames every variable and parameter the macro body declares
+// itself, so it can neither capture nor shadow identifiers passed in by the
+// caller. The new names come from env, so they stay unique across expansions.
+func hygienic(expanded ast.Node, args []ast.Expression, env *object.Env) ast.Node {
+	fromCaller := map[*ast.Ident]bool{}
+	for _, a := range args {
+		ast.Walk(a, func(n ast.Node) {
+			if ident, ok := n.(*ast.Ident); ok {
+				fromCaller[ident] = true
+			}
+		})
+	}
+
+	renames := map[string]string{}
+	ast.Walk(expanded, func(n ast.Node) {
+		for _, ident := range declaredIdents(n) {
+			if fromCaller[ident] {
+				continue
+			}
+			if _, ok := renames[ident.Value]; !ok {
+				renames[ident.Value] = env.Gensym(ident.Value)
+			}
+		}
+	})
+
+	if len(renames) == 0 {
+		return expanded
+	}
+
+	return ast.Modify(expanded, func(n ast.Node) ast.Node {
+		ident, ok := n.(*ast.Ident)
+		if !ok || fromCaller[ident] {
+			return n
+		}
+		name, ok := renames[ident.Value]
+		if !ok {
+			return n
+		}
+		renamed := *ident
+		renamed.Value = name
+		return &renamed
+	})
+}
+
+// declaredIdents returns the identifiers n binds, the names of var statements
+// and of parameters and the identifiers in their patterns.
+func declaredIdents(n ast.Node) []*ast.Ident {
+	idents := []*ast.Ident{}
+	patterns := []ast.Expression{}
+	switch n := n.(type) {
+	case *ast.LetStatement:
+		if n.Name != nil {
+			idents = append(idents, n.Name)
+		}
+		patterns = append(patterns, n.Pattern)
+	case *ast.ForInLoop:
+		patterns = append(patterns, n.Pattern)
+	case *ast.FunctionLiteral:
+		idents = append(idents, n.Params...)
+		patterns = append(patterns, n.ParamPatterns...)
+	}
+
+	for _, pattern := range patterns {
+		if pattern == nil {
+			continue
+		}
+		ast.Walk(pattern, func(p ast.Node) {
+			if ident, ok := p.(*ast.Ident); ok {
+				idents = append(idents, ident)
+			}
+		})
+	}
+	return idents
+}
@@ -0,0 +1,89 @@
+package eval
+
+import (
+	"strings"
+
+	"github.com/Arch-4ng3l/Monkey/ast"
+	"github.com/Arch-4ng3l/Monkey/object"
+)
+
+// checkMatches rejects a program with a match that is not exhaustive before
+// any of it runs, the way the compiler does. Arms resolve to the enums
+// declared in program or, for names it does not declare, to the variants
+// bound in env. Matches with an arm that does not resolve are left to run.
+func checkMatches(program *ast.Program, env *object.Env) *object.Error {
+	variants := map[string]object.Object{}
+	ast.Walk(program, func(node ast.Node) {
+		stmt, ok := node.(*ast.EnumStatement)
+		if !ok {
+			return
+		}
+		enum := &object.Enum{Name: stmt.Name.Value, Arity: make(map[string]int)}
+		for _, v := range stmt.Variants {
+			enum.Variants = append(enum.Variants, v.Name.Value)
+			enum.Arity[v.Name.Value] = len(v.Params)
+		}
+		for i, member := range object.NewEnumMembers(enum) {
+			name := enum.Variants[i]
+			if _, ok := variants[name]; ok {
+				// declared by two enums, the arm depends on which one is in scope
+				variants[name] = nil
+				continue
+			}
+			variants[name] = member
+		}
+	})
+
+	var err *object.Error
+	ast.Walk(program, func(node ast.Node) {
+		match, ok := node.(*ast.MatchExpression)
+		if !ok || err != nil {
+			return
+		}
+		err = checkArms(match, func(i int) (object.Object, bool) {
+			name := match.Arms[i].Variant.Value
+			if variant, ok := variants[name]; ok {
+				return variant, variant != nil
+			}
+			return env.Get(name)
+		})
+	})
+	return err
+}
+
+// checkArms checks the arms of node against the values variant resolves them
+// to. It gives up without an error on the first arm variant cant resolve.
+func checkArms(node *ast.MatchExpression, variant func(arm int) (object.Object, bool)) *object.Error {
+	var enum *object.Enum
+	covered := map[string]bool{}
+	wildcard := false
+	for i, arm := range node.Arms {
+		if arm.IsWildcard() {
+			wildcard = true
+			continue
+		}
+		obj, ok := variant(i)
+		if !ok {
+			return nil
+		}
+		en, name, ok := object.VariantOf(obj)
+		if !ok {
+			return newError("%s is not an Enum Variant", arm.Variant.Value)
+		}
+		if enum != nil && en.Name != enum.Name {
+			return newError("Cant match %s and %s in the same match", enum.Name, en.Name)
+		}
+		if len(arm.Bindings) != en.Arity[name] {
+			return newError("%s: Want %d Bindings got %d", arm.Variant.Value, en.Arity[name], len(arm.Bindings))
+		}
+		enum = en
+		covered[name] = true
+	}
+
+	if enum != nil && !wildcard {
+		if missing := enum.Missing(covered); len(missing) > 0 {
+			return newError("Match on %s is not exhaustive missing %s", enum.Name, strings.Join(missing, ", "))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package eval
+
+import (
+	"fmt"
+
+	"github.com/Arch-4ng3l/Monkey/ast"
+	"github.com/Arch-4ng3l/Monkey/object"
+	"github.com/Arch-4ng3l/Monkey/token"
+)
+
+func (e *Evaluator) quote(node ast.Node, env *object.Env) object.Object {
+	node = e.evalUnquoteCalls(node, env)
+	return &object.Quote{Node: node}
+}
+
+func (e *Evaluator) evalUnquoteCalls(quoted ast.Node, env *object.Env) ast.Node {
+	return ast.Modify(quoted, func(node ast.Node) ast.Node {
+		call, ok := node.(*ast.CallExpression)
+		if !ok || call.Function.TokenLiteral() != "unquote" || len(call.Args) != 1 {
+			return node
+		}
+
+		unquoted := e.Eval(call.Args[0], env)
+		if converted := convertObjectToASTNode(unquoted); converted != nil {
+			return converted
+		}
+		return node
+	})
+}
+
+func convertObjectToASTNode(obj object.Object) ast.Node {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		t := token.Token{Type: token.INT, Literal: fmt.Sprintf("%d", obj.Value)}
+		return &ast.IntLiteral{Token: t, Value: int64(obj.Value)}
+
+	case *object.Float:
+		t := token.Token{Type: token.FLOAT, Literal: fmt.Sprintf("%f", obj.Value)}
+		return &ast.FloatLiteral{Token: t, Value: obj.Value}
+
+	case *object.String:
+		t := token.Token{Type: token.STR, Literal: obj.Value}
+		return &ast.StrLiteral{Token: t, Value: obj.Value}
+
+	case *object.Regex:
+		t := token.Token{Type: token.REGEX, Literal: obj.Value.String()}
+		return &ast.RegexLiteral{Token: t, Value: obj.Value.String()}
+
+	case *object.Boolean:
+		if obj.Value {
+			return &ast.Boolean{Token: token.Token{Type: token.TRUE, Literal: "true"}, Value: true}
+		}
+		return &ast.Boolean{Token: token.Token{Type: token.FALSE, Literal: "false"}, Value: false}
+
+	case *object.Array:
+		arr := &ast.ArrayLiteral{Token: token.Token{Type: token.LBRACKET, Literal: "["}}
+		for _, el := range obj.Elements {
+			node, ok := convertObjectToASTNode(el).(ast.Expression)
+			if !ok {
+				return nil
+			}
+			arr.Elements = append(arr.Elements, node)
+		}
+		return arr
+
+	case *object.Quote:
+		return obj.Node
+
+	default:
+		return nil
+	}
+}
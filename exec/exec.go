@@ -10,6 +10,7 @@ import (
 	"github.com/Arch-4ng3l/Monkey/lexer"
 	"github.com/Arch-4ng3l/Monkey/object"
 	"github.com/Arch-4ng3l/Monkey/parser"
+	"github.com/Arch-4ng3l/Monkey/typecheck"
 )
 
 func ExecCodeWithInterpreter(code string) string {
@@ -33,8 +34,22 @@ func ExecCodeWithInterpreter(code string) string {
 		outC <- buf.String()
 	}()
 
-	fmt.Println("start Eval")
-	eval.Eval(program, env)
+	macroEnv := object.NewEnv()
+	eval.DefineMacros(program, macroEnv)
+	program, err := eval.ExpandMacros(program, macroEnv)
+
+	if err != nil {
+		fmt.Println("MACRO ERROR: " + err.Error())
+	} else if errs := typecheck.New(nil).Check(program); len(errs) != 0 {
+		for _, err := range errs {
+			fmt.Println("TYPE ERROR: " + err)
+		}
+	} else {
+		fmt.Println("start Eval")
+		if err, ok := eval.Eval(program, env).(*object.Error); ok {
+			fmt.Println(err.Message)
+		}
+	}
 
 	fmt.Println("Done eval")
 	w.Close()
@@ -66,8 +81,22 @@ func ExecCodeWithComp(code string) string {
 		outC <- buf.String()
 	}()
 
-	fmt.Println("start Eval")
-	eval.Eval(program, env)
+	macroEnv := object.NewEnv()
+	eval.DefineMacros(program, macroEnv)
+	program, err := eval.ExpandMacros(program, macroEnv)
+
+	if err != nil {
+		fmt.Println("MACRO ERROR: " + err.Error())
+	} else if errs := typecheck.New(nil).Check(program); len(errs) != 0 {
+		for _, err := range errs {
+			fmt.Println("TYPE ERROR: " + err)
+		}
+	} else {
+		fmt.Println("start Eval")
+		if err, ok := eval.Eval(program, env).(*object.Error); ok {
+			fmt.Println(err.Message)
+		}
+	}
 
 	fmt.Println("Done eval")
 	w.Close()
@@ -1,6 +1,8 @@
 package lexer
 
 import (
+	"strings"
+
 	"github.com/Arch-4ng3l/Monkey/token"
 )
 
@@ -9,6 +11,7 @@ type Lexer struct {
 	position     int
 	readPosition int
 	char         byte
+	last         token.TokenType
 }
 
 func NewLexer(input string) *Lexer {
@@ -61,6 +64,12 @@ func (l *Lexer) peakChar() byte {
 }
 
 func (l *Lexer) NextToken() token.Token {
+	tok := l.nextToken()
+	l.last = tok.Type
+	return tok
+}
+
+func (l *Lexer) nextToken() token.Token {
 	var tok token.Token
 	l.skipWhitespace()
 	switch l.char {
@@ -70,6 +79,10 @@ func (l *Lexer) NextToken() token.Token {
 			l.readChar()
 
 			tok = token.Token{Type: token.EQ, Literal: literal}
+		} else if l.peakChar() == '>' {
+			literal := "=>"
+			l.readChar()
+			tok = token.Token{Type: token.FAT_ARROW, Literal: literal}
 		} else {
 			tok = newToken(token.ASSIGN, l.char)
 		}
@@ -94,6 +107,38 @@ func (l *Lexer) NextToken() token.Token {
 		tok = newToken(token.COMMA, l.char)
 	case ';':
 		tok = newToken(token.SEMICOLON, l.char)
+	case ':':
+		tok = newToken(token.COLON, l.char)
+	case '.':
+		if l.peakChar() == '.' && l.readPosition+1 < len(l.input) && l.input[l.readPosition+1] == '.' {
+			l.readChar()
+			l.readChar()
+			tok = token.Token{Type: token.ELLIPSIS, Literal: "..."}
+		} else {
+			tok = newToken(token.ILLEGAL, l.char)
+		}
+	case '?':
+		switch l.peakChar() {
+		case '?':
+			l.readChar()
+			tok = token.Token{Type: token.COALESCE, Literal: "??"}
+		case '[':
+			l.readChar()
+			tok = token.Token{Type: token.OPT_INDEX, Literal: "?["}
+		case '.':
+			l.readChar()
+			tok = token.Token{Type: token.OPT_DOT, Literal: "?."}
+		default:
+			tok = newToken(token.QUESTION, l.char)
+		}
+	case '|':
+		if l.peakChar() == '>' {
+			literal := "|>"
+			l.readChar()
+			tok = token.Token{Type: token.PIPE, Literal: literal}
+		} else {
+			tok = newToken(token.ILLEGAL, l.char)
+		}
 	case '(':
 		tok = newToken(token.LPAREN, l.char)
 	case ')':
@@ -107,6 +152,10 @@ func (l *Lexer) NextToken() token.Token {
 			literal := "-="
 			l.readChar()
 			tok = token.Token{Type: token.MINUS_ASSIGN, Literal: literal}
+		} else if l.peakChar() == '>' {
+			literal := "->"
+			l.readChar()
+			tok = token.Token{Type: token.ARROW, Literal: literal}
 		} else {
 			tok = newToken(token.MINUS, l.char)
 		}
@@ -119,7 +168,9 @@ func (l *Lexer) NextToken() token.Token {
 			tok = newToken(token.STAR, l.char)
 		}
 	case '/':
-		if l.peakChar() == '=' {
+		if regex, ok := l.readRegex(); ok {
+			tok = regex
+		} else if l.peakChar() == '=' {
 			literal := "/="
 			l.readChar()
 			tok = token.Token{Type: token.SLASH_ASSIGN, Literal: literal}
@@ -136,9 +187,13 @@ func (l *Lexer) NextToken() token.Token {
 		}
 	case '>':
 		if l.peakChar() == '=' {
-			literal := "<="
+			literal := ">="
 			l.readChar()
 			tok = token.Token{Type: token.GT_EQ, Literal: literal}
+		} else if l.peakChar() == '>' {
+			literal := ">>"
+			l.readChar()
+			tok = token.Token{Type: token.COMPOSE, Literal: literal}
 		} else {
 			tok = newToken(token.GT, l.char)
 		}
@@ -195,6 +250,51 @@ func (l *Lexer) readStr() string {
 	return l.input[pos:l.position]
 }
 
+// readRegex reads /pattern/flags where no operand came before the /, so it
+// cant be a division. The flags become inline flags of the pattern, so /a+/i
+// is read as (?i)a+. A / without a closing / on the same line stays a SLASH.
+func (l *Lexer) readRegex() (token.Token, bool) {
+	if !l.regexAllowed() || l.peakChar() == '*' {
+		return token.Token{}, false
+	}
+
+	end := l.readPosition
+	for end < len(l.input) && l.input[end] != '/' && l.input[end] != '\n' {
+		if l.input[end] == '\\' {
+			end++
+		}
+		end++
+	}
+	if end >= len(l.input) || l.input[end] != '/' {
+		return token.Token{}, false
+	}
+	pattern := l.input[l.readPosition:end]
+	for l.position < end {
+		l.readChar()
+	}
+
+	flags := ""
+	for l.peakChar() != 0 && strings.IndexByte("imsU", l.peakChar()) >= 0 {
+		l.readChar()
+		flags += string(l.char)
+	}
+	if flags != "" {
+		pattern = "(?" + flags + ")" + pattern
+	}
+	return token.Token{Type: token.REGEX, Literal: pattern}, true
+}
+
+// regexAllowed reports whether a / can start a regex literal, which is the
+// case wherever no operand came before it.
+func (l *Lexer) regexAllowed() bool {
+	switch l.last {
+	case token.IDENT, token.INT, token.FLOAT, token.STR, token.REGEX,
+		token.TRUE, token.FALSE, token.RPAREN, token.RBRACKET:
+		return false
+	}
+	return true
+}
+
 func isLetter(char byte) bool {
 	return 'a' <= char && char <= 'z' || 'A' <= char && char <= 'Z' || char == '_'
 }
@@ -93,3 +93,49 @@ func TestNextToken(t *testing.T) {
 	}
 
 }
+
+func TestRegex(t *testing.T) {
+	input := `var re = /(\d+)\/(?P<x>[a-z])/i; a / b / 2; f(/x/)[0] /= 2; !-/*5;`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.LET, "var"},
+		{token.IDENT, "re"},
+		{token.ASSIGN, "="},
+		{token.REGEX, `(?i)(\d+)\/(?P<x>[a-z])`},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "a"},
+		{token.SLASH, "/"},
+		{token.IDENT, "b"},
+		{token.SLASH, "/"},
+		{token.INT, "2"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "f"},
+		{token.LPAREN, "("},
+		{token.REGEX, "x"},
+		{token.RPAREN, ")"},
+		{token.LBRACKET, "["},
+		{token.INT, "0"},
+		{token.RBRACKET, "]"},
+		{token.SLASH_ASSIGN, "/="},
+		{token.INT, "2"},
+		{token.SEMICOLON, ";"},
+		{token.BANG, "!"},
+		{token.MINUS, "-"},
+		{token.SLASH, "/"},
+		{token.STAR, "*"},
+		{token.INT, "5"},
+		{token.SEMICOLON, ";"},
+		{token.EOF, ""},
+	}
+
+	l := NewLexer(input)
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType || tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tested[%d] - expected %s %q got %s %q", i, tt.expectedType, tt.expectedLiteral, tok.Type, tok.Literal)
+		}
+	}
+}
@@ -1,10 +1,13 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"os"
 
-	"github.com/Arch-4ng3l/Monkey/exec"
+	"github.com/Arch-4ng3l/Monkey/monkey"
+	"github.com/Arch-4ng3l/Monkey/object"
 	"github.com/Arch-4ng3l/Monkey/repl"
 )
 
@@ -12,12 +15,34 @@ func main() {
 	input := os.Stdin
 
 	if len(os.Args) != 1 {
-		fileName := os.Args[1]
-		content, _ := os.ReadFile(fileName)
-		output := exec.ExecCodeWithInterpreter(string(content))
-		fmt.Println("output " + output)
+		os.Exit(run(os.Args[1], os.Args[2:]))
 	} else {
 		repl.StartComp(input, os.Stdout)
 	}
 
 }
+
+// run executes the script in fileName with args and returns the exit status,
+// which is 1 for parse and runtime errors unless the script called exit.
+func run(fileName string, args []string) int {
+	content, err := os.ReadFile(fileName)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	interp := monkey.New(monkey.Options{Args: args})
+	if err := interp.Compile(string(content)); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if _, err := interp.Run(context.Background()); err != nil {
+		var exit *object.ExitError
+		if errors.As(err, &exit) {
+			return exit.Code
+		}
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
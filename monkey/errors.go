@@ -0,0 +1,88 @@
+package monkey
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/Arch-4ng3l/Monkey/object"
+)
+
+var (
+	ErrNotCompiled = errors.New("monkey: nothing to run, call Compile first")
+	ErrUndefined   = errors.New("monkey: undefined global")
+)
+
+// ParseError lists the syntax errors of a source.
+type ParseError struct {
+	Errors []string
+}
+
+func (e *ParseError) Error() string {
+	return "parse error: " + strings.Join(e.Errors, "; ")
+}
+
+// TypeError lists what the type checker rejected.
+type TypeError struct {
+	Errors []string
+}
+
+func (e *TypeError) Error() string {
+	return "type error: " + strings.Join(e.Errors, "; ")
+}
+
+// CompileError is returned when macro expansion or the compiler rejects a
+// program.
+type CompileError struct {
+	Errors []string
+}
+
+func (e *CompileError) Error() string {
+	return "compile error: " + strings.Join(e.Errors, "; ")
+}
+
+// RuntimeError is returned when a program fails while running. Err is the Go
+// error behind it, like a *object.LimitError, when there is one.
+type RuntimeError struct {
+	Message string
+	Err     error
+}
+
+func (e *RuntimeError) Error() string {
+	return "runtime error: " + e.Message
+}
+
+func (e *RuntimeError) Unwrap() error {
+	return e.Err
+}
+
+func runtimeError(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &RuntimeError{Message: err.Error(), Err: err}
+}
+
+// recoverRuntimeError turns a panic of an engine into a *RuntimeError in err,
+// so a bug in an engine fails the run instead of the host.
+func recoverRuntimeError(err *error) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	cause, ok := r.(error)
+	if !ok {
+		cause = fmt.Errorf("%v", r)
+	}
+	*err = &RuntimeError{Message: "internal error: " + cause.Error(), Err: cause}
+}
+
+func fromObject(obj object.Object) (object.Object, error) {
+	if obj == nil {
+		return object.NullVal, nil
+	}
+	if err, ok := obj.(*object.Error); ok {
+		return nil, &RuntimeError{Message: err.Message, Err: err.Cause}
+	}
+	return obj, nil
+}
@@ -0,0 +1,73 @@
+package monkey
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Arch-4ng3l/Monkey/ast"
+	"github.com/Arch-4ng3l/Monkey/eval"
+	"github.com/Arch-4ng3l/Monkey/object"
+)
+
+type evalInterpreter struct {
+	frontend
+	evaluator *eval.Evaluator
+	env       *object.Env
+	program   *ast.Program
+}
+
+func newEvalInterpreter(opts Options, r *object.Registry) *evalInterpreter {
+	e := eval.New(r)
+	if opts.Sandbox != nil {
+		e = eval.NewSandboxed(r, opts.Sandbox)
+	}
+	if opts.MaxCallDepth > 0 {
+		e.MaxCallDepth = opts.MaxCallDepth
+	}
+	e.MaxSteps = opts.MaxSteps
+	e.Timeout = opts.Timeout
+
+	return &evalInterpreter{frontend: newFrontend(r), evaluator: e, env: object.NewEnv()}
+}
+
+func (i *evalInterpreter) Compile(source string) error {
+	program, err := i.parse(source)
+	if err != nil {
+		return err
+	}
+	i.program = program
+	return nil
+}
+
+func (i *evalInterpreter) Run(ctx context.Context) (res object.Object, err error) {
+	defer recoverRuntimeError(&err)
+	if i.program == nil {
+		return nil, ErrNotCompiled
+	}
+	return fromObject(i.evaluator.EvalContext(ctx, i.program, i.env))
+}
+
+func (i *evalInterpreter) Call(name string, args ...object.Object) (res object.Object, err error) {
+	defer recoverRuntimeError(&err)
+	fn, ok := i.GetGlobal(name)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUndefined, name)
+	}
+	return fromObject(i.evaluator.Apply(fn, args...))
+}
+
+func (i *evalInterpreter) Caller() object.Caller {
+	return i.evaluator
+}
+
+func (i *evalInterpreter) GetGlobal(name string) (object.Object, bool) {
+	if val, ok := i.env.Get(name); ok {
+		return val, true
+	}
+	return i.evaluator.Builtins.Lookup(name)
+}
+
+func (i *evalInterpreter) SetGlobal(name string, val object.Object) error {
+	_, err := fromObject(i.env.Set(name, val))
+	return err
+}
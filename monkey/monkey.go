@@ -0,0 +1,124 @@
+// Package monkey embeds the Monkey language in Go programs.
+package monkey
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/Arch-4ng3l/Monkey/ast"
+	"github.com/Arch-4ng3l/Monkey/eval"
+	"github.com/Arch-4ng3l/Monkey/lexer"
+	"github.com/Arch-4ng3l/Monkey/object"
+	"github.com/Arch-4ng3l/Monkey/parser"
+	"github.com/Arch-4ng3l/Monkey/typecheck"
+)
+
+type Engine int
+
+const (
+	// EngineEval runs programs with the tree-walking evaluator.
+	EngineEval Engine = iota
+	// EngineVM compiles programs to bytecode and runs them on the vm.
+	EngineVM
+)
+
+// Interpreter runs Monkey source. Compile can be called again after Run, the
+// globals of earlier runs stay defined.
+type Interpreter interface {
+	// Compile parses source and prepares it for the next Run.
+	Compile(source string) error
+	// Run executes what was compiled last and returns the value of its last
+	// statement.
+	Run(ctx context.Context) (object.Object, error)
+	// Call calls the global function called name.
+	Call(name string, args ...object.Object) (object.Object, error)
+	GetGlobal(name string) (object.Object, bool)
+	SetGlobal(name string, val object.Object) error
+	// Caller calls function values, like ones returned by GetGlobal, on the
+	// engine of the Interpreter.
+	Caller() object.Caller
+}
+
+type Options struct {
+	Engine Engine
+
+	// Stdout and Stdin replace os.Stdout and os.Stdin for the program.
+	Stdout io.Writer
+	Stdin  io.Reader
+	// Files replaces the file system of the host for the file builtins.
+	Files object.FileSystem
+	// Args are returned by args and Env, when set, replaces the environment
+	// of env.
+	Args []string
+	Env  func(name string) (string, bool)
+
+	// Builtins is the Registry the program sees, a new default Registry when
+	// nil. The options above are set on it.
+	Builtins *object.Registry
+	// Sandbox, when set, restricts the program to what the Policy allows.
+	Sandbox *object.Policy
+
+	// Zero values keep the defaults of the engine.
+	MaxCallDepth int
+	MaxSteps     int
+	Timeout      time.Duration
+}
+
+func New(opts Options) Interpreter {
+	r := opts.Builtins
+	if r == nil {
+		r = object.NewDefaultRegistry()
+	}
+	if opts.Stdout != nil {
+		r.Output = opts.Stdout
+	}
+	if opts.Stdin != nil {
+		r.Input = opts.Stdin
+	}
+	if opts.Files != nil {
+		r.Files = opts.Files
+	}
+	if opts.Args != nil {
+		r.Args = opts.Args
+	}
+	if opts.Env != nil {
+		r.Env = opts.Env
+	}
+	if opts.Sandbox != nil {
+		r = opts.Sandbox.Apply(r)
+	}
+
+	if opts.Engine == EngineVM {
+		return newVMInterpreter(opts, r)
+	}
+	return newEvalInterpreter(opts, r)
+}
+
+// frontend runs the steps both engines share before a program runs.
+type frontend struct {
+	macros  *object.Env
+	checker *typecheck.Checker
+}
+
+func newFrontend(r *object.Registry) frontend {
+	return frontend{macros: object.NewEnv(), checker: typecheck.New(r)}
+}
+
+func (f frontend) parse(source string) (*ast.Program, error) {
+	p := parser.NewParser(lexer.NewLexer(source))
+	program := p.ParseProgram()
+	if errs := p.Errors(); len(errs) != 0 {
+		return nil, &ParseError{Errors: errs}
+	}
+
+	eval.DefineMacros(program, f.macros)
+	program, err := eval.ExpandMacros(program, f.macros)
+	if err != nil {
+		return nil, &CompileError{Errors: []string{err.Error()}}
+	}
+	if errs := f.checker.Check(program); len(errs) != 0 {
+		return nil, &TypeError{Errors: errs}
+	}
+	return program, nil
+}
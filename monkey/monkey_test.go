@@ -0,0 +1,399 @@
+package monkey
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Arch-4ng3l/Monkey/object"
+)
+
+var engines = map[string]Engine{"eval": EngineEval, "vm": EngineVM}
+
+func TestInterpreter(t *testing.T) {
+	for name, engine := range engines {
+		t.Run(name, func(t *testing.T) {
+			var out bytes.Buffer
+			interp := New(Options{Engine: engine, Stdout: &out})
+
+			if _, err := interp.Run(context.Background()); !errors.Is(err, ErrNotCompiled) {
+				t.Errorf("expected ErrNotCompiled got %v", err)
+			}
+
+			if err := interp.SetGlobal("base", &object.Integer{Value: 10}); err != nil {
+				t.Fatalf("%s", err)
+			}
+			if err := interp.Compile(`var add = func(a, b) { a + b + base }; print("hello"); add(1, 2)`); err != nil {
+				t.Fatalf("%s", err)
+			}
+			res, err := interp.Run(context.Background())
+			if err != nil {
+				t.Fatalf("%s", err)
+			}
+			testInteger(t, res, 13)
+			if out.String() != "hello\n" {
+				t.Errorf("wrong output got %q", out.String())
+			}
+
+			res, err = interp.Call("add", &object.Integer{Value: 4}, &object.Integer{Value: 5})
+			if err != nil {
+				t.Fatalf("%s", err)
+			}
+			testInteger(t, res, 19)
+
+			if err := interp.Compile("var total = add(base, base)"); err != nil {
+				t.Fatalf("%s", err)
+			}
+			if _, err := interp.Run(context.Background()); err != nil {
+				t.Fatalf("%s", err)
+			}
+			total, ok := interp.GetGlobal("total")
+			if !ok {
+				t.Fatalf("total is not defined")
+			}
+			testInteger(t, total, 30)
+
+			if _, err := interp.Call("missing"); !errors.Is(err, ErrUndefined) {
+				t.Errorf("expected ErrUndefined got %v", err)
+			}
+		})
+	}
+}
+
+func TestInterpreterErrors(t *testing.T) {
+	for name, engine := range engines {
+		t.Run(name, func(t *testing.T) {
+			interp := New(Options{Engine: engine, MaxSteps: 1000})
+
+			var parseErr *ParseError
+			if err := interp.Compile("var = 1"); !errors.As(err, &parseErr) {
+				t.Errorf("expected a ParseError got %v", err)
+			}
+
+			if err := interp.Compile("while (true) {}"); err != nil {
+				t.Fatalf("%s", err)
+			}
+			_, err := interp.Run(context.Background())
+			var runtimeErr *RuntimeError
+			if !errors.As(err, &runtimeErr) {
+				t.Fatalf("expected a RuntimeError got %v", err)
+			}
+			var limit *object.LimitError
+			if !errors.As(err, &limit) || limit.Kind != object.LimitSteps {
+				t.Errorf("expected the step limit got %v", err)
+			}
+
+			if err := interp.Compile("const c = 1"); err != nil {
+				t.Fatalf("%s", err)
+			}
+			if _, err := interp.Run(context.Background()); err != nil {
+				t.Fatalf("%s", err)
+			}
+			if err := interp.SetGlobal("c", &object.Integer{Value: 2}); err == nil {
+				t.Errorf("expected an error assigning to a constant")
+			}
+		})
+	}
+}
+
+func TestInterpreterPanics(t *testing.T) {
+	for name, engine := range engines {
+		t.Run(name, func(t *testing.T) {
+			r := object.NewDefaultRegistry()
+			r.RegisterFunc("boom", func(args ...object.Object) object.Object {
+				panic("boom")
+			})
+			interp := New(Options{Engine: engine, Builtins: r})
+			if err := interp.Compile("var f = func() { boom() }; boom()"); err != nil {
+				t.Fatalf("%s", err)
+			}
+
+			_, err := interp.Run(context.Background())
+			var runtimeErr *RuntimeError
+			if !errors.As(err, &runtimeErr) || err.Error() != "runtime error: internal error: boom" {
+				t.Errorf("expected the panic as a RuntimeError got %v", err)
+			}
+			_, err = interp.Call("f")
+			if !errors.As(err, &runtimeErr) {
+				t.Errorf("expected the panic of Call as a RuntimeError got %v", err)
+			}
+		})
+	}
+}
+
+func TestInterpreterSandbox(t *testing.T) {
+	for name, engine := range engines {
+		t.Run(name, func(t *testing.T) {
+			interp := New(Options{Engine: engine, Sandbox: &object.Policy{Modules: []string{"core"}}})
+
+			err := interp.Compile(`print("hi")`)
+			if err == nil {
+				_, err = interp.Run(context.Background())
+			}
+			if err == nil {
+				t.Errorf("expected print to be denied")
+			}
+		})
+	}
+}
+
+func TestSandboxLimits(t *testing.T) {
+	policy := &object.Policy{
+		Modules:        []string{"core", "io", "strings", "random"},
+		Capabilities:   []object.Capability{object.CapOutput},
+		MaxArrayLen:    4,
+		MaxStringLen:   8,
+		MaxOutputBytes: 10,
+	}
+	run := func(engine Engine, out *bytes.Buffer, input string) error {
+		interp := New(Options{Engine: engine, Stdout: out, Sandbox: policy})
+		if err := interp.Compile(input); err != nil {
+			return err
+		}
+		_, err := interp.Run(context.Background())
+		return err
+	}
+
+	for name, engine := range engines {
+		t.Run(name, func(t *testing.T) {
+			for i := 0; i < 3; i++ {
+				var out bytes.Buffer
+				if err := run(engine, &out, `print("abcd")`); err != nil {
+					t.Fatalf("run %d: %s", i, err)
+				}
+				if out.String() != "abcd\n" {
+					t.Errorf("run %d: wrong output got %q", i, out.String())
+				}
+			}
+
+			tests := []struct {
+				input    string
+				expected string
+			}{
+				{`split(",,,,", ",")`, "Sandbox: array size limit of 4 exceeded"},
+				{`split("abcde", "")`, "Sandbox: array size limit of 4 exceeded"},
+				{`split("12345", /[0-9]/)`, "Sandbox: array size limit of 4 exceeded"},
+				{`chars("abcde")`, "Sandbox: array size limit of 4 exceeded"},
+				{`join(["abcd", "efgh"], "-")`, "Sandbox: string size limit of 8 exceeded"},
+				{`randIntArray(10, 1000000000000, false)`, "Sandbox: array size limit of 4 exceeded"},
+				{`repeat("ab", 1000000000000)`, "Sandbox: string size limit of 8 exceeded"},
+				{`padLeft("ab", 1000000000000, "x")`, "Sandbox: string size limit of 8 exceeded"},
+				{`padRight("ab", 9, "ü")`, "Sandbox: string size limit of 8 exceeded"},
+			}
+			for _, tt := range tests {
+				err := run(engine, &bytes.Buffer{}, tt.input)
+				var sandboxErr *object.SandboxError
+				if !errors.As(err, &sandboxErr) || !strings.Contains(err.Error(), tt.expected) {
+					t.Errorf("%s: expected %q got %v", tt.input, tt.expected, err)
+				}
+			}
+		})
+	}
+}
+
+func testInteger(t *testing.T, obj object.Object, want int) {
+	t.Helper()
+	i, ok := obj.(*object.Integer)
+	if !ok {
+		t.Fatalf("object is not Integer got %T (%v)", obj, obj)
+	}
+	if i.Value != want {
+		t.Errorf("wrong value want %d got %d", want, i.Value)
+	}
+}
+
+func TestBind(t *testing.T) {
+	type user struct {
+		Name string `monkey:"name"`
+		Age  int    `monkey:"age"`
+	}
+
+	for name, engine := range engines {
+		t.Run(name, func(t *testing.T) {
+			r := object.NewDefaultRegistry()
+			r.Bind("greet", func(u user) string { return "hi " + u.Name })
+			r.Bind("older", func(u user, years int) user {
+				u.Age += years
+				return u
+			})
+
+			interp := New(Options{Engine: engine, Builtins: r})
+			if err := interp.Compile(`var u = older({"name": "ada", "age": 36}, 1); greet(u) + " " + toStr(u["age"])`); err != nil {
+				t.Fatalf("%s", err)
+			}
+			res, err := interp.Run(context.Background())
+			if err != nil {
+				t.Fatalf("%s", err)
+			}
+			if res.Inspect() != "hi ada 37" {
+				t.Errorf("wrong result got %q", res.Inspect())
+			}
+
+			u, _ := interp.GetGlobal("u")
+			var got user
+			if err := object.FromObject(u, &got); err != nil || got != (user{"ada", 37}) {
+				t.Errorf("wrong user got %#v (%v)", got, err)
+			}
+		})
+	}
+}
+
+func TestCaller(t *testing.T) {
+	for name, engine := range engines {
+		t.Run(name, func(t *testing.T) {
+			r := object.NewDefaultRegistry()
+			r.Bind("each", func(n int, f func(int) int) int {
+				sum := 0
+				for i := 0; i < n; i++ {
+					sum += f(i)
+				}
+				return sum
+			})
+
+			interp := New(Options{Engine: engine, Builtins: r})
+			if err := interp.Compile(`var square = func(x) { x * x }; each(4, square)`); err != nil {
+				t.Fatalf("%s", err)
+			}
+			res, err := interp.Run(context.Background())
+			if err != nil {
+				t.Fatalf("%s", err)
+			}
+			if res.Inspect() != "14" {
+				t.Errorf("wrong result got %q", res.Inspect())
+			}
+
+			square, _ := interp.GetGlobal("square")
+			var f func(int) int
+			if err := object.FromObjectWith(interp.Caller(), square, &f); err != nil {
+				t.Fatalf("%s", err)
+			}
+			if got := f(9); got != 81 {
+				t.Errorf("wrong result of the callback got %d", got)
+			}
+
+			for i := 0; i < 20000; i++ {
+				if _, err := interp.Call("square", &object.String{Value: "s"}); err == nil {
+					t.Fatalf("expected an error squaring a STRING")
+				}
+			}
+			res, err = interp.Call("square", &object.Integer{Value: 3})
+			if err != nil {
+				t.Fatalf("calling after failed calls: %s", err)
+			}
+			testInteger(t, res, 9)
+		})
+	}
+}
+
+func TestFiles(t *testing.T) {
+	for name, engine := range engines {
+		t.Run(name, func(t *testing.T) {
+			files := object.NewMemFileSystem(map[string]string{"data/in.txt": "3\n4\n"})
+			interp := New(Options{
+				Engine:  engine,
+				Files:   files,
+				Sandbox: &object.Policy{Modules: []string{"core", "collections", "files", "path"}, Capabilities: []object.Capability{object.CapFileRead, object.CapFileWrite}},
+			})
+
+			src := `var nums = map(readLines(pathJoin("data", "in.txt")), func(l) { toInt(l) });
+writeFile("data/out.txt", toStr(reduce(nums, func(a, b) { a + b })));
+listDir("data")`
+			if err := interp.Compile(src); err != nil {
+				t.Fatalf("%s", err)
+			}
+			res, err := interp.Run(context.Background())
+			if err != nil {
+				t.Fatalf("%s", err)
+			}
+			if res.Inspect() != "[in.txt, out.txt]" {
+				t.Errorf("wrong entries got %s", res.Inspect())
+			}
+			if data, err := files.ReadFile("data/out.txt"); err != nil || string(data) != "7" {
+				t.Errorf("wrong output file got %q (%v)", data, err)
+			}
+
+			denied := New(Options{Engine: engine, Files: files, Sandbox: &object.Policy{Modules: []string{"files"}, Capabilities: []object.Capability{object.CapFileRead}}})
+			err = denied.Compile(`remove("data/out.txt")`)
+			if err == nil {
+				_, err = denied.Run(context.Background())
+			}
+			if err == nil {
+				t.Errorf("expected remove to need the file write capability")
+			}
+		})
+	}
+}
+
+func TestStdin(t *testing.T) {
+	for name, engine := range engines {
+		t.Run(name, func(t *testing.T) {
+			var out bytes.Buffer
+			interp := New(Options{Engine: engine, Stdin: strings.NewReader("3\n1\n2\n"), Stdout: &out})
+
+			src := `var n = toInt(input("count: ")); var s = 0; for (l in lines()) { s += toInt(l) }; s * n`
+			if err := interp.Compile(src); err != nil {
+				t.Fatalf("%s", err)
+			}
+			res, err := interp.Run(context.Background())
+			if err != nil {
+				t.Fatalf("%s", err)
+			}
+			testInteger(t, res, 9)
+			if out.String() != "count: " {
+				t.Errorf("wrong prompt got %q", out.String())
+			}
+		})
+	}
+}
+
+func TestSystem(t *testing.T) {
+	env := map[string]string{"USER": "ada"}
+	lookup := func(name string) (string, bool) {
+		v, ok := env[name]
+		return v, ok
+	}
+
+	for name, engine := range engines {
+		t.Run(name, func(t *testing.T) {
+			interp := New(Options{Engine: engine, Args: []string{"in.txt", "-v"}, Env: lookup})
+			if err := interp.Compile(`var a = args(); a[1] + " " + env("USER") + " " + toStr(env("HOME") ?? "none")`); err != nil {
+				t.Fatalf("%s", err)
+			}
+			res, err := interp.Run(context.Background())
+			if err != nil {
+				t.Fatalf("%s", err)
+			}
+			if res.Inspect() != "-v ada none" {
+				t.Errorf("wrong result got %q", res.Inspect())
+			}
+
+			if err := interp.Compile(`var f = func(x) { if (x > 2) { exit(x) }; x }; f(1); f(7); 0`); err != nil {
+				t.Fatalf("%s", err)
+			}
+			_, err = interp.Run(context.Background())
+			var exit *object.ExitError
+			if !errors.As(err, &exit) || exit.Code != 7 {
+				t.Errorf("expected exit status 7 got %v", err)
+			}
+
+			hidden := New(Options{Engine: engine, Env: object.NoEnv})
+			if err := hidden.Compile(`env("PATH")`); err != nil {
+				t.Fatalf("%s", err)
+			}
+			if res, err := hidden.Run(context.Background()); err != nil || res != object.NullVal {
+				t.Errorf("expected the environment to be hidden got %v (%v)", res, err)
+			}
+
+			denied := New(Options{Engine: engine, Sandbox: &object.Policy{Modules: []string{"system"}}})
+			err = denied.Compile(`env("PATH")`)
+			if err == nil {
+				_, err = denied.Run(context.Background())
+			}
+			if err == nil {
+				t.Errorf("expected env to need the env capability")
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package monkey
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Arch-4ng3l/Monkey/compiler"
+	"github.com/Arch-4ng3l/Monkey/object"
+	"github.com/Arch-4ng3l/Monkey/vm"
+)
+
+type vmInterpreter struct {
+	frontend
+	opts      Options
+	builtins  *object.Registry
+	symbols   *compiler.SymbolTable
+	constants []object.Object
+	globals   []object.Object
+	bytecode  *compiler.Bytecode
+	machine   *vm.Vm
+}
+
+func newVMInterpreter(opts Options, r *object.Registry) *vmInterpreter {
+	symbols := compiler.NewSymbolTable()
+	compiler.DefineBuiltins(symbols, r)
+
+	return &vmInterpreter{
+		frontend:  newFrontend(r),
+		opts:      opts,
+		builtins:  r,
+		symbols:   symbols,
+		constants: []object.Object{},
+		globals:   make([]object.Object, vm.GlobalSize),
+	}
+}
+
+func (i *vmInterpreter) Compile(source string) error {
+	program, err := i.parse(source)
+	if err != nil {
+		return err
+	}
+
+	comp := compiler.NewWithState(i.builtins, i.symbols, i.constants)
+	if err := comp.Compile(program); err != nil {
+		return &CompileError{Errors: []string{err.Error()}}
+	}
+	i.bytecode = comp.Bytecode()
+	i.constants = i.bytecode.Constants
+	return nil
+}
+
+func (i *vmInterpreter) Run(ctx context.Context) (res object.Object, err error) {
+	defer recoverRuntimeError(&err)
+	if i.bytecode == nil {
+		return nil, ErrNotCompiled
+	}
+
+	machine := vm.NewWithGLobalStore(i.bytecode, i.globals)
+	if i.opts.Sandbox != nil {
+		machine.Sandbox(i.opts.Sandbox)
+	}
+	if i.opts.MaxCallDepth > 0 {
+		machine.MaxCallDepth = i.opts.MaxCallDepth
+	}
+	if i.opts.MaxSteps > 0 {
+		machine.MaxSteps = i.opts.MaxSteps
+	}
+	machine.Timeout = i.opts.Timeout
+	i.machine = machine
+
+	if err := machine.RunContext(ctx); err != nil {
+		return nil, runtimeError(err)
+	}
+	return fromObject(machine.LastPoppedStackElement())
+}
+
+func (i *vmInterpreter) Call(name string, args ...object.Object) (res object.Object, err error) {
+	defer recoverRuntimeError(&err)
+	fn, ok := i.GetGlobal(name)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUndefined, name)
+	}
+	res, err = i.Caller().Call(fn, args...)
+	if err != nil {
+		return nil, runtimeError(err)
+	}
+	return fromObject(res)
+}
+
+// Caller returns the Vm of the last Run, or a Vm without a program before the
+// first one.
+func (i *vmInterpreter) Caller() object.Caller {
+	if i.machine == nil {
+		i.machine = vm.NewWithGLobalStore(&compiler.Bytecode{Builtins: i.builtins}, i.globals)
+		if i.opts.Sandbox != nil {
+			i.machine.Sandbox(i.opts.Sandbox)
+		}
+	}
+	return i.machine
+}
+
+func (i *vmInterpreter) GetGlobal(name string) (object.Object, bool) {
+	symbol, ok := i.symbols.Resolve(name)
+	if !ok {
+		return nil, false
+	}
+	switch {
+	case symbol.Variant != nil:
+		return symbol.Variant, true
+	case symbol.Scope == compiler.BuiltinScope:
+		return i.builtins.Lookup(name)
+	}
+	val := i.globals[symbol.Index]
+	return val, val != nil
+}
+
+func (i *vmInterpreter) SetGlobal(name string, val object.Object) error {
+	symbol, ok := i.symbols.Resolve(name)
+	if ok && symbol.Constant {
+		return &RuntimeError{Message: fmt.Sprintf("Cant assign to Constant %s", name)}
+	}
+	if !ok || symbol.Scope != compiler.GlobalScope || symbol.Variant != nil {
+		symbol = i.symbols.Define(name)
+	}
+	i.globals[symbol.Index] = val
+	return nil
+}
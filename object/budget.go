@@ -0,0 +1,58 @@
+package object
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// checkInterval is how many steps pass between looking at the clock and the
+// context, which are too slow to check on every step.
+const checkInterval = 1024
+
+// Budget counts the steps of a run and stops it once a limit is hit. A step is
+// one evaluated node for the evaluator and one instruction for the vm.
+type Budget struct {
+	ctx      context.Context
+	maxSteps int
+	deadline time.Time
+	steps    int
+}
+
+// NewBudget returns a Budget for ctx. A maxSteps or timeout of 0 means no
+// limit.
+func NewBudget(ctx context.Context, maxSteps int, timeout time.Duration) *Budget {
+	b := &Budget{ctx: ctx, maxSteps: maxSteps}
+	if timeout > 0 {
+		b.deadline = time.Now().Add(timeout)
+	}
+	return b
+}
+
+func (b *Budget) Steps() int {
+	return b.steps
+}
+
+func (b *Budget) Step() error {
+	b.steps++
+	if b.maxSteps > 0 && b.steps > b.maxSteps {
+		return &LimitError{Kind: LimitSteps, Steps: b.maxSteps}
+	}
+	if b.steps%checkInterval != 0 {
+		return nil
+	}
+	return b.Check()
+}
+
+func (b *Budget) Check() error {
+	if !b.deadline.IsZero() && time.Now().After(b.deadline) {
+		return &LimitError{Kind: LimitDeadline, Steps: b.steps, Err: context.DeadlineExceeded}
+	}
+	if err := b.ctx.Err(); err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			return &LimitError{Kind: LimitDeadline, Steps: b.steps, Err: err}
+		}
+		return &LimitError{Kind: LimitCanceled, Steps: b.steps, Err: err}
+	}
+	return nil
+}
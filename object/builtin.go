@@ -2,8 +2,11 @@ package object
 
 import (
 	"fmt"
+	"io"
 	"math/rand"
+	"os"
 	"strconv"
+	"unicode/utf8"
 )
 
 func length(args ...Object) Object {
@@ -14,7 +17,7 @@ func length(args ...Object) Object {
 	switch arg := args[0].(type) {
 	case *String:
 		return &Integer{
-			Value: len(arg.Value),
+			Value: utf8.RuneCountInString(arg.Value),
 		}
 	case *Array:
 		return &Integer{
@@ -49,64 +52,53 @@ func push(args ...Object) Object {
 }
 
 func print(args ...Object) Object {
-	for _, arg := range args {
-		fmt.Println(arg.Inspect())
-	}
-	return NullVal
+	return printTo(stdout)(args...)
 }
 
-func sort(args ...Object) Object {
-	if len(args) != 1 {
-		return argumentAmountError(1, len(args))
-	}
-	arrObj, ok := args[0].(*Array)
-	if !ok {
-		return argumentTypeError(ARR_OBJ, args[0].Type(), 1)
-	}
-	var elements []*Integer
-	for i, obj := range arrObj.Elements {
-		if obj, ok := obj.(*Integer); ok {
-			elements = append(elements, obj)
-			continue
-		} else {
-			return newError("Element on Position %d is Not an INTEGER", i)
+func printTo(out func() io.Writer) BuiltInFunction {
+	return func(args ...Object) Object {
+		for _, arg := range args {
+			if _, err := fmt.Fprintln(out(), arg.Inspect()); err != nil {
+				return &Error{Message: err.Error(), Cause: err}
+			}
 		}
-
-	}
-
-	quickSort(elements, 0, len(elements)-1)
-
-	var objArr []Object
-	for _, e := range elements {
-		objArr = append(objArr, e)
+		return NullVal
 	}
+}
 
-	return &Array{
-		Elements: objArr,
+func freeze(args ...Object) Object {
+	if len(args) != 1 {
+		return argumentAmountError(1, len(args))
 	}
-}
 
-func quickSort(arr []*Integer, low, high int) {
-	if low < high {
-		pivot := partition(arr, low, high)
-		quickSort(arr, low, pivot-1)
-		quickSort(arr, pivot+1, high)
+	switch arg := args[0].(type) {
+	case *Array, *Hash:
+		Freeze(arg)
+		return arg
+	default:
+		return newError("Object of Type %s cant be frozen", arg.Type())
 	}
 }
 
-func partition(arr []*Integer, low, high int) int {
-	pivot := arr[high].Value
-	i := low - 1
-
-	for j := low; j < high; j++ {
-		if arr[j].Value < pivot {
-			i++
-			arr[i], arr[j] = arr[j], arr[i]
+func Freeze(obj Object) {
+	switch obj := obj.(type) {
+	case *Array:
+		if obj.Frozen {
+			return
+		}
+		obj.Frozen = true
+		for _, el := range obj.Elements {
+			Freeze(el)
+		}
+	case *Hash:
+		if obj.Frozen {
+			return
+		}
+		obj.Frozen = true
+		for _, pair := range obj.Pairs {
+			Freeze(pair.Value)
 		}
 	}
-
-	arr[i+1], arr[high] = arr[high], arr[i+1]
-	return i + 1
 }
 
 func typeof(args ...Object) Object {
@@ -155,6 +147,20 @@ func randFloat(args ...Object) Object {
 
 }
 
+// randIntArrayWithin checks the length against p before it fills the array.
+func randIntArrayWithin(p *Policy) BuiltInFunction {
+	return func(args ...Object) Object {
+		if len(args) >= 2 {
+			if l, ok := args[1].(*Integer); ok {
+				if err := p.CheckArrayLen(l.Value); err != nil {
+					return ErrorFrom(err)
+				}
+			}
+		}
+		return randIntArray(args...)
+	}
+}
+
 func randIntArray(args ...Object) Object {
 
 	if len(args) != 2 && len(args) != 3 {
@@ -207,13 +213,36 @@ func randIntArray(args ...Object) Object {
 }
 
 func printf(args ...Object) Object {
+	return printfTo(stdout)(args...)
+}
+
+func printfTo(out func() io.Writer) BuiltInFunction {
+	return func(args ...Object) Object {
+		return formatTo(out(), args...)
+	}
+}
+
+func formatTo(w io.Writer, args ...Object) Object {
+	formatedStr, err := sprintf(args...)
+	if err != nil {
+		return err
+	}
+
+	if _, err := fmt.Fprintln(w, formatedStr); err != nil {
+		return &Error{Message: err.Error(), Cause: err}
+	}
+
+	return NullVal
+}
+
+func sprintf(args ...Object) (string, *Error) {
 	if len(args) < 1 {
-		return argumentAmountError(1, len(args))
+		return "", argumentAmountError(1, len(args))
 	}
 
 	str, ok := args[0].(*String)
 	if !ok {
-		return argumentTypeError(STR_OBJ, args[0].Type(), 1)
+		return "", argumentTypeError(STR_OBJ, args[0].Type(), 1)
 	}
 	list := []any{}
 	for _, arg := range args[1:] {
@@ -227,14 +256,16 @@ func printf(args ...Object) Object {
 		case *String:
 			list = append(list, arg.Value)
 		default:
-			return newError("Object of Type %s cant be Formated", arg.Type())
+			return "", newError("Object of Type %s cant be Formated", arg.Type())
 		}
 	}
-	formatedStr := formatString(str.Value, list...)
-
-	fmt.Println(formatedStr)
+	return formatString(str.Value, list...), nil
+}
 
-	return NullVal
+// stdout is looked up on every call so hosts that swap os.Stdout still see
+// the output.
+func stdout() io.Writer {
+	return os.Stdout
 }
 
 func toStr(args ...Object) Object {
@@ -244,6 +275,8 @@ func toStr(args ...Object) Object {
 	}
 
 	switch arg := args[0].(type) {
+	case *String:
+		return arg
 	case *Integer:
 		return &String{
 			Value: fmt.Sprintf("%d", arg.Value),
@@ -252,6 +285,10 @@ func toStr(args ...Object) Object {
 		return &String{
 			Value: fmt.Sprintf("%f", arg.Value),
 		}
+	case *Boolean, *EnumVariant:
+		return &String{
+			Value: arg.Inspect(),
+		}
 	default:
 		return newError("Object of Type %s cant be converted to String", arg.Type())
 	}
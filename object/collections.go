@@ -0,0 +1,412 @@
+package object
+
+import (
+	gosort "sort"
+	"strings"
+)
+
+func arrayArg(args []Object, pos int) (*Array, *Error) {
+	arr, ok := args[pos].(*Array)
+	if !ok {
+		return nil, argumentTypeError(ARR_OBJ, args[pos].Type(), pos+1)
+	}
+	return arr, nil
+}
+
+func callWith(c Caller, fn Object, args ...Object) Object {
+	res, err := c.Call(fn, args...)
+	if err != nil {
+		return ErrorFrom(err)
+	}
+	return res
+}
+
+func truthy(obj Object) bool {
+	switch obj := obj.(type) {
+	case *Boolean:
+		return obj.Value
+	case *Null:
+		return false
+	default:
+		return true
+	}
+}
+
+func mapArray(c Caller, args ...Object) Object {
+	if len(args) != 2 {
+		return argumentAmountError(2, len(args))
+	}
+	arr, err := arrayArg(args, 0)
+	if err != nil {
+		return err
+	}
+
+	elements := make([]Object, len(arr.Elements))
+	for i, el := range arr.Elements {
+		res := callWith(c, args[1], el)
+		if isError(res) {
+			return res
+		}
+		elements[i] = res
+	}
+	return &Array{Elements: elements}
+}
+
+func filter(c Caller, args ...Object) Object {
+	if len(args) != 2 {
+		return argumentAmountError(2, len(args))
+	}
+	arr, err := arrayArg(args, 0)
+	if err != nil {
+		return err
+	}
+
+	elements := []Object{}
+	for _, el := range arr.Elements {
+		res := callWith(c, args[1], el)
+		if isError(res) {
+			return res
+		}
+		if truthy(res) {
+			elements = append(elements, el)
+		}
+	}
+	return &Array{Elements: elements}
+}
+
+// reduce folds arr from the left, starting with the first element when no
+// initial value is given.
+func reduce(c Caller, args ...Object) Object {
+	if len(args) != 2 && len(args) != 3 {
+		return argumentAmountError(3, len(args))
+	}
+	arr, err := arrayArg(args, 0)
+	if err != nil {
+		return err
+	}
+
+	elements := arr.Elements
+	var acc Object
+	if len(args) == 3 {
+		acc = args[2]
+	} else {
+		if len(elements) == 0 {
+			return newError("Cant reduce an empty ARRAY without an initial Value")
+		}
+		acc, elements = elements[0], elements[1:]
+	}
+	for _, el := range elements {
+		acc = callWith(c, args[1], acc, el)
+		if isError(acc) {
+			return acc
+		}
+	}
+	return acc
+}
+
+func find(c Caller, args ...Object) Object {
+	if len(args) != 2 {
+		return argumentAmountError(2, len(args))
+	}
+	arr, err := arrayArg(args, 0)
+	if err != nil {
+		return err
+	}
+
+	for _, el := range arr.Elements {
+		res := callWith(c, args[1], el)
+		if isError(res) {
+			return res
+		}
+		if truthy(res) {
+			return el
+		}
+	}
+	return NullVal
+}
+
+func anyOf(c Caller, args ...Object) Object {
+	return checkAll(c, true, args...)
+}
+
+func allOf(c Caller, args ...Object) Object {
+	return checkAll(c, false, args...)
+}
+
+// checkAll stops at the first element whose result is want, so any stops at
+// the first match and all at the first mismatch.
+func checkAll(c Caller, want bool, args ...Object) Object {
+	if len(args) != 2 {
+		return argumentAmountError(2, len(args))
+	}
+	arr, err := arrayArg(args, 0)
+	if err != nil {
+		return err
+	}
+
+	for _, el := range arr.Elements {
+		res := callWith(c, args[1], el)
+		if isError(res) {
+			return res
+		}
+		if truthy(res) == want {
+			return NativeBool(want)
+		}
+	}
+	return NativeBool(!want)
+}
+
+// zip stops at the end of the shortest array.
+func zip(args ...Object) Object {
+	if len(args) < 1 {
+		return argumentAmountError(1, len(args))
+	}
+
+	arrays := make([]*Array, len(args))
+	length := -1
+	for i := range args {
+		arr, err := arrayArg(args, i)
+		if err != nil {
+			return err
+		}
+		arrays[i] = arr
+		if length == -1 || len(arr.Elements) < length {
+			length = len(arr.Elements)
+		}
+	}
+
+	elements := make([]Object, length)
+	for i := range elements {
+		tuple := make([]Object, len(arrays))
+		for j, arr := range arrays {
+			tuple[j] = arr.Elements[i]
+		}
+		elements[i] = &Array{Elements: tuple}
+	}
+	return &Array{Elements: elements}
+}
+
+func enumerate(args ...Object) Object {
+	if len(args) != 1 {
+		return argumentAmountError(1, len(args))
+	}
+	arr, err := arrayArg(args, 0)
+	if err != nil {
+		return err
+	}
+
+	elements := make([]Object, len(arr.Elements))
+	for i, el := range arr.Elements {
+		elements[i] = &Array{Elements: []Object{&Integer{Value: i}, el}}
+	}
+	return &Array{Elements: elements}
+}
+
+// flatten removes one level of nesting, or depth levels when it is given.
+func flatten(args ...Object) Object {
+	if len(args) != 1 && len(args) != 2 {
+		return argumentAmountError(2, len(args))
+	}
+	arr, err := arrayArg(args, 0)
+	if err != nil {
+		return err
+	}
+	depth := 1
+	if len(args) == 2 {
+		d, ok := args[1].(*Integer)
+		if !ok {
+			return argumentTypeError(INTEGER_OBJ, args[1].Type(), 2)
+		}
+		depth = d.Value
+	}
+
+	return &Array{Elements: flattenElements(arr.Elements, depth)}
+}
+
+func flattenElements(elements []Object, depth int) []Object {
+	flat := []Object{}
+	for _, el := range elements {
+		if inner, ok := el.(*Array); ok && depth > 0 {
+			flat = append(flat, flattenElements(inner.Elements, depth-1)...)
+			continue
+		}
+		flat = append(flat, el)
+	}
+	return flat
+}
+
+// elementKey is the key unique and groupBy compare elements by.
+func elementKey(obj Object) (any, bool) {
+	switch obj := obj.(type) {
+	case Hashable:
+		return obj.HashKey(), true
+	case *Float:
+		return obj.Value, true
+	case *Null:
+		return obj, true
+	}
+	return nil, false
+}
+
+func unique(args ...Object) Object {
+	if len(args) != 1 {
+		return argumentAmountError(1, len(args))
+	}
+	arr, err := arrayArg(args, 0)
+	if err != nil {
+		return err
+	}
+
+	seen := map[any]bool{}
+	elements := []Object{}
+	for i, el := range arr.Elements {
+		key, ok := elementKey(el)
+		if !ok {
+			return newError("Element on Position %d of Type %s cant be compared", i, el.Type())
+		}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		elements = append(elements, el)
+	}
+	return &Array{Elements: elements}
+}
+
+// groupBy collects the elements of arr in a hash under the key fn returns for
+// them, keeping their order inside each group.
+func groupBy(c Caller, args ...Object) Object {
+	if len(args) != 2 {
+		return argumentAmountError(2, len(args))
+	}
+	arr, err := arrayArg(args, 0)
+	if err != nil {
+		return err
+	}
+
+	groups := &Hash{Pairs: map[HashKey]HashPair{}}
+	for _, el := range arr.Elements {
+		key := callWith(c, args[1], el)
+		if isError(key) {
+			return key
+		}
+		hashable, ok := key.(Hashable)
+		if !ok {
+			return newError("Unusable as Hash Key: %s", key.Type())
+		}
+		pair, ok := groups.Pairs[hashable.HashKey()]
+		if !ok {
+			pair = HashPair{Key: key, Value: &Array{Elements: []Object{}}}
+		}
+		group := pair.Value.(*Array)
+		group.Elements = append(group.Elements, el)
+		groups.Pairs[hashable.HashKey()] = pair
+	}
+	return groups
+}
+
+func sort(args ...Object) Object {
+	return sortWith(nil, args...)
+}
+
+// sortWith sorts a copy of arr with a stable sort. Without a comparator
+// numbers and strings are sorted ascending. A comparator gets two elements
+// and returns a negative INTEGER or true when the first one goes first.
+func sortWith(c Caller, args ...Object) Object {
+	if len(args) != 1 && len(args) != 2 {
+		return argumentAmountError(2, len(args))
+	}
+	arr, err := arrayArg(args, 0)
+	if err != nil {
+		return err
+	}
+
+	elements := make([]Object, len(arr.Elements))
+	copy(elements, arr.Elements)
+
+	less := func(a, b Object) (bool, Object) {
+		cmp, err := compareObjects(a, b)
+		return cmp < 0, err
+	}
+	if len(args) == 2 {
+		if c == nil {
+			return newError("Cant call the Comparator %s", args[1].Inspect())
+		}
+		less = func(a, b Object) (bool, Object) {
+			res := callWith(c, args[1], a, b)
+			switch res := res.(type) {
+			case *Integer:
+				return res.Value < 0, nil
+			case *Boolean:
+				return res.Value, nil
+			case *Error:
+				return false, res
+			default:
+				return false, newError("Comparator has to return an INTEGER or BOOLEAN got %s", res.Type())
+			}
+		}
+	}
+
+	var failed Object
+	gosort.SliceStable(elements, func(i, j int) bool {
+		if failed != nil {
+			return false
+		}
+		res, err := less(elements[i], elements[j])
+		if err != nil {
+			failed = err
+		}
+		return res
+	})
+	if failed != nil {
+		return failed
+	}
+
+	return &Array{Elements: elements}
+}
+
+func compareObjects(a, b Object) (int, Object) {
+	if x, ok := a.(*Integer); ok {
+		if y, ok := b.(*Integer); ok {
+			return compare(x.Value, y.Value), nil
+		}
+	}
+	switch a := a.(type) {
+	case *Integer, *Float:
+		x, okA := toNumber(a)
+		y, okB := toNumber(b)
+		if okA && okB {
+			return compare(x, y), nil
+		}
+	case *String:
+		if b, ok := b.(*String); ok {
+			return strings.Compare(a.Value, b.Value), nil
+		}
+	}
+	return 0, newError("Cant compare %s and %s", a.Type(), b.Type())
+}
+
+func compare[T int | float64](x, y T) int {
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	}
+	return 0
+}
+
+func toNumber(obj Object) (float64, bool) {
+	switch obj := obj.(type) {
+	case *Integer:
+		return float64(obj.Value), true
+	case *Float:
+		return obj.Value, true
+	}
+	return 0, false
+}
+
+func isError(obj Object) bool {
+	_, ok := obj.(*Error)
+	return ok
+}
@@ -0,0 +1,482 @@
+package object
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+)
+
+var (
+	objectType = reflect.TypeOf((*Object)(nil)).Elem()
+	errorType  = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// ToObject converts a Go value to the object a program sees. Slices and arrays
+// become arrays, maps and structs become hashes and functions become builtins
+// like the ones made by Bind. Objects are returned as they are.
+func ToObject(v any) (Object, error) {
+	if obj, ok := v.(Object); ok {
+		return obj, nil
+	}
+	if v == nil {
+		return NullVal, nil
+	}
+	return toObject(reflect.ValueOf(v))
+}
+
+// visit is a pointer, map or slice toObject is inside of.
+type visit struct {
+	ptr uintptr
+	typ reflect.Type
+}
+
+func toObject(v reflect.Value) (Object, error) {
+	return toObjectSeen(v, map[visit]bool{})
+}
+
+// toObjectSeen keeps the pointers, maps and slices it is inside of in seen,
+// so a cyclic value is an error instead of endless recursion.
+func toObjectSeen(v reflect.Value, seen map[visit]bool) (Object, error) {
+	if v.Type().Implements(objectType) {
+		if v.IsNil() {
+			return NullVal, nil
+		}
+		return v.Interface().(Object), nil
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Map, reflect.Slice:
+		if !v.IsNil() && (v.Kind() != reflect.Slice || v.Len() > 0) {
+			key := visit{ptr: v.Pointer(), typ: v.Type()}
+			if seen[key] {
+				return nil, fmt.Errorf("cant convert cyclic %s to an object", v.Type())
+			}
+			seen[key] = true
+			defer delete(seen, key)
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		return NativeBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &Integer{Value: int(v.Int())}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if v.Uint() > math.MaxInt {
+			return nil, fmt.Errorf("cant convert %d to an INTEGER", v.Uint())
+		}
+		return &Integer{Value: int(v.Uint())}, nil
+	case reflect.Float32, reflect.Float64:
+		return &Float{Value: v.Float()}, nil
+	case reflect.String:
+		return &String{Value: v.String()}, nil
+
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return NullVal, nil
+		}
+		return toObjectSeen(v.Elem(), seen)
+
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return NullVal, nil
+		}
+		elements := make([]Object, v.Len())
+		for i := range elements {
+			el, err := toObjectSeen(v.Index(i), seen)
+			if err != nil {
+				return nil, err
+			}
+			elements[i] = el
+		}
+		return &Array{Elements: elements}, nil
+
+	case reflect.Map:
+		if v.IsNil() {
+			return NullVal, nil
+		}
+		hash := &Hash{Pairs: make(map[HashKey]HashPair)}
+		iter := v.MapRange()
+		for iter.Next() {
+			if err := setPair(hash, iter.Key(), iter.Value(), seen); err != nil {
+				return nil, err
+			}
+		}
+		return hash, nil
+
+	case reflect.Struct:
+		hash := &Hash{Pairs: make(map[HashKey]HashPair)}
+		for i := 0; i < v.NumField(); i++ {
+			name, ok := fieldName(v.Type().Field(i))
+			if !ok {
+				continue
+			}
+			if err := setPair(hash, reflect.ValueOf(name), v.Field(i), seen); err != nil {
+				return nil, err
+			}
+		}
+		return hash, nil
+
+	case reflect.Func:
+		if v.IsNil() {
+			return NullVal, nil
+		}
+		return bindFunc(v), nil
+	}
+
+	return nil, fmt.Errorf("cant convert %s to an object", v.Type())
+}
+
+func setPair(hash *Hash, k, v reflect.Value, seen map[visit]bool) error {
+	key, err := toObjectSeen(k, seen)
+	if err != nil {
+		return err
+	}
+	hashable, ok := key.(Hashable)
+	if !ok {
+		return fmt.Errorf("cant use %s as a hash key", k.Type())
+	}
+	val, err := toObjectSeen(v, seen)
+	if err != nil {
+		return err
+	}
+	hash.Pairs[hashable.HashKey()] = HashPair{Key: key, Value: val}
+	return nil
+}
+
+// fieldName is the key of a struct field in a hash, its name unless a
+// `monkey:"name"` tag renames it. Fields tagged "-" and unexported fields are
+// left out.
+func fieldName(f reflect.StructField) (string, bool) {
+	if !f.IsExported() {
+		return "", false
+	}
+	switch tag := f.Tag.Get("monkey"); tag {
+	case "-":
+		return "", false
+	case "":
+		return f.Name, true
+	default:
+		return tag, true
+	}
+}
+
+// FromObject stores obj in the value target points to, converting it the
+// opposite way of ToObject.
+func FromObject(obj Object, target any) error {
+	return FromObjectWith(nil, obj, target)
+}
+
+// FromObjectWith is like FromObject but can also convert callable objects to
+// Go functions that call them with c.
+func FromObjectWith(c Caller, obj Object, target any) error {
+	v := reflect.ValueOf(target)
+	if v.Kind() != reflect.Pointer || v.IsNil() {
+		return errors.New("FromObject needs a non nil pointer")
+	}
+	return fromObject(obj, v.Elem(), c)
+}
+
+func fromObject(obj Object, v reflect.Value, c Caller) error {
+	if obj == nil {
+		obj = NullVal
+	}
+	if v.Kind() == reflect.Interface && v.NumMethod() == 0 {
+		if native := nativeValue(obj); native == nil {
+			v.Set(reflect.Zero(v.Type()))
+		} else {
+			v.Set(reflect.ValueOf(native))
+		}
+		return nil
+	}
+	if reflect.TypeOf(obj).AssignableTo(v.Type()) {
+		v.Set(reflect.ValueOf(obj))
+		return nil
+	}
+	if obj.Type() == NULL {
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer:
+		elem := reflect.New(v.Type().Elem())
+		if err := fromObject(obj, elem.Elem(), c); err != nil {
+			return err
+		}
+		v.Set(elem)
+		return nil
+
+	case reflect.Bool:
+		if b, ok := obj.(*Boolean); ok {
+			v.SetBool(b.Value)
+			return nil
+		}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if i, ok := obj.(*Integer); ok && !v.OverflowInt(int64(i.Value)) {
+			v.SetInt(int64(i.Value))
+			return nil
+		}
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if i, ok := obj.(*Integer); ok && i.Value >= 0 && !v.OverflowUint(uint64(i.Value)) {
+			v.SetUint(uint64(i.Value))
+			return nil
+		}
+
+	case reflect.Float32, reflect.Float64:
+		switch n := obj.(type) {
+		case *Float:
+			v.SetFloat(n.Value)
+			return nil
+		case *Integer:
+			v.SetFloat(float64(n.Value))
+			return nil
+		}
+
+	case reflect.String:
+		if s, ok := obj.(*String); ok {
+			v.SetString(s.Value)
+			return nil
+		}
+
+	case reflect.Slice:
+		if arr, ok := obj.(*Array); ok {
+			slice := reflect.MakeSlice(v.Type(), len(arr.Elements), len(arr.Elements))
+			for i, el := range arr.Elements {
+				if err := fromObject(el, slice.Index(i), c); err != nil {
+					return err
+				}
+			}
+			v.Set(slice)
+			return nil
+		}
+
+	case reflect.Array:
+		if arr, ok := obj.(*Array); ok && len(arr.Elements) == v.Len() {
+			for i, el := range arr.Elements {
+				if err := fromObject(el, v.Index(i), c); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+
+	case reflect.Map:
+		if hash, ok := obj.(*Hash); ok {
+			m := reflect.MakeMapWithSize(v.Type(), len(hash.Pairs))
+			for _, pair := range hash.Pairs {
+				key := reflect.New(v.Type().Key()).Elem()
+				if err := fromObject(pair.Key, key, c); err != nil {
+					return err
+				}
+				val := reflect.New(v.Type().Elem()).Elem()
+				if err := fromObject(pair.Value, val, c); err != nil {
+					return err
+				}
+				m.SetMapIndex(key, val)
+			}
+			v.Set(m)
+			return nil
+		}
+
+	case reflect.Func:
+		if c != nil && IsCallable(obj) {
+			v.Set(callbackFunc(c, obj, v.Type()))
+			return nil
+		}
+
+	case reflect.Struct:
+		if hash, ok := obj.(*Hash); ok {
+			for i := 0; i < v.NumField(); i++ {
+				name, ok := fieldName(v.Type().Field(i))
+				if !ok {
+					continue
+				}
+				pair, ok := hash.Pairs[(&String{Value: name}).HashKey()]
+				if !ok {
+					continue
+				}
+				if err := fromObject(pair.Value, v.Field(i), c); err != nil {
+					return fmt.Errorf("field %s: %w", name, err)
+				}
+			}
+			return nil
+		}
+	}
+
+	return &ConversionError{From: obj.Type(), To: v.Type()}
+}
+
+// nativeValue is the plain Go value for obj used for interface{} targets.
+func nativeValue(obj Object) any {
+	switch obj := obj.(type) {
+	case *Integer:
+		return obj.Value
+	case *Float:
+		return obj.Value
+	case *String:
+		return obj.Value
+	case *Boolean:
+		return obj.Value
+	case *Null:
+		return nil
+	case *Array:
+		res := make([]any, len(obj.Elements))
+		for i, el := range obj.Elements {
+			res[i] = nativeValue(el)
+		}
+		return res
+	case *Hash:
+		res := make(map[string]any, len(obj.Pairs))
+		for _, pair := range obj.Pairs {
+			res[pair.Key.Inspect()] = nativeValue(pair.Value)
+		}
+		return res
+	}
+	return obj
+}
+
+// ConversionError is returned when an object does not fit a Go type.
+type ConversionError struct {
+	From ObjectType
+	To   reflect.Type
+}
+
+func (e *ConversionError) Error() string {
+	return fmt.Sprintf("cant convert %s to %s", e.From, e.To)
+}
+
+// callbackError carries the error of a call made by a Go function that has
+// no error result up to the builtin that called it.
+type callbackError struct {
+	err error
+}
+
+// callbackFunc makes a Go function of type t that calls fn with c. A failed
+// call is returned through a last error result, or panics up to bindFunc when
+// t has none.
+func callbackFunc(c Caller, fn Object, t reflect.Type) reflect.Value {
+	return reflect.MakeFunc(t, func(in []reflect.Value) []reflect.Value {
+		out := make([]reflect.Value, t.NumOut())
+		for i := range out {
+			out[i] = reflect.New(t.Out(i)).Elem()
+		}
+		fail := func(err error) []reflect.Value {
+			if len(out) == 0 || t.Out(len(out)-1) != errorType {
+				panic(callbackError{err})
+			}
+			out[len(out)-1].Set(reflect.ValueOf(&err).Elem())
+			return out
+		}
+
+		args := make([]Object, len(in))
+		for i, arg := range in {
+			obj, err := toObject(arg)
+			if err != nil {
+				return fail(err)
+			}
+			args[i] = obj
+		}
+
+		res, err := c.Call(fn, args...)
+		if err != nil {
+			return fail(err)
+		}
+		if len(out) > 0 && t.Out(0) != errorType {
+			if err := fromObject(res, out[0], c); err != nil {
+				return fail(err)
+			}
+		}
+		return out
+	})
+}
+
+// bindFunc wraps a Go function as a builtin that converts its arguments with
+// FromObject and its results with ToObject. A last result of type error
+// becomes an error object when it is not nil. Function arguments can be any
+// callable object.
+func bindFunc(fn reflect.Value) *BuiltIn {
+	t := fn.Type()
+	want := t.NumIn()
+	if t.IsVariadic() {
+		want--
+	}
+
+	call := func(c Caller, args ...Object) (res Object) {
+		if len(args) < want || (!t.IsVariadic() && len(args) != want) {
+			return argumentAmountError(want, len(args))
+		}
+
+		in := make([]reflect.Value, len(args))
+		for i, arg := range args {
+			var paramType reflect.Type
+			if t.IsVariadic() && i >= want {
+				paramType = t.In(want).Elem()
+			} else {
+				paramType = t.In(i)
+			}
+			param := reflect.New(paramType).Elem()
+			if err := fromObject(arg, param, c); err != nil {
+				return argumentTypeError(typeName(paramType), arg.Type(), i+1)
+			}
+			in[i] = param
+		}
+
+		defer func() {
+			if p := recover(); p != nil {
+				cb, ok := p.(callbackError)
+				if !ok {
+					panic(p)
+				}
+				res = ErrorFrom(cb.err)
+			}
+		}()
+
+		out := fn.Call(in)
+		if n := len(out); n > 0 && t.Out(n-1) == errorType {
+			if err, _ := out[n-1].Interface().(error); err != nil {
+				return ErrorFrom(err)
+			}
+			out = out[:n-1]
+		}
+		if len(out) == 0 {
+			return NullVal
+		}
+
+		obj, err := toObject(out[0])
+		if err != nil {
+			return ErrorFrom(err)
+		}
+		return obj
+	}
+
+	return &BuiltIn{
+		Fn:     func(args ...Object) Object { return call(nil, args...) },
+		CallFn: call,
+	}
+}
+
+// typeName names t the way argument errors of builtins name object types.
+func typeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Bool:
+		return BOOLEAN_OBJ
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return INTEGER_OBJ
+	case reflect.Float32, reflect.Float64:
+		return NUMBER_OBJ
+	case reflect.String:
+		return STR_OBJ
+	case reflect.Slice, reflect.Array:
+		return ARR_OBJ
+	case reflect.Map, reflect.Struct:
+		return HASH_OBJ
+	case reflect.Pointer:
+		return typeName(t.Elem())
+	}
+	return ANY_OBJ
+}
@@ -0,0 +1,185 @@
+package object
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type point struct {
+	X, Y   int
+	Label  string `monkey:"label"`
+	hidden int
+}
+
+func TestConvertRoundTrip(t *testing.T) {
+	tests := []struct {
+		in     any
+		target any
+	}{
+		{42, new(int)},
+		{uint8(7), new(uint8)},
+		{1.5, new(float64)},
+		{"monkey", new(string)},
+		{true, new(bool)},
+		{[]int{1, 2, 3}, new([]int)},
+		{[2]string{"a", "b"}, new([2]string)},
+		{map[string]int{"a": 1, "b": 2}, new(map[string]int)},
+		{map[int][]bool{1: {true}, 2: {false, true}}, new(map[int][]bool)},
+		{point{X: 1, Y: 2, Label: "p"}, new(point)},
+		{&point{X: 3}, new(*point)},
+	}
+
+	for _, tt := range tests {
+		obj, err := ToObject(tt.in)
+		if err != nil {
+			t.Fatalf("ToObject(%#v) %s", tt.in, err)
+		}
+		if err := FromObject(obj, tt.target); err != nil {
+			t.Fatalf("FromObject(%s) %s", obj.Inspect(), err)
+		}
+		got := reflect.ValueOf(tt.target).Elem().Interface()
+		if !reflect.DeepEqual(got, tt.in) {
+			t.Errorf("round trip of %#v gave %#v", tt.in, got)
+		}
+	}
+}
+
+func TestToObject(t *testing.T) {
+	obj, err := ToObject(point{X: 1, Y: 2, Label: "p", hidden: 3})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	hash := obj.(*Hash)
+	if len(hash.Pairs) != 3 {
+		t.Errorf("wrong number of fields got %d", len(hash.Pairs))
+	}
+	if _, ok := hash.Pairs[(&String{Value: "label"}).HashKey()]; !ok {
+		t.Errorf("tagged field is missing")
+	}
+
+	obj, _ = ToObject(false)
+	if obj != FalseVal {
+		t.Errorf("booleans are not the shared values")
+	}
+	obj, _ = ToObject((*point)(nil))
+	if obj != NullVal {
+		t.Errorf("nil pointer is not null got %s", obj.Inspect())
+	}
+	if _, err := ToObject(make(chan int)); err == nil {
+		t.Errorf("expected an error converting a channel")
+	}
+
+	var native any
+	obj, _ = ToObject(map[string]any{"a": []any{1, "x"}})
+	if err := FromObject(obj, &native); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if !reflect.DeepEqual(native, map[string]any{"a": []any{1, "x"}}) {
+		t.Errorf("wrong native value got %#v", native)
+	}
+
+	var n int
+	var convErr *ConversionError
+	if err := FromObject(&String{Value: "1"}, &n); !errors.As(err, &convErr) {
+		t.Errorf("expected a ConversionError got %v", err)
+	}
+	var small int8
+	if err := FromObject(&Integer{Value: 300}, &small); !errors.As(err, &convErr) || small != 0 {
+		t.Errorf("expected a ConversionError for 300 in an int8 got %v (%d)", err, small)
+	}
+	var u uint
+	if err := FromObject(&Integer{Value: -1}, &u); !errors.As(err, &convErr) || u != 0 {
+		t.Errorf("expected a ConversionError for -1 in a uint got %v (%d)", err, u)
+	}
+	if _, err := ToObject(uint64(1 << 63)); err == nil {
+		t.Errorf("expected an error converting a uint64 above the INTEGER range")
+	}
+
+	type node struct{ Next *node }
+	loop := &node{}
+	loop.Next = loop
+	cyclicMap := map[string]any{}
+	cyclicMap["self"] = cyclicMap
+	cyclicSlice := []any{nil}
+	cyclicSlice[0] = cyclicSlice
+	for _, v := range []any{loop, cyclicMap, cyclicSlice} {
+		if _, err := ToObject(v); err == nil || !strings.Contains(err.Error(), "cyclic") {
+			t.Errorf("expected a cyclic error converting %T got %v", v, err)
+		}
+	}
+	shared := &point{X: 1}
+	if _, err := ToObject([]*point{shared, shared}); err != nil {
+		t.Errorf("shared pointer is not a cycle got %s", err)
+	}
+}
+
+func TestBind(t *testing.T) {
+	r := NewRegistry()
+	if err := r.Bind("notFunc", 1); err == nil {
+		t.Errorf("expected an error binding a non function")
+	}
+	r.Bind("join", strings.Join)
+	r.Bind("sum", func(nums ...float64) float64 {
+		total := 0.0
+		for _, n := range nums {
+			total += n
+		}
+		return total
+	})
+	r.Bind("div", func(a, b int) (int, error) {
+		if b == 0 {
+			return 0, errors.New("division by zero")
+		}
+		return a / b, nil
+	})
+	r.Bind("move", func(p point, dx int) point {
+		p.X += dx
+		return p
+	})
+
+	call := func(name string, args ...any) Object {
+		b, ok := r.Lookup(name)
+		if !ok {
+			t.Fatalf("%s is not registered", name)
+		}
+		objs := []Object{}
+		for _, arg := range args {
+			obj, err := ToObject(arg)
+			if err != nil {
+				t.Fatalf("%s", err)
+			}
+			objs = append(objs, obj)
+		}
+		return b.Fn(objs...)
+	}
+
+	tests := []struct {
+		res  Object
+		want string
+	}{
+		{call("join", []string{"a", "b"}, "-"), "a-b"},
+		{call("sum", 1, 2.5, 3), "6.500000"},
+		{call("sum"), "0.000000"},
+		{call("div", 7, 2), "3"},
+		{call("div", 1, 0), "ERROR : division by zero"},
+		{call("join", "a", "-"), "ERROR : Argument 1 has to be of Type ARRAY got STRING"},
+		{call("div", 1), "ERROR : Want 2 Arguments got 1"},
+	}
+	for _, tt := range tests {
+		got := tt.res.Inspect()
+		if e, ok := tt.res.(*Error); ok {
+			got = e.Inspect()
+		}
+		if got != tt.want {
+			t.Errorf("want %q got %q", tt.want, got)
+		}
+	}
+
+	moved := call("move", point{X: 1, Label: "p"}, 2)
+	var p point
+	if err := FromObject(moved, &p); err != nil || p.X != 3 || p.Label != "p" {
+		t.Errorf("wrong point got %#v (%v)", p, err)
+	}
+}
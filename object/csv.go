@@ -0,0 +1,220 @@
+package object
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// csvOptions are read from the optional hash argument of csvParse and
+// csvStringify.
+type csvOptions struct {
+	delimiter rune
+	header    bool
+	numbers   bool
+	columns   []string
+	crlf      bool
+}
+
+func csvOptionsArg(args []Object, pos int, opts csvOptions) (csvOptions, *Error) {
+	if len(args) <= pos {
+		return opts, nil
+	}
+	hash, ok := args[pos].(*Hash)
+	if !ok {
+		return opts, argumentTypeError(HASH_OBJ, args[pos].Type(), pos+1)
+	}
+
+	for _, pair := range hash.Pairs {
+		name := pair.Key.Inspect()
+		switch name {
+		case "delimiter":
+			str, ok := pair.Value.(*String)
+			if !ok || utf8.RuneCountInString(str.Value) != 1 {
+				return opts, newError("CSV Option delimiter has to be a single Character got %s", pair.Value.Inspect())
+			}
+			opts.delimiter, _ = utf8.DecodeRuneInString(str.Value)
+		case "header", "numbers", "crlf":
+			b, ok := pair.Value.(*Boolean)
+			if !ok {
+				return opts, newError("CSV Option %s has to be a BOOLEAN got %s", name, pair.Value.Type())
+			}
+			switch name {
+			case "header":
+				opts.header = b.Value
+			case "numbers":
+				opts.numbers = b.Value
+			default:
+				opts.crlf = b.Value
+			}
+		case "columns":
+			arr, ok := pair.Value.(*Array)
+			if !ok {
+				return opts, newError("CSV Option columns has to be an ARRAY got %s", pair.Value.Type())
+			}
+			opts.columns = make([]string, len(arr.Elements))
+			for i, el := range arr.Elements {
+				opts.columns[i] = el.Inspect()
+			}
+		default:
+			return opts, newError("Unknown CSV Option %s", name)
+		}
+	}
+	return opts, nil
+}
+
+// csvParse returns the records of str as arrays, or as hashes keyed by the
+// first record when the header option is set. Fields that look like numbers
+// become INTEGERs and FLOATs unless the numbers option is false; fields with
+// leading zeros like 007 stay STRINGs.
+func csvParse(args ...Object) Object {
+	if len(args) != 1 && len(args) != 2 {
+		return argumentAmountError(2, len(args))
+	}
+	str, err := stringArg(args, 0)
+	if err != nil {
+		return err
+	}
+	opts, err := csvOptionsArg(args, 1, csvOptions{delimiter: ',', numbers: true})
+	if err != nil {
+		return err
+	}
+
+	r := csv.NewReader(strings.NewReader(str))
+	r.Comma = opts.delimiter
+	records, readErr := r.ReadAll()
+	if readErr != nil {
+		return newError("Invalid CSV: %s", readErr)
+	}
+
+	var header []string
+	if opts.header && len(records) > 0 {
+		header, records = records[0], records[1:]
+		seen := make(map[string]bool, len(header))
+		for _, name := range header {
+			if seen[name] {
+				return newError("Duplicate CSV Header %s", name)
+			}
+			seen[name] = true
+		}
+	}
+
+	rows := make([]Object, len(records))
+	for i, record := range records {
+		fields := make([]Object, len(record))
+		for j, field := range record {
+			fields[j] = csvField(field, opts.numbers)
+		}
+		if header == nil {
+			rows[i] = &Array{Elements: fields}
+			continue
+		}
+		row := &Hash{Pairs: make(map[HashKey]HashPair, len(fields))}
+		for j, field := range fields {
+			key := &String{Value: header[j]}
+			row.Pairs[key.HashKey()] = HashPair{Key: key, Value: field}
+		}
+		rows[i] = row
+	}
+	return &Array{Elements: rows}
+}
+
+func csvField(field string, numbers bool) Object {
+	if !numbers || field == "" || !strings.ContainsAny(field[:1], "+-.0123456789") {
+		return &String{Value: field}
+	}
+	if digits := strings.TrimLeft(field, "+-"); len(digits) > 1 && digits[0] == '0' && digits[1] >= '0' && digits[1] <= '9' {
+		return &String{Value: field}
+	}
+	if i, err := strconv.ParseInt(field, 10, 64); err == nil {
+		return &Integer{Value: int(i)}
+	}
+	if f, err := strconv.ParseFloat(field, 64); err == nil {
+		return &Float{Value: f}
+	}
+	return &String{Value: field}
+}
+
+// csvStringify writes rows of arrays, or rows of hashes under a header of
+// their sorted keys or of the columns option. Fields are quoted where needed.
+func csvStringify(args ...Object) Object {
+	if len(args) != 1 && len(args) != 2 {
+		return argumentAmountError(2, len(args))
+	}
+	rows, err := arrayArg(args, 0)
+	if err != nil {
+		return err
+	}
+	opts, err := csvOptionsArg(args, 1, csvOptions{delimiter: ',', header: true})
+	if err != nil {
+		return err
+	}
+
+	records := [][]string{}
+	columns := opts.columns
+	for i, row := range rows.Elements {
+		switch row := row.(type) {
+		case *Array:
+			record := make([]string, len(row.Elements))
+			for j, el := range row.Elements {
+				field, err := csvString(el)
+				if err != nil {
+					return err
+				}
+				record[j] = field
+			}
+			records = append(records, record)
+		case *Hash:
+			if columns == nil {
+				for _, pair := range SortedPairs(row) {
+					columns = append(columns, pair.Key.Inspect())
+				}
+			}
+			if opts.header && len(records) == 0 {
+				records = append(records, columns)
+			}
+			record := make([]string, len(columns))
+			for j, column := range columns {
+				pair, ok := row.Pairs[(&String{Value: column}).HashKey()]
+				if !ok {
+					continue
+				}
+				field, err := csvString(pair.Value)
+				if err != nil {
+					return err
+				}
+				record[j] = field
+			}
+			records = append(records, record)
+		default:
+			return newError("Row %d has to be an ARRAY or HASH got %s", i, row.Type())
+		}
+	}
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Comma = opts.delimiter
+	w.UseCRLF = opts.crlf
+	if err := w.WriteAll(records); err != nil {
+		return newError("Invalid CSV: %s", err)
+	}
+	return &String{Value: buf.String()}
+}
+
+func csvString(obj Object) (string, *Error) {
+	switch obj := obj.(type) {
+	case *String:
+		return obj.Value, nil
+	case *Integer:
+		return strconv.Itoa(obj.Value), nil
+	case *Float:
+		return strconv.FormatFloat(obj.Value, 'f', -1, 64), nil
+	case *Boolean:
+		return strconv.FormatBool(obj.Value), nil
+	case *Null:
+		return "", nil
+	}
+	return "", newError("Object of Type %s cant be converted to CSV", obj.Type())
+}
@@ -0,0 +1,87 @@
+package object
+
+import (
+	"testing"
+)
+
+func csvOpts(pairs map[string]any) Object {
+	obj, err := ToObject(pairs)
+	if err != nil {
+		panic(err)
+	}
+	return obj
+}
+
+func TestCSVParse(t *testing.T) {
+	input := "name,age,score\nada,36,1.5\n\"lovelace, a\",-7,x\n"
+
+	tests := []struct {
+		args     []Object
+		expected string
+	}{
+		{[]Object{&String{Value: input}}, "[[name, age, score], [ada, 36, 1.500000], [lovelace, a, -7, x]]"},
+		{[]Object{&String{Value: "a;1\n"}, csvOpts(map[string]any{"delimiter": ";", "numbers": false})}, "[[a, 1]]"},
+		{[]Object{&String{Value: ""}}, "[]"},
+		{[]Object{&String{Value: "007,-007,0,0.5,-0.25,00.5\n"}}, "[[007, -007, 0, 0.500000, -0.250000, 00.5]]"},
+		{[]Object{&String{Value: "a,b,a\n1,2,3\n"}, csvOpts(map[string]any{"header": true})}, "ERROR : Duplicate CSV Header a"},
+		{[]Object{&String{Value: "a,b\n1\n"}}, "ERROR : Invalid CSV: record on line 2: wrong number of fields"},
+		{[]Object{&String{Value: "a"}, csvOpts(map[string]any{"delimiter": ";;"})}, "ERROR : CSV Option delimiter has to be a single Character got ;;"},
+		{[]Object{&String{Value: "a"}, csvOpts(map[string]any{"quote": true})}, "ERROR : Unknown CSV Option quote"},
+	}
+
+	for _, tt := range tests {
+		res := csvParse(tt.args...)
+		if res.Inspect() != tt.expected {
+			t.Errorf("want %s got %s", tt.expected, res.Inspect())
+		}
+	}
+
+	rows := csvParse(&String{Value: input}, csvOpts(map[string]any{"header": true})).(*Array)
+	if len(rows.Elements) != 2 {
+		t.Fatalf("expected 2 rows got %s", rows.Inspect())
+	}
+	var got []struct {
+		Name  string  `monkey:"name"`
+		Age   int     `monkey:"age"`
+		Score float64 `monkey:"score"`
+	}
+	rows.Elements = rows.Elements[:1]
+	if err := FromObject(rows, &got); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if got[0].Name != "ada" || got[0].Age != 36 || got[0].Score != 1.5 {
+		t.Errorf("wrong first row got %#v", got[0])
+	}
+}
+
+func TestCSVStringify(t *testing.T) {
+	rows, _ := ToObject([]any{[]any{"a", 1, 1.5, true, nil}, []any{"b,c", "say \"hi\""}})
+	if got := csvStringify(rows).Inspect(); got != "a,1,1.5,true,\n\"b,c\",\"say \"\"hi\"\"\"\n" {
+		t.Errorf("wrong csv got %q", got)
+	}
+
+	hashes, _ := ToObject([]map[string]any{{"b": 2, "a": "x"}, {"a": "y"}})
+	if got := csvStringify(hashes).Inspect(); got != "a,b\nx,2\ny,\n" {
+		t.Errorf("wrong csv of hashes got %q", got)
+	}
+	opts := csvOpts(map[string]any{"columns": []any{"b"}, "delimiter": "\t", "header": false, "crlf": true})
+	if got := csvStringify(hashes, opts).Inspect(); got != "2\r\n\r\n" {
+		t.Errorf("wrong csv with options got %q", got)
+	}
+
+	input := "name;n\n\"a;b\";1.25\nc;-3\n"
+	opts = csvOpts(map[string]any{"header": true, "delimiter": ";"})
+	opts2 := csvOpts(map[string]any{"columns": []any{"name", "n"}, "delimiter": ";"})
+	if got := csvStringify(csvParse(&String{Value: input}, opts), opts2).Inspect(); got != input {
+		t.Errorf("round trip gave %q", got)
+	}
+
+	bad, _ := ToObject([]any{1})
+	if got := csvStringify(bad).Inspect(); got != "ERROR : Row 0 has to be an ARRAY or HASH got INTEGER" {
+		t.Errorf("expected an error for a bad row got %q", got)
+	}
+	nested, _ := ToObject([]any{[]any{[]any{}}})
+	if got := csvStringify(nested).Inspect(); got != "ERROR : Object of Type ARRAY cant be converted to CSV" {
+		t.Errorf("expected an error for a nested array got %q", got)
+	}
+}
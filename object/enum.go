@@ -0,0 +1,126 @@
+package object
+
+import (
+	"strings"
+)
+
+const ENUM_OBJ = "ENUM"
+
+type Enum struct {
+	Name     string
+	Variants []string
+	Arity    map[string]int
+}
+
+func (e *Enum) Type() ObjectType {
+	return ENUM_OBJ
+}
+
+func (e *Enum) Inspect() string {
+	return "enum " + e.Name
+}
+
+func (e *Enum) HasVariant(name string) bool {
+	_, ok := e.Arity[name]
+	return ok
+}
+
+func (e *Enum) Missing(covered map[string]bool) []string {
+	missing := []string{}
+	for _, name := range e.Variants {
+		if !covered[name] {
+			missing = append(missing, name)
+		}
+	}
+	return missing
+}
+
+// EnumVariant is a value of an enum. Its Type is the name of the enum, so
+// typeof(Red) reports "Color" for a variant declared in enum Color.
+type EnumVariant struct {
+	Enum   *Enum
+	Name   string
+	Values []Object
+}
+
+func (ev *EnumVariant) Type() ObjectType {
+	return ObjectType(ev.Enum.Name)
+}
+
+func (ev *EnumVariant) Inspect() string {
+	if len(ev.Values) == 0 {
+		return ev.Enum.Name + "." + ev.Name
+	}
+
+	values := []string{}
+	for _, v := range ev.Values {
+		values = append(values, v.Inspect())
+	}
+	return ev.Enum.Name + "." + ev.Name + "(" + strings.Join(values, ", ") + ")"
+}
+
+func (ev *EnumVariant) Equals(other Object) bool {
+	o, ok := other.(*EnumVariant)
+	if !ok {
+		return false
+	}
+	return ev.Enum.Name == o.Enum.Name && ev.Inspect() == o.Inspect()
+}
+
+// EnumConstructor builds the variants that carry values, like Blue(rgb).
+type EnumConstructor struct {
+	Enum *Enum
+	Name string
+}
+
+func (ec *EnumConstructor) Type() ObjectType {
+	return FUNCTION_OBJ
+}
+
+func (ec *EnumConstructor) Inspect() string {
+	return ec.Enum.Name + "." + ec.Name
+}
+
+func (ec *EnumConstructor) Construct(args []Object) Object {
+	if arity := ec.Enum.Arity[ec.Name]; len(args) != arity {
+		return newError("%s: Want %d Arguments got %d", ec.Inspect(), arity, len(args))
+	}
+	values := make([]Object, len(args))
+	copy(values, args)
+
+	return &EnumVariant{Enum: ec.Enum, Name: ec.Name, Values: values}
+}
+
+// NewEnumMembers returns the objects the variant names of e are bound to.
+func NewEnumMembers(e *Enum) []Object {
+	members := []Object{}
+	for _, name := range e.Variants {
+		if e.Arity[name] == 0 {
+			members = append(members, &EnumVariant{Enum: e, Name: name})
+		} else {
+			members = append(members, &EnumConstructor{Enum: e, Name: name})
+		}
+	}
+	return members
+}
+
+// VariantOf reports the enum and variant name an object used in a match arm
+// refers to.
+func VariantOf(obj Object) (*Enum, string, bool) {
+	switch obj := obj.(type) {
+	case *EnumVariant:
+		return obj.Enum, obj.Name, true
+	case *EnumConstructor:
+		return obj.Enum, obj.Name, true
+	}
+	return nil, "", false
+}
+
+func IsVariant(val, of Object) bool {
+	v, ok := val.(*EnumVariant)
+	if !ok {
+		return false
+	}
+	e, name, ok := VariantOf(of)
+	return ok && v.Enum.Name == e.Name && v.Name == name
+}
@@ -1,15 +1,25 @@
 package object
 
+import (
+	"fmt"
+
+	"github.com/Arch-4ng3l/Monkey/ast"
+)
+
 type Env struct {
-	store map[string]Object
-	outer *Env
+	store    map[string]Object
+	consts   map[string]bool
+	deferred []ast.Expression
+	outer    *Env
+	gensyms  int
 }
 
 func NewEnv() *Env {
 	s := make(map[string]Object)
 	return &Env{
-		store: s,
-		outer: nil,
+		store:  s,
+		consts: make(map[string]bool),
+		outer:  nil,
 	}
 }
 
@@ -28,10 +38,75 @@ func (e *Env) Get(name string) (Object, bool) {
 }
 
 func (e *Env) Set(name string, val Object) Object {
+	if e.consts[name] {
+		return newError("Cant assign to Constant %s", name)
+	}
+	e.store[name] = val
+	return val
+}
+
+// Assign sets name in the Env that declared it, so functions update the
+// variables they closed over instead of shadowing them.
+func (e *Env) Assign(name string, val Object) Object {
+	for env := e; env != nil; env = env.outer {
+		if _, ok := env.store[name]; ok {
+			return env.Set(name, val)
+		}
+	}
+	return e.Set(name, val)
+}
+
+func (e *Env) SetConst(name string, val Object) Object {
+	if _, ok := e.store[name]; ok {
+		return newError("Cant redeclare %s as Constant", name)
+	}
 	e.store[name] = val
+	e.consts[name] = true
 	return val
 }
 
+// Gensym returns a new name based on name that programs cannot write. The
+// names are unique among the ones returned for the outermost Env of e.
+func (e *Env) Gensym(name string) string {
+	root := e
+	for root.outer != nil {
+		root = root.outer
+	}
+	root.gensyms++
+	return fmt.Sprintf("%s#%d", name, root.gensyms)
+}
+
+func (e *Env) IsTopLevel() bool {
+	return e.outer == nil
+}
+
+func (e *Env) Defer(exp ast.Expression) {
+	e.deferred = append(e.deferred, exp)
+}
+
+func (e *Env) HasDeferred() bool {
+	return len(e.deferred) > 0
+}
+
+func (e *Env) PopDeferred() (ast.Expression, bool) {
+	if len(e.deferred) == 0 {
+		return nil, false
+	}
+	exp := e.deferred[len(e.deferred)-1]
+	e.deferred = e.deferred[:len(e.deferred)-1]
+	return exp, true
+}
+
+func (e *Env) IsConst(name string) bool {
+	if _, ok := e.store[name]; ok {
+		return e.consts[name]
+	}
+	if e.outer != nil {
+		return e.outer.IsConst(name)
+	}
+	return false
+}
+
 func extendFunctionEnv(fn *Function, args []Object) *Env {
 	env := NewEnclosedEnv(fn.Env)
 
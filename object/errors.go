@@ -0,0 +1,61 @@
+package object
+
+import (
+	"fmt"
+	"strings"
+)
+
+// StackOverflowError is returned by both engines when a program goes deeper
+// than the configured call depth or stack size.
+type StackOverflowError struct {
+	Kind  string
+	Limit int
+	Chain []string
+}
+
+func (e *StackOverflowError) Error() string {
+	chain := e.Chain
+	if len(chain) > 10 {
+		chain = append(append(chain[:5:5], fmt.Sprintf("... %d more", len(e.Chain)-10)), chain[len(chain)-5:]...)
+	}
+	return fmt.Sprintf("Stack Overflow: %s limit of %d exceeded in %s", e.Kind, e.Limit, strings.Join(chain, " -> "))
+}
+
+func FunctionName(name string) string {
+	if name == "" {
+		return "<anonymous>"
+	}
+	return name
+}
+
+const (
+	LimitSteps    = "step budget"
+	LimitDeadline = "deadline"
+	LimitCanceled = "canceled"
+)
+
+// LimitError is returned by both engines when a run is stopped by its step
+// budget, its deadline or the cancellation of its context.
+type LimitError struct {
+	Kind  string
+	Steps int
+	Err   error
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("Execution Limit: %s exceeded after %d steps", e.Kind, e.Steps)
+}
+
+func (e *LimitError) Unwrap() error {
+	return e.Err
+}
+
+// ExitError stops a program that called exit. Both engines return it like a
+// Go error so hosts can pick up Code.
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("Exit: status %d", e.Code)
+}
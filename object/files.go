@@ -0,0 +1,391 @@
+package object
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	gosort "sort"
+	"strings"
+	"sync"
+)
+
+// FileSystem is what the file builtins read and write through, so hosts can
+// give programs the real file system, an in-memory one or none at all.
+type FileSystem interface {
+	ReadFile(name string) ([]byte, error)
+	WriteFile(name string, data []byte) error
+	AppendFile(name string, data []byte) error
+	// ReadDir returns the sorted names of the entries of the directory.
+	ReadDir(name string) ([]string, error)
+	Exists(name string) (bool, error)
+	Remove(name string) error
+}
+
+// OSFileSystem is the file system of the host.
+type OSFileSystem struct{}
+
+func (OSFileSystem) ReadFile(name string) ([]byte, error) {
+	return os.ReadFile(name)
+}
+
+func (OSFileSystem) WriteFile(name string, data []byte) error {
+	return os.WriteFile(name, data, 0644)
+}
+
+func (OSFileSystem) AppendFile(name string, data []byte) error {
+	f, err := os.OpenFile(name, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+func (OSFileSystem) ReadDir(name string) ([]string, error) {
+	entries, err := os.ReadDir(name)
+	return entryNames(entries), err
+}
+
+func (OSFileSystem) Exists(name string) (bool, error) {
+	return exists(os.Stat(name))
+}
+
+func (OSFileSystem) Remove(name string) error {
+	return os.Remove(name)
+}
+
+func entryNames(entries []fs.DirEntry) []string {
+	names := make([]string, len(entries))
+	for i, entry := range entries {
+		names[i] = entry.Name()
+	}
+	return names
+}
+
+func exists(_ fs.FileInfo, err error) (bool, error) {
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// ReadOnlyFS serves the file builtins from fsys, like an embed.FS, and
+// refuses every write.
+func ReadOnlyFS(fsys fs.FS) FileSystem {
+	return readOnlyFS{fsys}
+}
+
+type readOnlyFS struct {
+	fsys fs.FS
+}
+
+func (r readOnlyFS) ReadFile(name string) ([]byte, error) {
+	return fs.ReadFile(r.fsys, name)
+}
+
+func (r readOnlyFS) WriteFile(name string, data []byte) error {
+	return &fs.PathError{Op: "write", Path: name, Err: fs.ErrPermission}
+}
+
+func (r readOnlyFS) AppendFile(name string, data []byte) error {
+	return &fs.PathError{Op: "append", Path: name, Err: fs.ErrPermission}
+}
+
+func (r readOnlyFS) ReadDir(name string) ([]string, error) {
+	entries, err := fs.ReadDir(r.fsys, name)
+	return entryNames(entries), err
+}
+
+func (r readOnlyFS) Exists(name string) (bool, error) {
+	return exists(fs.Stat(r.fsys, name))
+}
+
+func (r readOnlyFS) Remove(name string) error {
+	return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrPermission}
+}
+
+// NoFileSystem denies every access.
+var NoFileSystem FileSystem = noFS{}
+
+type noFS struct{}
+
+func (noFS) ReadFile(name string) ([]byte, error) {
+	return nil, &fs.PathError{Op: "read", Path: name, Err: fs.ErrPermission}
+}
+
+func (noFS) WriteFile(name string, data []byte) error {
+	return &fs.PathError{Op: "write", Path: name, Err: fs.ErrPermission}
+}
+
+func (noFS) AppendFile(name string, data []byte) error {
+	return &fs.PathError{Op: "append", Path: name, Err: fs.ErrPermission}
+}
+
+func (noFS) ReadDir(name string) ([]string, error) {
+	return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrPermission}
+}
+
+func (noFS) Exists(name string) (bool, error) {
+	return false, &fs.PathError{Op: "stat", Path: name, Err: fs.ErrPermission}
+}
+
+func (noFS) Remove(name string) error {
+	return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrPermission}
+}
+
+// MemFileSystem keeps files in memory. Directories exist as long as a file
+// is inside of them.
+type MemFileSystem struct {
+	mu    sync.Mutex
+	files map[string][]byte
+}
+
+// NewMemFileSystem returns a MemFileSystem holding files.
+func NewMemFileSystem(files map[string]string) *MemFileSystem {
+	m := &MemFileSystem{files: map[string][]byte{}}
+	for name, data := range files {
+		m.files[path.Clean(name)] = []byte(data)
+	}
+	return m
+}
+
+func (m *MemFileSystem) ReadFile(name string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	data, ok := m.files[path.Clean(name)]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	return bytes.Clone(data), nil
+}
+
+func (m *MemFileSystem) WriteFile(name string, data []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.isDir(path.Clean(name)) {
+		return &fs.PathError{Op: "write", Path: name, Err: fs.ErrExist}
+	}
+	m.files[path.Clean(name)] = bytes.Clone(data)
+	return nil
+}
+
+func (m *MemFileSystem) AppendFile(name string, data []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.isDir(path.Clean(name)) {
+		return &fs.PathError{Op: "append", Path: name, Err: fs.ErrExist}
+	}
+	m.files[path.Clean(name)] = append(m.files[path.Clean(name)], data...)
+	return nil
+}
+
+func (m *MemFileSystem) ReadDir(name string) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	dir := path.Clean(name)
+	if !m.isDir(dir) {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fs.ErrNotExist}
+	}
+
+	seen := map[string]bool{}
+	names := []string{}
+	for file := range m.files {
+		rest, ok := m.under(dir, file)
+		if !ok {
+			continue
+		}
+		entry, _, _ := strings.Cut(rest, "/")
+		if !seen[entry] {
+			seen[entry] = true
+			names = append(names, entry)
+		}
+	}
+	gosort.Strings(names)
+	return names, nil
+}
+
+func (m *MemFileSystem) Exists(name string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, ok := m.files[path.Clean(name)]
+	return ok || m.isDir(path.Clean(name)), nil
+}
+
+func (m *MemFileSystem) Remove(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.files[path.Clean(name)]; !ok {
+		return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrNotExist}
+	}
+	delete(m.files, path.Clean(name))
+	return nil
+}
+
+func (m *MemFileSystem) isDir(dir string) bool {
+	if dir == "." || dir == "/" {
+		return true
+	}
+	for file := range m.files {
+		if _, ok := m.under(dir, file); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// under returns the path of file relative to dir when it is inside of it.
+func (m *MemFileSystem) under(dir, file string) (string, bool) {
+	switch dir {
+	case ".":
+		return file, !strings.HasPrefix(file, "/")
+	case "/":
+		return strings.TrimPrefix(file, "/"), strings.HasPrefix(file, "/")
+	}
+	return strings.CutPrefix(file, dir+"/")
+}
+
+func osFiles() FileSystem {
+	return OSFileSystem{}
+}
+
+func fileError(err error) *Error {
+	return newError("File Error: %s", err)
+}
+
+func readFileFrom(files func() FileSystem) BuiltInFunction {
+	return func(args ...Object) Object {
+		strs, err := stringArgs(args, 1)
+		if err != nil {
+			return err
+		}
+		data, readErr := files().ReadFile(strs[0])
+		if readErr != nil {
+			return fileError(readErr)
+		}
+		return &String{Value: string(data)}
+	}
+}
+
+// readLinesFrom splits a file at \n and \r\n. A last empty line is dropped.
+func readLinesFrom(files func() FileSystem) BuiltInFunction {
+	return func(args ...Object) Object {
+		strs, err := stringArgs(args, 1)
+		if err != nil {
+			return err
+		}
+		data, readErr := files().ReadFile(strs[0])
+		if readErr != nil {
+			return fileError(readErr)
+		}
+		text := strings.TrimSuffix(string(data), "\n")
+		if text == "" {
+			return &Array{Elements: []Object{}}
+		}
+		lines := strings.Split(text, "\n")
+		for i, line := range lines {
+			lines[i] = strings.TrimSuffix(line, "\r")
+		}
+		return stringArray(lines)
+	}
+}
+
+func writeFileTo(files func() FileSystem, appending bool) BuiltInFunction {
+	return func(args ...Object) Object {
+		strs, err := stringArgs(args, 2)
+		if err != nil {
+			return err
+		}
+		fsys := files()
+		write := fsys.WriteFile
+		if appending {
+			write = fsys.AppendFile
+		}
+		if err := write(strs[0], []byte(strs[1])); err != nil {
+			return fileError(err)
+		}
+		return NullVal
+	}
+}
+
+func listDirFrom(files func() FileSystem) BuiltInFunction {
+	return func(args ...Object) Object {
+		strs, err := stringArgs(args, 1)
+		if err != nil {
+			return err
+		}
+		names, readErr := files().ReadDir(strs[0])
+		if readErr != nil {
+			return fileError(readErr)
+		}
+		return stringArray(names)
+	}
+}
+
+func existsIn(files func() FileSystem) BuiltInFunction {
+	return func(args ...Object) Object {
+		strs, err := stringArgs(args, 1)
+		if err != nil {
+			return err
+		}
+		ok, statErr := files().Exists(strs[0])
+		if statErr != nil {
+			return fileError(statErr)
+		}
+		return NativeBool(ok)
+	}
+}
+
+func removeFrom(files func() FileSystem) BuiltInFunction {
+	return func(args ...Object) Object {
+		strs, err := stringArgs(args, 1)
+		if err != nil {
+			return err
+		}
+		if err := files().Remove(strs[0]); err != nil {
+			return fileError(err)
+		}
+		return NullVal
+	}
+}
+
+func pathJoin(args ...Object) Object {
+	parts := make([]string, len(args))
+	for i := range args {
+		str, err := stringArg(args, i)
+		if err != nil {
+			return err
+		}
+		parts[i] = str
+	}
+	return &String{Value: filepath.Join(parts...)}
+}
+
+func pathBase(args ...Object) Object {
+	strs, err := stringArgs(args, 1)
+	if err != nil {
+		return err
+	}
+	return &String{Value: filepath.Base(strs[0])}
+}
+
+func pathDir(args ...Object) Object {
+	strs, err := stringArgs(args, 1)
+	if err != nil {
+		return err
+	}
+	return &String{Value: filepath.Dir(strs[0])}
+}
+
+func pathExt(args ...Object) Object {
+	strs, err := stringArgs(args, 1)
+	if err != nil {
+		return err
+	}
+	return &String{Value: filepath.Ext(strs[0])}
+}
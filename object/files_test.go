@@ -0,0 +1,123 @@
+package object
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func testFileSystem(t *testing.T, fsys FileSystem, dir string) {
+	t.Helper()
+	file := filepath.Join(dir, "logs", "a.txt")
+
+	if err := fsys.WriteFile(file, []byte("one\n")); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if err := fsys.AppendFile(file, []byte("two\n")); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if err := fsys.WriteFile(filepath.Join(dir, "logs", "b.txt"), nil); err != nil {
+		t.Fatalf("%s", err)
+	}
+	data, err := fsys.ReadFile(file)
+	if err != nil || string(data) != "one\ntwo\n" {
+		t.Errorf("wrong content got %q (%v)", data, err)
+	}
+	names, err := fsys.ReadDir(filepath.Join(dir, "logs"))
+	if err != nil || !reflect.DeepEqual(names, []string{"a.txt", "b.txt"}) {
+		t.Errorf("wrong entries got %v (%v)", names, err)
+	}
+	if ok, err := fsys.Exists(filepath.Join(dir, "logs")); !ok || err != nil {
+		t.Errorf("expected the directory to exist got %v (%v)", ok, err)
+	}
+	if err := fsys.Remove(file); err != nil {
+		t.Fatalf("%s", err)
+	}
+	if ok, err := fsys.Exists(file); ok || err != nil {
+		t.Errorf("expected the file to be removed got %v (%v)", ok, err)
+	}
+	if _, err := fsys.ReadFile(file); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected ErrNotExist got %v", err)
+	}
+}
+
+func TestFileSystems(t *testing.T) {
+	t.Run("os", func(t *testing.T) {
+		dir := t.TempDir()
+		if err := os.Mkdir(filepath.Join(dir, "logs"), 0755); err != nil {
+			t.Fatalf("%s", err)
+		}
+		testFileSystem(t, OSFileSystem{}, dir)
+	})
+	t.Run("memory", func(t *testing.T) {
+		testFileSystem(t, NewMemFileSystem(nil), "/data")
+		testFileSystem(t, NewMemFileSystem(map[string]string{"x": ""}), ".")
+	})
+
+	ro := ReadOnlyFS(fstest.MapFS{"a/b.txt": {Data: []byte("hi")}})
+	if data, err := ro.ReadFile("a/b.txt"); err != nil || string(data) != "hi" {
+		t.Errorf("wrong content got %q (%v)", data, err)
+	}
+	if names, err := ro.ReadDir("a"); err != nil || !reflect.DeepEqual(names, []string{"b.txt"}) {
+		t.Errorf("wrong entries got %v (%v)", names, err)
+	}
+	if err := ro.WriteFile("a/c.txt", nil); !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("expected ErrPermission got %v", err)
+	}
+	if _, err := NoFileSystem.ReadFile("a"); !errors.Is(err, fs.ErrPermission) {
+		t.Errorf("expected ErrPermission got %v", err)
+	}
+}
+
+func TestFileBuiltins(t *testing.T) {
+	r := NewDefaultRegistry()
+	r.Files = NewMemFileSystem(map[string]string{"in.csv": "a\r\nb\n\nc\n"})
+	call := func(name string, args ...string) string {
+		b, _ := r.Lookup(name)
+		objs := make([]Object, len(args))
+		for i, arg := range args {
+			objs[i] = &String{Value: arg}
+		}
+		return b.Fn(objs...).Inspect()
+	}
+
+	tests := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{"readLines", []string{"in.csv"}, "[a, b, , c]"},
+		{"writeFile", []string{"out/x.txt", "1"}, "null"},
+		{"appendFile", []string{"out/x.txt", "2"}, "null"},
+		{"readFile", []string{"out/x.txt"}, "12"},
+		{"listDir", []string{"."}, "[in.csv, out]"},
+		{"exists", []string{"out"}, "true"},
+		{"remove", []string{"out/x.txt"}, "null"},
+		{"exists", []string{"out"}, "false"},
+		{"readFile", []string{"out/x.txt"}, "ERROR : File Error: open out/x.txt: file does not exist"},
+		{"pathJoin", []string{"a", "b/../c", "d.txt"}, "a/c/d.txt"},
+		{"pathBase", []string{"a/c/d.txt"}, "d.txt"},
+		{"pathDir", []string{"a/c/d.txt"}, "a/c"},
+		{"pathExt", []string{"a/c/d.txt"}, ".txt"},
+	}
+	for _, tt := range tests {
+		if got := call(tt.name, tt.args...); got != tt.expected {
+			t.Errorf("%s%v: want %s got %s", tt.name, tt.args, tt.expected, got)
+		}
+	}
+
+	sandboxed := (&Policy{Modules: []string{"files"}, Capabilities: []Capability{CapFileRead}}).Apply(r)
+	if _, ok := sandboxed.Lookup("readFile"); !ok {
+		t.Errorf("expected readFile to be allowed")
+	}
+	if _, ok := sandboxed.Lookup("writeFile"); ok {
+		t.Errorf("expected writeFile to need the file write capability")
+	}
+	if sandboxed.FileSystem() != r.Files {
+		t.Errorf("expected the sandboxed registry to keep the file system")
+	}
+}
@@ -0,0 +1,114 @@
+package object
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+var (
+	stdinMu     sync.Mutex
+	stdinReader *bufio.Reader
+)
+
+// stdin buffers os.Stdin once for the builtins of Builtins, so no input is
+// lost between calls.
+func stdin() *bufio.Reader {
+	stdinMu.Lock()
+	defer stdinMu.Unlock()
+	if stdinReader == nil {
+		stdinReader = bufio.NewReader(os.Stdin)
+	}
+	return stdinReader
+}
+
+// readLine returns the next line without its line ending, or false at the
+// end of the input.
+func readLine(in *bufio.Reader) (string, bool, error) {
+	line, err := in.ReadString('\n')
+	if err == io.EOF {
+		if line == "" {
+			return "", false, nil
+		}
+		err = nil
+	}
+	line = strings.TrimSuffix(line, "\n")
+	return strings.TrimSuffix(line, "\r"), err == nil, err
+}
+
+func inputError(err error) *Error {
+	return &Error{Message: fmt.Sprintf("Input Error: %s", err), Cause: err}
+}
+
+// inputFrom writes the prompt, when there is one, and reads a line. At the
+// end of the input it returns null.
+func inputFrom(in func() *bufio.Reader, out func() io.Writer) BuiltInFunction {
+	return func(args ...Object) Object {
+		if len(args) > 1 {
+			return argumentAmountError(1, len(args))
+		}
+		if len(args) == 1 {
+			prompt, err := stringArg(args, 0)
+			if err != nil {
+				return err
+			}
+			if _, err := io.WriteString(out(), prompt); err != nil {
+				return &Error{Message: err.Error(), Cause: err}
+			}
+		}
+		return readLineFrom(in)()
+	}
+}
+
+func readLineFrom(in func() *bufio.Reader) BuiltInFunction {
+	return func(args ...Object) Object {
+		if len(args) != 0 {
+			return argumentAmountError(0, len(args))
+		}
+		line, ok, err := readLine(in())
+		if err != nil {
+			return inputError(err)
+		}
+		if !ok {
+			return NullVal
+		}
+		return &String{Value: line}
+	}
+}
+
+func readAllFrom(in func() *bufio.Reader) BuiltInFunction {
+	return func(args ...Object) Object {
+		if len(args) != 0 {
+			return argumentAmountError(0, len(args))
+		}
+		data, err := io.ReadAll(in())
+		if err != nil {
+			return inputError(err)
+		}
+		return &String{Value: string(data)}
+	}
+}
+
+// linesFrom reads the rest of the input as an array of lines, so for loops
+// can iterate over it.
+func linesFrom(in func() *bufio.Reader) BuiltInFunction {
+	return func(args ...Object) Object {
+		if len(args) != 0 {
+			return argumentAmountError(0, len(args))
+		}
+		lines := []string{}
+		for {
+			line, ok, err := readLine(in())
+			if err != nil {
+				return inputError(err)
+			}
+			if !ok {
+				return stringArray(lines)
+			}
+			lines = append(lines, line)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package object
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestInputBuiltins(t *testing.T) {
+	var out bytes.Buffer
+	r := NewDefaultRegistry()
+	r.Input = strings.NewReader("ada\r\nfirst\nsecond\nthird\nrest\nof it")
+	r.Output = &out
+	call := func(name string, args ...Object) string {
+		b, _ := r.Lookup(name)
+		return b.Fn(args...).Inspect()
+	}
+
+	if got := call("input", &String{Value: "name? "}); got != "ada" {
+		t.Errorf("wrong input got %q", got)
+	}
+	if out.String() != "name? " {
+		t.Errorf("wrong prompt got %q", out.String())
+	}
+	if got := call("readLine"); got != "first" {
+		t.Errorf("wrong line got %q", got)
+	}
+	if got := call("input"); got != "second" {
+		t.Errorf("wrong input without a prompt got %q", got)
+	}
+	if got := call("readLine"); got != "third" {
+		t.Errorf("wrong line got %q", got)
+	}
+	if got := call("readAll"); got != "rest\nof it" {
+		t.Errorf("wrong rest got %q", got)
+	}
+	if got := call("readLine"); got != "null" {
+		t.Errorf("expected null at the end of the input got %q", got)
+	}
+	if got := call("readAll"); got != "" {
+		t.Errorf("expected nothing at the end of the input got %q", got)
+	}
+
+	r.Input = strings.NewReader("a\n\nb")
+	if got := call("lines"); got != "[a, , b]" {
+		t.Errorf("wrong lines got %q", got)
+	}
+	if got := call("lines"); got != "[]" {
+		t.Errorf("expected no lines at the end of the input got %q", got)
+	}
+	if got := call("readLine", &String{Value: "x"}); got != "ERROR : Want 0 Arguments got 1" {
+		t.Errorf("expected an argument error got %q", got)
+	}
+
+	sandboxed := (&Policy{Modules: []string{"io"}, Capabilities: []Capability{CapOutput}}).Apply(r)
+	if _, ok := sandboxed.Lookup("readLine"); ok {
+		t.Errorf("expected readLine to need the input capability")
+	}
+}
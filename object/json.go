@@ -0,0 +1,166 @@
+package object
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+func jsonParse(args ...Object) Object {
+	strs, err := stringArgs(args, 1)
+	if err != nil {
+		return err
+	}
+
+	dec := json.NewDecoder(strings.NewReader(strs[0]))
+	dec.UseNumber()
+	var value any
+	if err := dec.Decode(&value); err != nil {
+		return newError("Invalid JSON: %s", err)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return newError("Invalid JSON: unexpected data after the value")
+	}
+	return fromJSON(value)
+}
+
+func fromJSON(value any) Object {
+	switch value := value.(type) {
+	case map[string]any:
+		hash := &Hash{Pairs: make(map[HashKey]HashPair, len(value))}
+		for k, v := range value {
+			key := &String{Value: k}
+			hash.Pairs[key.HashKey()] = HashPair{Key: key, Value: fromJSON(v)}
+		}
+		return hash
+	case []any:
+		elements := make([]Object, len(value))
+		for i, v := range value {
+			elements[i] = fromJSON(v)
+		}
+		return &Array{Elements: elements}
+	case json.Number:
+		if i, err := strconv.ParseInt(string(value), 10, 64); err == nil {
+			return &Integer{Value: int(i)}
+		}
+		f, err := value.Float64()
+		if err != nil {
+			return newError("Invalid JSON: %s", err)
+		}
+		return &Float{Value: f}
+	case string:
+		return &String{Value: value}
+	case bool:
+		return NativeBool(value)
+	}
+	return NullVal
+}
+
+// jsonStringify writes value as JSON, indented by indent spaces or the indent
+// string when it is given. Keys of hashes are sorted so the output is stable.
+func jsonStringify(args ...Object) Object {
+	if len(args) != 1 && len(args) != 2 {
+		return argumentAmountError(2, len(args))
+	}
+
+	var buf bytes.Buffer
+	if err := writeJSON(&buf, args[0], map[Object]bool{}); err != nil {
+		return err
+	}
+	if len(args) == 1 {
+		return &String{Value: buf.String()}
+	}
+
+	var indent string
+	switch arg := args[1].(type) {
+	case *Integer:
+		indent = strings.Repeat(" ", arg.Value)
+	case *String:
+		indent = arg.Value
+	default:
+		return argumentTypeError("INTEGER or STRING", arg.Type(), 2)
+	}
+	var out bytes.Buffer
+	if err := json.Indent(&out, buf.Bytes(), "", indent); err != nil {
+		return newError("Invalid JSON: %s", err)
+	}
+	return &String{Value: out.String()}
+}
+
+// writeJSON keeps the arrays and hashes it is inside of in seen, so a cyclic
+// value is an error instead of endless recursion.
+func writeJSON(buf *bytes.Buffer, obj Object, seen map[Object]bool) *Error {
+	switch obj := obj.(type) {
+	case *Null:
+		buf.WriteString("null")
+	case *Boolean:
+		buf.WriteString(strconv.FormatBool(obj.Value))
+	case *Integer:
+		buf.WriteString(strconv.Itoa(obj.Value))
+	case *Float:
+		if math.IsNaN(obj.Value) || math.IsInf(obj.Value, 0) {
+			return newError("Float %s cant be converted to JSON", obj.Inspect())
+		}
+		str := strconv.FormatFloat(obj.Value, 'g', -1, 64)
+		if !strings.ContainsAny(str, ".eE") {
+			str += ".0"
+		}
+		buf.WriteString(str)
+	case *String:
+		writeJSONString(buf, obj.Value)
+	case *Array:
+		if seen[obj] {
+			return newError("Cyclic ARRAY cant be converted to JSON")
+		}
+		seen[obj] = true
+		defer delete(seen, obj)
+
+		buf.WriteByte('[')
+		for i, el := range obj.Elements {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if err := writeJSON(buf, el, seen); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(']')
+	case *Hash:
+		if seen[obj] {
+			return newError("Cyclic HASH cant be converted to JSON")
+		}
+		seen[obj] = true
+		defer delete(seen, obj)
+
+		buf.WriteByte('{')
+		for i, pair := range SortedPairs(obj) {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			switch pair.Key.(type) {
+			case *String, *Integer, *Boolean:
+				writeJSONString(buf, pair.Key.Inspect())
+			default:
+				return newError("Hash Key of Type %s cant be converted to JSON", pair.Key.Type())
+			}
+			buf.WriteByte(':')
+			if err := writeJSON(buf, pair.Value, seen); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte('}')
+	default:
+		return newError("Object of Type %s cant be converted to JSON", obj.Type())
+	}
+	return nil
+}
+
+func writeJSONString(buf *bytes.Buffer, str string) {
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode(str)
+	buf.Truncate(buf.Len() - 1)
+}
@@ -0,0 +1,100 @@
+package object
+
+import (
+	"testing"
+)
+
+func TestJSONRoundTrip(t *testing.T) {
+	tests := []string{
+		`null`,
+		`true`,
+		`-12`,
+		`1.5`,
+		`2.0`,
+		`1e+21`,
+		`"a \"quoted\" <tag> ü\n"`,
+		`[]`,
+		`{}`,
+		`[1,"two",3.5,false,null,[[]]]`,
+		`{"a":1,"b":[true,{"c":"d"}],"e":{}}`,
+	}
+
+	for _, input := range tests {
+		obj := jsonParse(&String{Value: input})
+		if err, ok := obj.(*Error); ok {
+			t.Fatalf("jsonParse(%s) %s", input, err.Message)
+		}
+		out := jsonStringify(obj)
+		if out.Inspect() != input {
+			t.Errorf("round trip of %s gave %s", input, out.Inspect())
+		}
+		again := jsonStringify(jsonParse(out))
+		if again.Inspect() != input {
+			t.Errorf("second round trip of %s gave %s", input, again.Inspect())
+		}
+	}
+}
+
+func TestJSONParse(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected ObjectType
+	}{
+		{`42`, INTEGER_OBJ},
+		{`42.0`, FLOAT_OBJ},
+		{`9223372036854775808`, FLOAT_OBJ},
+		{`"s"`, STR_OBJ},
+		{`[1]`, ARR_OBJ},
+		{`{"a": 1}`, HASH_OBJ},
+		{` {"a": [1, 2] } `, HASH_OBJ},
+		{`{"a": 1`, ERROR_OBJ},
+		{`[1] [2]`, ERROR_OBJ},
+		{``, ERROR_OBJ},
+	}
+
+	for _, tt := range tests {
+		obj := jsonParse(&String{Value: tt.input})
+		if obj.Type() != tt.expected {
+			t.Errorf("jsonParse(%s) want %s got %s", tt.input, tt.expected, obj.Inspect())
+		}
+	}
+
+	if jsonParse(&String{Value: "true"}) != TrueVal {
+		t.Errorf("expected jsonParse to return the shared true")
+	}
+}
+
+func TestJSONStringify(t *testing.T) {
+	arr := &Array{Elements: []Object{&Integer{Value: 1}}}
+	arr.Elements = append(arr.Elements, arr)
+	hash, _ := ToObject(map[string]any{"b": 1, "a": []any{true}})
+	self := hash.(*Hash)
+	key := &String{Value: "self"}
+	shared := &Array{Elements: []Object{}}
+
+	tests := []struct {
+		args     []Object
+		expected string
+	}{
+		{[]Object{hash}, `{"a":[true],"b":1}`},
+		{[]Object{hash, &Integer{Value: 2}}, "{\n  \"a\": [\n    true\n  ],\n  \"b\": 1\n}"},
+		{[]Object{&Array{Elements: []Object{}}, &String{Value: "\t"}}, "[]"},
+		{[]Object{&Array{Elements: []Object{shared, shared}}}, "[[],[]]"},
+		{[]Object{&Hash{Pairs: map[HashKey]HashPair{(&Integer{Value: 1}).HashKey(): {Key: &Integer{Value: 1}, Value: NullVal}}}}, `{"1":null}`},
+		{[]Object{arr}, "ERROR : Cyclic ARRAY cant be converted to JSON"},
+		{[]Object{&Function{}}, "ERROR : Object of Type FUNCTION_OBJ cant be converted to JSON"},
+		{[]Object{&Array{Elements: []Object{&BuiltIn{}}}}, "ERROR : Object of Type BUILTIN_FUNCTION cant be converted to JSON"},
+		{[]Object{hash, TrueVal}, "ERROR : Argument 2 has to be of Type INTEGER or STRING got BOOLEAN"},
+	}
+
+	for _, tt := range tests {
+		if got := jsonStringify(tt.args...).Inspect(); got != tt.expected {
+			t.Errorf("want %q got %q", tt.expected, got)
+		}
+	}
+
+	self.Pairs[key.HashKey()] = HashPair{Key: key, Value: self}
+	if got := jsonStringify(self).Inspect(); got != "ERROR : Cyclic HASH cant be converted to JSON" {
+		t.Errorf("expected an error for a cyclic hash got %q", got)
+	}
+}
@@ -3,6 +3,9 @@ package object
 import (
 	"bytes"
 	"fmt"
+	"hash/fnv"
+	"os"
+	gosort "sort"
 	"strings"
 	"time"
 
@@ -12,13 +15,28 @@ import (
 
 var NullVal = &Null{}
 
+// TrueVal and FalseVal are the booleans both engines compare by identity.
+var (
+	TrueVal  = &Boolean{Value: true}
+	FalseVal = &Boolean{Value: false}
+)
+
+func NativeBool(b bool) *Boolean {
+	if b {
+		return TrueVal
+	}
+	return FalseVal
+}
+
 const (
 	INTEGER_OBJ = "INTEGER"
 	FLOAT_OBJ   = "FLOAT"
 	BOOLEAN_OBJ = "BOOLEAN"
 	STR_OBJ     = "STRING"
 	ARR_OBJ     = "ARRAY"
+	HASH_OBJ    = "HASH"
 	TIME_OBJ    = "TIME"
+	REGEX_OBJ   = "REGEX"
 
 	WINDOW_OBJ = "WINDOW"
 	EDIT_OBJ   = "EDIT"
@@ -30,6 +48,15 @@ const (
 	ERROR_OBJ             = "ERROR"
 	BUILTIN_OBJ           = "BUILTIN_FUNCTION"
 	COMPILED_FUNCTION_OBJ = "COMPILED_FUNCTION"
+	CLOSURE_OBJ           = "CLOSURE"
+	CELL_OBJ              = "CELL"
+	QUOTE_OBJ             = "QUOTE"
+	MACRO_OBJ             = "MACRO"
+
+	TAIL_CALL_OBJ = "TAIL_CALL"
+
+	ANY_OBJ    = "ANY"
+	NUMBER_OBJ = "NUMBER"
 )
 
 type ObjectType string
@@ -53,6 +80,7 @@ func (t *Time) Inspect() string {
 }
 
 type CompiledFunction struct {
+	Name         string
 	Instructions code.Instructions
 	NumLocals    int
 	NumParams    int
@@ -66,6 +94,38 @@ func (cf *CompiledFunction) Inspect() string {
 	return fmt.Sprintf("CompiledFunction[%p]", cf)
 }
 
+// Closure is a CompiledFunction together with the variables it captured from
+// the functions around it.
+type Closure struct {
+	Fn   *CompiledFunction
+	Free []*Cell
+}
+
+func (c *Closure) Type() ObjectType {
+	return CLOSURE_OBJ
+}
+
+func (c *Closure) Inspect() string {
+	return fmt.Sprintf("Closure[%p]", c)
+}
+
+// Cell is a captured variable. While the function that declared it runs the
+// variable lives in stack slot Slot and Open is set, once it returns the Vm
+// moves the value into Value.
+type Cell struct {
+	Value Object
+	Slot  int
+	Open  bool
+}
+
+func (c *Cell) Type() ObjectType {
+	return CELL_OBJ
+}
+
+func (c *Cell) Inspect() string {
+	return fmt.Sprintf("Cell[%p]", c)
+}
+
 //type Label struct {
 //	Label *widgets.QLabel
 //}
@@ -109,8 +169,47 @@ func (cf *CompiledFunction) Inspect() string {
 
 type BuiltInFunction func(args ...Object) Object
 
+// Caller calls any callable value on the engine that runs the program, so
+// builtins and hosts can call back into Monkey functions.
+type Caller interface {
+	Call(fn Object, args ...Object) (Object, error)
+}
+
+// CallerFunction is a builtin that gets the Caller of the running engine.
+type CallerFunction func(c Caller, args ...Object) Object
+
+type Signature struct {
+	Params   []ObjectType
+	Variadic bool
+	// Optional is the number of trailing Params that can be left out.
+	Optional int
+	Return   ObjectType
+}
+
+func sig(ret ObjectType, params ...ObjectType) *Signature {
+	return &Signature{Params: params, Return: ret}
+}
+
+func variadicSig(ret ObjectType, params ...ObjectType) *Signature {
+	return &Signature{Params: params, Variadic: true, Return: ret}
+}
+
+func optionalSig(ret ObjectType, optional int, params ...ObjectType) *Signature {
+	return &Signature{Params: params, Optional: optional, Return: ret}
+}
+
 type BuiltIn struct {
-	Fn BuiltInFunction
+	Fn        BuiltInFunction
+	Sig       *Signature
+	Stringify bool
+	// CallFn is called instead of Fn when it is set.
+	CallFn CallerFunction
+	// Needs is the capability a sandbox has to grant before the builtin can
+	// be used.
+	Needs Capability
+	// Bind builds Fn for builtins that use the resources of the Registry they
+	// are registered in, like its output.
+	Bind func(r *Registry) BuiltInFunction
 }
 
 func (bi *BuiltIn) Type() ObjectType {
@@ -121,9 +220,11 @@ func (bi *BuiltIn) Inspect() string {
 }
 
 type Function struct {
-	Params []*ast.Ident
-	Body   *ast.BlockStatement
-	Env    *Env
+	Name     string
+	Params   []*ast.Ident
+	Patterns []ast.Expression
+	Body     *ast.BlockStatement
+	Env      *Env
 }
 
 func (f *Function) Type() ObjectType {
@@ -148,8 +249,87 @@ func (f *Function) Inspect() string {
 	return out.String()
 }
 
+// TailCall is returned by a call in tail position instead of its result, the
+// caller keeps invoking until it gets a real value.
+type TailCall struct {
+	Fn   Object
+	Args []Object
+}
+
+func (tc *TailCall) Type() ObjectType {
+	return TAIL_CALL_OBJ
+}
+
+func (tc *TailCall) Inspect() string {
+	return "tail call " + tc.Fn.Inspect()
+}
+
+type ComposedFunction struct {
+	First  Object
+	Second Object
+}
+
+func (cf *ComposedFunction) Type() ObjectType {
+	return FUNCTION_OBJ
+}
+
+func (cf *ComposedFunction) Inspect() string {
+	return cf.First.Inspect() + " >> " + cf.Second.Inspect()
+}
+
+func IsCallable(obj Object) bool {
+	switch obj.(type) {
+	case *Function, *CompiledFunction, *Closure, *BuiltIn, *ComposedFunction, *EnumConstructor:
+		return true
+	}
+	return false
+}
+
+type Quote struct {
+	Node ast.Node
+}
+
+func (q *Quote) Type() ObjectType {
+	return QUOTE_OBJ
+}
+
+func (q *Quote) Inspect() string {
+	return "QUOTE(" + q.Node.String() + ")"
+}
+
+type Macro struct {
+	Params []*ast.Ident
+	Body   *ast.BlockStatement
+	Env    *Env
+}
+
+func (m *Macro) Type() ObjectType {
+	return MACRO_OBJ
+}
+
+func (m *Macro) Inspect() string {
+	var out bytes.Buffer
+
+	var params = []string{}
+	for _, p := range m.Params {
+		params = append(params, p.String())
+	}
+
+	out.WriteString("macro")
+	out.WriteString("(")
+	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(") {\n")
+	out.WriteString(m.Body.String())
+	out.WriteString("\n}")
+
+	return out.String()
+}
+
 type Error struct {
 	Message string
+	// Cause is set when the error comes from a Go error the host may want to
+	// inspect, like a *StackOverflowError or a *LimitError.
+	Cause error
 }
 
 func (e *Error) Type() ObjectType {
@@ -159,6 +339,22 @@ func (e *Error) Inspect() string {
 	return "ERROR : " + e.Message
 }
 
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Unwrap() error {
+	return e.Cause
+}
+
+// ErrorFrom turns err into an error object, keeping it as the Cause.
+func ErrorFrom(err error) *Error {
+	if e, ok := err.(*Error); ok {
+		return e
+	}
+	return &Error{Message: err.Error(), Cause: err}
+}
+
 type ReturnValue struct {
 	Value Object
 }
@@ -172,6 +368,7 @@ func (rv *ReturnValue) Inspect() string {
 
 type Array struct {
 	Elements []Object
+	Frozen   bool
 }
 
 func (a *Array) Type() ObjectType {
@@ -193,6 +390,98 @@ func (a *Array) Inspect() string {
 	return out.String()
 }
 
+type HashKey struct {
+	Type  ObjectType
+	Value uint64
+}
+
+type Hashable interface {
+	HashKey() HashKey
+}
+
+type HashPair struct {
+	Key   Object
+	Value Object
+}
+
+type Hash struct {
+	Pairs  map[HashKey]HashPair
+	Frozen bool
+}
+
+func (h *Hash) Type() ObjectType {
+	return HASH_OBJ
+}
+
+func Iterate(obj Object) ([]Object, bool) {
+	switch obj := obj.(type) {
+	case *Array:
+		return obj.Elements, true
+	case *String:
+		items := []Object{}
+		for _, char := range obj.Value {
+			items = append(items, &String{Value: string(char)})
+		}
+		return items, true
+	case *Hash:
+		items := []Object{}
+		for _, pair := range SortedPairs(obj) {
+			items = append(items, &Array{Elements: []Object{pair.Key, pair.Value}})
+		}
+		return items, true
+	}
+	return nil, false
+}
+
+func SortedPairs(h *Hash) []HashPair {
+	pairs := make([]HashPair, 0, len(h.Pairs))
+	keys := make([]string, 0, len(h.Pairs))
+	for _, pair := range h.Pairs {
+		pairs = append(pairs, pair)
+		keys = append(keys, pair.Key.Inspect())
+	}
+	gosort.Sort(pairsByKey{pairs, keys})
+	return pairs
+}
+
+// pairsByKey orders pairs by the Inspect of their keys and keys that look
+// the same, like 1 and "1", by their Type.
+type pairsByKey struct {
+	pairs []HashPair
+	keys  []string
+}
+
+func (p pairsByKey) Len() int { return len(p.pairs) }
+
+func (p pairsByKey) Less(i, j int) bool {
+	if p.keys[i] != p.keys[j] {
+		return p.keys[i] < p.keys[j]
+	}
+	return p.pairs[i].Key.Type() < p.pairs[j].Key.Type()
+}
+
+func (p pairsByKey) Swap(i, j int) {
+	p.pairs[i], p.pairs[j] = p.pairs[j], p.pairs[i]
+	p.keys[i], p.keys[j] = p.keys[j], p.keys[i]
+}
+
+// Inspect lists the pairs sorted by key, so the output does not change from
+// run to run.
+func (h *Hash) Inspect() string {
+	var out bytes.Buffer
+
+	pairs := []string{}
+	for _, pair := range SortedPairs(h) {
+		pairs = append(pairs, pair.Key.Inspect()+": "+pair.Value.Inspect())
+	}
+
+	out.WriteString("{")
+	out.WriteString(strings.Join(pairs, ", "))
+	out.WriteString("}")
+
+	return out.String()
+}
+
 type String struct {
 	Value string
 }
@@ -204,6 +493,13 @@ func (s *String) Inspect() string {
 	return s.Value
 }
 
+func (s *String) HashKey() HashKey {
+	h := fnv.New64a()
+	h.Write([]byte(s.Value))
+
+	return HashKey{Type: s.Type(), Value: h.Sum64()}
+}
+
 type Integer struct {
 	Value int
 }
@@ -216,6 +512,10 @@ func (i *Integer) Inspect() string {
 	return fmt.Sprintf("%d", i.Value)
 }
 
+func (i *Integer) HashKey() HashKey {
+	return HashKey{Type: i.Type(), Value: uint64(i.Value)}
+}
+
 type Float struct {
 	Value float64
 }
@@ -240,6 +540,15 @@ func (b *Boolean) Inspect() string {
 	return fmt.Sprintf("%t", b.Value)
 }
 
+func (b *Boolean) HashKey() HashKey {
+	var value uint64
+	if b.Value {
+		value = 1
+	}
+
+	return HashKey{Type: b.Type(), Value: value}
+}
+
 type Null struct{}
 
 func (n *Null) Type() ObjectType {
@@ -262,34 +571,101 @@ var Builtins = []struct {
 	Name    string
 	Builtin *BuiltIn
 }{
-	{"len", &BuiltIn{Fn: length}},
-	{"print", &BuiltIn{Fn: print}},
-	{"push", &BuiltIn{Fn: push}},
-	{"sort", &BuiltIn{Fn: sort}},
-	{"typeof", &BuiltIn{Fn: typeof}},
-	{"randInt", &BuiltIn{Fn: randInt}},
-	{"randIntArray", &BuiltIn{Fn: randIntArray}},
-	{"toStr", &BuiltIn{Fn: toStr}},
-	{"toInt", &BuiltIn{Fn: toInt}},
-
-	{"sin", &BuiltIn{Fn: sin}},
-	{"asin", &BuiltIn{Fn: asin}},
-
-	{"cos", &BuiltIn{Fn: cos}},
-	{"acos", &BuiltIn{Fn: acos}},
-
-	{"tan", &BuiltIn{Fn: tan}},
-	{"atan", &BuiltIn{Fn: atan}},
-
-	{"cot", &BuiltIn{Fn: cot}},
-	{"acot", &BuiltIn{Fn: acot}},
-
-	{"sec", &BuiltIn{Fn: sec}},
-	{"asec", &BuiltIn{Fn: asec}},
-
-	{"csc", &BuiltIn{Fn: csc}},
-	{"acsc", &BuiltIn{Fn: acsc}},
-
-	{"ln", &BuiltIn{Fn: nlog}},
-	{"log", &BuiltIn{Fn: logBase}},
+	{"len", &BuiltIn{Fn: length, Sig: sig(INTEGER_OBJ, ANY_OBJ)}},
+	{"print", &BuiltIn{Fn: print, Sig: variadicSig(NULL, ANY_OBJ), Stringify: true, Needs: CapOutput, Bind: func(r *Registry) BuiltInFunction { return printTo(r.Stdout) }}},
+	{"push", &BuiltIn{Fn: push, Sig: sig(ARR_OBJ, ARR_OBJ, ANY_OBJ)}},
+	{"sort", &BuiltIn{Fn: sort, CallFn: sortWith, Sig: optionalSig(ARR_OBJ, 1, ARR_OBJ, FUNCTION_OBJ)}},
+	{"typeof", &BuiltIn{Fn: typeof, Sig: sig(STR_OBJ, ANY_OBJ)}},
+	{"randInt", &BuiltIn{Fn: randInt, Sig: sig(INTEGER_OBJ, INTEGER_OBJ)}},
+	{"randIntArray", &BuiltIn{Fn: randIntArray, Sig: variadicSig(ARR_OBJ, INTEGER_OBJ, INTEGER_OBJ, BOOLEAN_OBJ), Bind: func(r *Registry) BuiltInFunction { return randIntArrayWithin(r.policy) }}},
+	{"toStr", &BuiltIn{Fn: toStr, Sig: sig(STR_OBJ, ANY_OBJ), Stringify: true}},
+	{"toInt", &BuiltIn{Fn: toInt, Sig: sig(INTEGER_OBJ, STR_OBJ)}},
+
+	{"sin", &BuiltIn{Fn: sin, Sig: sig(FLOAT_OBJ, NUMBER_OBJ)}},
+	{"asin", &BuiltIn{Fn: asin, Sig: sig(FLOAT_OBJ, NUMBER_OBJ)}},
+
+	{"cos", &BuiltIn{Fn: cos, Sig: sig(FLOAT_OBJ, NUMBER_OBJ)}},
+	{"acos", &BuiltIn{Fn: acos, Sig: sig(FLOAT_OBJ, NUMBER_OBJ)}},
+
+	{"tan", &BuiltIn{Fn: tan, Sig: sig(FLOAT_OBJ, NUMBER_OBJ)}},
+	{"atan", &BuiltIn{Fn: atan, Sig: sig(FLOAT_OBJ, NUMBER_OBJ)}},
+
+	{"cot", &BuiltIn{Fn: cot, Sig: sig(FLOAT_OBJ, NUMBER_OBJ)}},
+	{"acot", &BuiltIn{Fn: acot, Sig: sig(FLOAT_OBJ, NUMBER_OBJ)}},
+
+	{"sec", &BuiltIn{Fn: sec, Sig: sig(FLOAT_OBJ, NUMBER_OBJ)}},
+	{"asec", &BuiltIn{Fn: asec, Sig: sig(FLOAT_OBJ, NUMBER_OBJ)}},
+
+	{"csc", &BuiltIn{Fn: csc, Sig: sig(FLOAT_OBJ, NUMBER_OBJ)}},
+	{"acsc", &BuiltIn{Fn: acsc, Sig: sig(FLOAT_OBJ, NUMBER_OBJ)}},
+
+	{"ln", &BuiltIn{Fn: nlog, Sig: sig(FLOAT_OBJ, NUMBER_OBJ)}},
+	{"log", &BuiltIn{Fn: logBase, Sig: sig(FLOAT_OBJ, NUMBER_OBJ, NUMBER_OBJ)}},
+
+	{"freeze", &BuiltIn{Fn: freeze, Sig: sig(ANY_OBJ, ANY_OBJ)}},
+
+	{"printf", &BuiltIn{Fn: printf, Sig: variadicSig(NULL, STR_OBJ, ANY_OBJ), Needs: CapOutput, Bind: func(r *Registry) BuiltInFunction { return printfTo(r.Stdout) }}},
+	{"toFloat", &BuiltIn{Fn: toFloat, Sig: sig(FLOAT_OBJ, STR_OBJ)}},
+	{"randFloat", &BuiltIn{Fn: randFloat, Sig: sig(FLOAT_OBJ, FLOAT_OBJ)}},
+
+	{"map", &BuiltIn{CallFn: mapArray, Sig: sig(ARR_OBJ, ARR_OBJ, FUNCTION_OBJ)}},
+	{"filter", &BuiltIn{CallFn: filter, Sig: sig(ARR_OBJ, ARR_OBJ, FUNCTION_OBJ)}},
+	{"reduce", &BuiltIn{CallFn: reduce, Sig: optionalSig(ANY_OBJ, 1, ARR_OBJ, FUNCTION_OBJ, ANY_OBJ)}},
+	{"find", &BuiltIn{CallFn: find, Sig: sig(ANY_OBJ, ARR_OBJ, FUNCTION_OBJ)}},
+	{"any", &BuiltIn{CallFn: anyOf, Sig: sig(BOOLEAN_OBJ, ARR_OBJ, FUNCTION_OBJ)}},
+	{"all", &BuiltIn{CallFn: allOf, Sig: sig(BOOLEAN_OBJ, ARR_OBJ, FUNCTION_OBJ)}},
+	{"zip", &BuiltIn{Fn: zip, Sig: variadicSig(ARR_OBJ, ARR_OBJ)}},
+	{"enumerate", &BuiltIn{Fn: enumerate, Sig: sig(ARR_OBJ, ARR_OBJ)}},
+	{"flatten", &BuiltIn{Fn: flatten, Sig: optionalSig(ARR_OBJ, 1, ARR_OBJ, INTEGER_OBJ)}},
+	{"unique", &BuiltIn{Fn: unique, Sig: sig(ARR_OBJ, ARR_OBJ)}},
+	{"groupBy", &BuiltIn{CallFn: groupBy, Sig: sig(HASH_OBJ, ARR_OBJ, FUNCTION_OBJ)}},
+
+	{"split", &BuiltIn{Fn: splitWithin(nil), Sig: sig(ARR_OBJ, STR_OBJ, ANY_OBJ), Bind: func(r *Registry) BuiltInFunction { return splitWithin(r.policy) }}},
+	{"join", &BuiltIn{Fn: joinWithin(nil), Sig: sig(STR_OBJ, ARR_OBJ, STR_OBJ), Bind: func(r *Registry) BuiltInFunction { return joinWithin(r.policy) }}},
+	{"replace", &BuiltIn{Fn: replace, Sig: sig(STR_OBJ, STR_OBJ, ANY_OBJ, STR_OBJ)}},
+	{"contains", &BuiltIn{Fn: contains, Sig: sig(BOOLEAN_OBJ, STR_OBJ, STR_OBJ)}},
+	{"startsWith", &BuiltIn{Fn: startsWith, Sig: sig(BOOLEAN_OBJ, STR_OBJ, STR_OBJ)}},
+	{"endsWith", &BuiltIn{Fn: endsWith, Sig: sig(BOOLEAN_OBJ, STR_OBJ, STR_OBJ)}},
+	{"indexOf", &BuiltIn{Fn: indexOf, Sig: sig(INTEGER_OBJ, STR_OBJ, STR_OBJ)}},
+	{"trim", &BuiltIn{Fn: trim, Sig: optionalSig(STR_OBJ, 1, STR_OBJ, STR_OBJ)}},
+	{"upper", &BuiltIn{Fn: upper, Sig: sig(STR_OBJ, STR_OBJ)}},
+	{"lower", &BuiltIn{Fn: lower, Sig: sig(STR_OBJ, STR_OBJ)}},
+	{"repeat", &BuiltIn{Fn: repeatWithin(nil), Sig: sig(STR_OBJ, STR_OBJ, INTEGER_OBJ), Bind: func(r *Registry) BuiltInFunction { return repeatWithin(r.policy) }}},
+	{"padLeft", &BuiltIn{Fn: padWithin(nil, true), Sig: optionalSig(STR_OBJ, 1, STR_OBJ, INTEGER_OBJ, STR_OBJ), Bind: func(r *Registry) BuiltInFunction { return padWithin(r.policy, true) }}},
+	{"padRight", &BuiltIn{Fn: padWithin(nil, false), Sig: optionalSig(STR_OBJ, 1, STR_OBJ, INTEGER_OBJ, STR_OBJ), Bind: func(r *Registry) BuiltInFunction { return padWithin(r.policy, false) }}},
+	{"chars", &BuiltIn{Fn: charsWithin(nil), Sig: sig(ARR_OBJ, STR_OBJ), Bind: func(r *Registry) BuiltInFunction { return charsWithin(r.policy) }}},
+	{"ord", &BuiltIn{Fn: ord, Sig: sig(INTEGER_OBJ, STR_OBJ)}},
+	{"chr", &BuiltIn{Fn: chr, Sig: sig(STR_OBJ, INTEGER_OBJ)}},
+	{"format", &BuiltIn{Fn: format, Sig: variadicSig(STR_OBJ, STR_OBJ, ANY_OBJ)}},
+
+	{"regex", &BuiltIn{Fn: regex, Sig: sig(REGEX_OBJ, STR_OBJ)}},
+	{"match", &BuiltIn{Fn: match, Sig: sig(ANY_OBJ, REGEX_OBJ, STR_OBJ)}},
+	{"matchAll", &BuiltIn{Fn: matchAll, Sig: sig(ARR_OBJ, REGEX_OBJ, STR_OBJ)}},
+
+	{"jsonParse", &BuiltIn{Fn: jsonParse, Sig: sig(ANY_OBJ, STR_OBJ)}},
+	{"jsonStringify", &BuiltIn{Fn: jsonStringify, Sig: optionalSig(STR_OBJ, 1, ANY_OBJ, ANY_OBJ)}},
+
+	{"csvParse", &BuiltIn{Fn: csvParse, Sig: optionalSig(ARR_OBJ, 1, STR_OBJ, HASH_OBJ)}},
+	{"csvStringify", &BuiltIn{Fn: csvStringify, Sig: optionalSig(STR_OBJ, 1, ARR_OBJ, HASH_OBJ)}},
+
+	{"readFile", &BuiltIn{Fn: readFileFrom(osFiles), Sig: sig(STR_OBJ, STR_OBJ), Needs: CapFileRead, Bind: func(r *Registry) BuiltInFunction { return readFileFrom(r.FileSystem) }}},
+	{"writeFile", &BuiltIn{Fn: writeFileTo(osFiles, false), Sig: sig(NULL, STR_OBJ, STR_OBJ), Needs: CapFileWrite, Bind: func(r *Registry) BuiltInFunction { return writeFileTo(r.FileSystem, false) }}},
+	{"appendFile", &BuiltIn{Fn: writeFileTo(osFiles, true), Sig: sig(NULL, STR_OBJ, STR_OBJ), Needs: CapFileWrite, Bind: func(r *Registry) BuiltInFunction { return writeFileTo(r.FileSystem, true) }}},
+	{"readLines", &BuiltIn{Fn: readLinesFrom(osFiles), Sig: sig(ARR_OBJ, STR_OBJ), Needs: CapFileRead, Bind: func(r *Registry) BuiltInFunction { return readLinesFrom(r.FileSystem) }}},
+	{"listDir", &BuiltIn{Fn: listDirFrom(osFiles), Sig: sig(ARR_OBJ, STR_OBJ), Needs: CapFileRead, Bind: func(r *Registry) BuiltInFunction { return listDirFrom(r.FileSystem) }}},
+	{"exists", &BuiltIn{Fn: existsIn(osFiles), Sig: sig(BOOLEAN_OBJ, STR_OBJ), Needs: CapFileRead, Bind: func(r *Registry) BuiltInFunction { return existsIn(r.FileSystem) }}},
+	{"remove", &BuiltIn{Fn: removeFrom(osFiles), Sig: sig(NULL, STR_OBJ), Needs: CapFileWrite, Bind: func(r *Registry) BuiltInFunction { return removeFrom(r.FileSystem) }}},
+	{"pathJoin", &BuiltIn{Fn: pathJoin, Sig: variadicSig(STR_OBJ, STR_OBJ)}},
+	{"pathBase", &BuiltIn{Fn: pathBase, Sig: sig(STR_OBJ, STR_OBJ)}},
+	{"pathDir", &BuiltIn{Fn: pathDir, Sig: sig(STR_OBJ, STR_OBJ)}},
+	{"pathExt", &BuiltIn{Fn: pathExt, Sig: sig(STR_OBJ, STR_OBJ)}},
+
+	{"input", &BuiltIn{Fn: inputFrom(stdin, stdout), Sig: optionalSig(ANY_OBJ, 1, STR_OBJ), Needs: CapInput, Bind: func(r *Registry) BuiltInFunction { return inputFrom(r.Reader, r.Stdout) }}},
+	{"readLine", &BuiltIn{Fn: readLineFrom(stdin), Sig: sig(ANY_OBJ), Needs: CapInput, Bind: func(r *Registry) BuiltInFunction { return readLineFrom(r.Reader) }}},
+	{"readAll", &BuiltIn{Fn: readAllFrom(stdin), Sig: sig(STR_OBJ), Needs: CapInput, Bind: func(r *Registry) BuiltInFunction { return readAllFrom(r.Reader) }}},
+	{"lines", &BuiltIn{Fn: linesFrom(stdin), Sig: sig(ARR_OBJ), Needs: CapInput, Bind: func(r *Registry) BuiltInFunction { return linesFrom(r.Reader) }}},
+
+	{"args", &BuiltIn{Fn: argsFrom(noArgs), Sig: sig(ARR_OBJ), Bind: func(r *Registry) BuiltInFunction { return argsFrom(r.Arguments) }}},
+	{"env", &BuiltIn{Fn: envFrom(os.LookupEnv), Sig: sig(ANY_OBJ, STR_OBJ), Needs: CapEnv, Bind: func(r *Registry) BuiltInFunction { return envFrom(r.LookupEnv) }}},
+	{"exit", &BuiltIn{Fn: exit, Sig: optionalSig(NULL, 1, INTEGER_OBJ)}},
 }
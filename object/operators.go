@@ -0,0 +1,48 @@
+package object
+
+const (
+	NEG_METHOD   = "__neg__"
+	INDEX_METHOD = "__index__"
+	STR_METHOD   = "__str__"
+	EQ_METHOD    = "__eq__"
+	NE_METHOD    = "__ne__"
+	LT_METHOD    = "__lt__"
+)
+
+var OperatorMethods = map[string]string{
+	"+":  "__add__",
+	"-":  "__sub__",
+	"*":  "__mul__",
+	"/":  "__div__",
+	"^":  "__pow__",
+	"==": EQ_METHOD,
+	"!=": NE_METHOD,
+	"<":  LT_METHOD,
+}
+
+func Method(obj Object, name string) (Object, bool) {
+	hash, ok := obj.(*Hash)
+	if !ok {
+		return nil, false
+	}
+
+	pair, ok := hash.Pairs[(&String{Value: name}).HashKey()]
+	if !ok {
+		return nil, false
+	}
+
+	switch pair.Value.(type) {
+	case *Function, *CompiledFunction, *Closure, *BuiltIn:
+		return pair.Value, true
+	}
+	return nil, false
+}
+
+func FindMethod(name string, operands ...Object) (Object, bool) {
+	for _, operand := range operands {
+		if fn, ok := Method(operand, name); ok {
+			return fn, true
+		}
+	}
+	return nil, false
+}
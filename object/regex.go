@@ -0,0 +1,107 @@
+package object
+
+import (
+	"regexp"
+)
+
+type Regex struct {
+	Value *regexp.Regexp
+}
+
+func (r *Regex) Type() ObjectType {
+	return REGEX_OBJ
+}
+func (r *Regex) Inspect() string {
+	return "/" + r.Value.String() + "/"
+}
+
+func NewRegex(pattern string) Object {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return newError("Invalid Regex /%s/: %s", pattern, err)
+	}
+	return &Regex{Value: re}
+}
+
+func regexArg(args []Object, pos int) (*regexp.Regexp, *Error) {
+	re, ok := args[pos].(*Regex)
+	if !ok {
+		return nil, argumentTypeError(REGEX_OBJ, args[pos].Type(), pos+1)
+	}
+	return re.Value, nil
+}
+
+func regex(args ...Object) Object {
+	strs, err := stringArgs(args, 1)
+	if err != nil {
+		return err
+	}
+	return NewRegex(strs[0])
+}
+
+// match returns the first match of re in str or null. A regex with named
+// groups gives a hash of them, any other one an array of the whole match and
+// its groups. Groups that did not take part in the match are null.
+func match(args ...Object) Object {
+	if len(args) != 2 {
+		return argumentAmountError(2, len(args))
+	}
+	re, err := regexArg(args, 0)
+	if err != nil {
+		return err
+	}
+	str, err := stringArg(args, 1)
+	if err != nil {
+		return err
+	}
+
+	loc := re.FindStringSubmatchIndex(str)
+	if loc == nil {
+		return NullVal
+	}
+	return matchObject(re, str, loc)
+}
+
+func matchAll(args ...Object) Object {
+	if len(args) != 2 {
+		return argumentAmountError(2, len(args))
+	}
+	re, err := regexArg(args, 0)
+	if err != nil {
+		return err
+	}
+	str, err := stringArg(args, 1)
+	if err != nil {
+		return err
+	}
+
+	elements := []Object{}
+	for _, loc := range re.FindAllStringSubmatchIndex(str, -1) {
+		elements = append(elements, matchObject(re, str, loc))
+	}
+	return &Array{Elements: elements}
+}
+
+func matchObject(re *regexp.Regexp, str string, loc []int) Object {
+	groups := make([]Object, len(loc)/2)
+	for i := range groups {
+		if loc[2*i] < 0 {
+			groups[i] = NullVal
+			continue
+		}
+		groups[i] = &String{Value: str[loc[2*i]:loc[2*i+1]]}
+	}
+
+	named := &Hash{Pairs: map[HashKey]HashPair{}}
+	for i, name := range re.SubexpNames() {
+		if name == "" {
+			continue
+		}
+		key := &String{Value: name}
+		named.Pairs[key.HashKey()] = HashPair{Key: key, Value: groups[i]}
+	}
+	if len(named.Pairs) > 0 {
+		return named
+	}
+	return &Array{Elements: groups}
+}
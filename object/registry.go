@@ -0,0 +1,155 @@
+package object
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+)
+
+// Registry holds the builtins of one interpreter. The compiler refers to
+// builtins by their position in the Registry, so the vm has to run bytecode
+// with the Registry it was compiled with.
+type Registry struct {
+	// Output receives what print and printf write, os.Stdout when nil.
+	Output io.Writer
+	// Input is what programs read from, os.Stdin when nil.
+	Input io.Reader
+	// Files is the file system of the file builtins, the one of the host
+	// when nil.
+	Files FileSystem
+	// Args are the arguments args returns to the program.
+	Args []string
+	// Env looks up the variables of env, os.LookupEnv when nil. Hosts set it
+	// to NoEnv to hide the environment.
+	Env func(name string) (string, bool)
+
+	names    []string
+	builtins []*BuiltIn
+	policy   *Policy
+
+	in     *bufio.Reader
+	inFrom io.Reader
+}
+
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// NewDefaultRegistry returns a Registry with every builtin in Builtins.
+func NewDefaultRegistry() *Registry {
+	r := NewRegistry()
+	for _, def := range Builtins {
+		r.Register(def.Name, def.Builtin)
+	}
+	return r
+}
+
+// Register adds b under name, replacing a builtin of the same name in place.
+func (r *Registry) Register(name string, b *BuiltIn) {
+	if b != nil {
+		bound := *b
+		if b.Bind != nil {
+			bound.Fn = b.Bind(r)
+		}
+		b = &bound
+	}
+
+	if i := r.Index(name); i >= 0 {
+		r.builtins[i] = b
+		return
+	}
+	r.names = append(r.names, name)
+	r.builtins = append(r.builtins, b)
+}
+
+// RegisterFunc adds a Go function that takes and returns objects.
+func (r *Registry) RegisterFunc(name string, fn BuiltInFunction) {
+	r.Register(name, &BuiltIn{Fn: fn})
+}
+
+func (r *Registry) Index(name string) int {
+	for i, n := range r.names {
+		if n == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// Lookup returns the builtin called name. Builtins removed by a sandbox keep
+// their name and position but are nil.
+func (r *Registry) Lookup(name string) (*BuiltIn, bool) {
+	if i := r.Index(name); i >= 0 && r.builtins[i] != nil {
+		return r.builtins[i], true
+	}
+	return nil, false
+}
+
+func (r *Registry) Len() int {
+	return len(r.names)
+}
+
+func (r *Registry) Name(i int) string {
+	return r.names[i]
+}
+
+func (r *Registry) At(i int) *BuiltIn {
+	return r.builtins[i]
+}
+
+func (r *Registry) Stdout() io.Writer {
+	if r.Output != nil {
+		return r.Output
+	}
+	return os.Stdout
+}
+
+func (r *Registry) Stdin() io.Reader {
+	if r.Input != nil {
+		return r.Input
+	}
+	return os.Stdin
+}
+
+// Reader buffers Stdin for the input builtins. Hosts that read the same input
+// themselves, like the REPL, should read through it too.
+func (r *Registry) Reader() *bufio.Reader {
+	if r.Input == nil {
+		return stdin()
+	}
+	if r.in == nil || r.inFrom != r.Input {
+		r.in, r.inFrom = bufio.NewReader(r.Input), r.Input
+	}
+	return r.in
+}
+
+func (r *Registry) Arguments() []string {
+	return r.Args
+}
+
+func (r *Registry) LookupEnv(name string) (string, bool) {
+	if r.Env != nil {
+		return r.Env(name)
+	}
+	return os.LookupEnv(name)
+}
+
+func (r *Registry) FileSystem() FileSystem {
+	if r.Files != nil {
+		return r.Files
+	}
+	return OSFileSystem{}
+}
+
+// Bind registers the Go function fn as a builtin called name. Arguments are
+// checked and converted with FromObject and results with ToObject.
+func (r *Registry) Bind(name string, fn any) error {
+	v := reflect.ValueOf(fn)
+	if v.Kind() != reflect.Func || v.IsNil() {
+		return fmt.Errorf("cant bind %T as %s, it is not a function", fn, name)
+	}
+	r.Register(name, bindFunc(v))
+	return nil
+}
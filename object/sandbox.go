@@ -0,0 +1,177 @@
+package object
+
+import (
+	"fmt"
+	"io"
+	"sync"
+)
+
+type Capability string
+
+const (
+	CapOutput    Capability = "output"
+	CapInput     Capability = "input"
+	CapEnv       Capability = "env"
+	CapFileRead  Capability = "file read"
+	CapFileWrite Capability = "file write"
+)
+
+// Modules groups builtins so a Policy can allow them together.
+var Modules = map[string][]string{
+	"core":        {"len", "push", "sort", "typeof", "toStr", "toInt", "toFloat", "freeze"},
+	"io":          {"print", "printf", "input", "readLine", "readAll", "lines"},
+	"math":        {"sin", "asin", "cos", "acos", "tan", "atan", "cot", "acot", "sec", "asec", "csc", "acsc", "ln", "log"},
+	"random":      {"randInt", "randIntArray", "randFloat"},
+	"collections": {"map", "filter", "reduce", "find", "any", "all", "zip", "enumerate", "flatten", "unique", "groupBy"},
+	"strings":     {"split", "join", "replace", "contains", "startsWith", "endsWith", "indexOf", "trim", "upper", "lower", "repeat", "padLeft", "padRight", "chars", "ord", "chr", "format"},
+	"regex":       {"regex", "match", "matchAll"},
+	"json":        {"jsonParse", "jsonStringify"},
+	"csv":         {"csvParse", "csvStringify"},
+	"files":       {"readFile", "writeFile", "appendFile", "readLines", "listDir", "exists", "remove"},
+	"path":        {"pathJoin", "pathBase", "pathDir", "pathExt"},
+	"system":      {"args", "env", "exit"},
+}
+
+// SandboxError is returned when a sandboxed program hits one of the limits of
+// its Policy or uses a builtin the Policy does not allow.
+type SandboxError struct {
+	Limit string
+	Max   int
+	Name  string
+}
+
+func (e *SandboxError) Error() string {
+	if e.Name != "" {
+		return fmt.Sprintf("Sandbox: %s is not allowed", e.Name)
+	}
+	return fmt.Sprintf("Sandbox: %s limit of %d exceeded", e.Limit, e.Max)
+}
+
+// Policy selects what a sandboxed program may use. The same Policy is applied
+// by the evaluator and by the compiler and vm. Zero limits mean no limit and
+// MaxSteps, like the step limits of the engines, only applies to runs started
+// with EvalContext or RunContext.
+type Policy struct {
+	Builtins     []string
+	Modules      []string
+	Capabilities []Capability
+
+	MaxArrayLen    int
+	MaxStringLen   int
+	MaxHashLen     int
+	MaxOutputBytes int
+	MaxSteps       int
+}
+
+func (p *Policy) Grants(c Capability) bool {
+	for _, granted := range p.Capabilities {
+		if granted == c {
+			return true
+		}
+	}
+	return false
+}
+
+// Allows reports whether the builtin b registered as name may be used.
+func (p *Policy) Allows(name string, b *BuiltIn) bool {
+	if b == nil || (b.Needs != "" && !p.Grants(b.Needs)) {
+		return false
+	}
+	for _, allowed := range p.Builtins {
+		if allowed == name {
+			return true
+		}
+	}
+	for _, module := range p.Modules {
+		for _, allowed := range Modules[module] {
+			if allowed == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Apply returns the Registry a sandboxed program sees. Builtins the Policy
+// does not allow stay in their position as nil, so bytecode compiled against
+// r cannot reach them, and output is counted against MaxOutputBytes. Each
+// Registry Apply returns counts its own output.
+func (p *Policy) Apply(r *Registry) *Registry {
+	if r.policy == p {
+		return r
+	}
+	sandboxed := &Registry{Output: &limitWriter{policy: p, out: r.Stdout}, Input: r.Input, Files: r.Files, Args: r.Args, Env: r.Env, policy: p}
+	for i := 0; i < r.Len(); i++ {
+		if b := r.At(i); p.Allows(r.Name(i), b) {
+			sandboxed.Register(r.Name(i), b)
+		} else {
+			sandboxed.names = append(sandboxed.names, r.Name(i))
+			sandboxed.builtins = append(sandboxed.builtins, nil)
+		}
+	}
+	return sandboxed
+}
+
+// CheckSize reports whether obj is larger than the Policy allows.
+func (p *Policy) CheckSize(obj Object) error {
+	switch obj := obj.(type) {
+	case *Array:
+		return p.CheckArrayLen(len(obj.Elements))
+	case *String:
+		return p.CheckStringLen(len(obj.Value))
+	case *Hash:
+		if p.MaxHashLen > 0 && len(obj.Pairs) > p.MaxHashLen {
+			return &SandboxError{Limit: "hash size", Max: p.MaxHashLen}
+		}
+	}
+	return nil
+}
+
+// CheckStringLen reports whether a string of n bytes is larger than the
+// Policy allows, so builtins can check before they build it. A nil Policy
+// allows every size.
+func (p *Policy) CheckStringLen(n int) error {
+	if p != nil && p.MaxStringLen > 0 && n > p.MaxStringLen {
+		return &SandboxError{Limit: "string size", Max: p.MaxStringLen}
+	}
+	return nil
+}
+
+// CheckArrayLen is CheckStringLen for arrays of n elements.
+func (p *Policy) CheckArrayLen(n int) error {
+	if p != nil && p.MaxArrayLen > 0 && n > p.MaxArrayLen {
+		return &SandboxError{Limit: "array size", Max: p.MaxArrayLen}
+	}
+	return nil
+}
+
+// Steps returns the tighter of max and the step limit of the Policy.
+func (p *Policy) Steps(max int) int {
+	if p.MaxSteps > 0 && (max == 0 || p.MaxSteps < max) {
+		return p.MaxSteps
+	}
+	return max
+}
+
+type limitWriter struct {
+	policy *Policy
+	out    func() io.Writer
+
+	mu      sync.Mutex
+	written int
+}
+
+func (w *limitWriter) Write(b []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	p := w.policy
+	out := w.out()
+	if p.MaxOutputBytes > 0 && w.written+len(b) > p.MaxOutputBytes {
+		n, _ := out.Write(b[:p.MaxOutputBytes-w.written])
+		w.written += n
+		return n, &SandboxError{Limit: "output", Max: p.MaxOutputBytes}
+	}
+	n, err := out.Write(b)
+	w.written += n
+	return n, err
+}
@@ -0,0 +1,355 @@
+package object
+
+import (
+	"math"
+	"strings"
+	"unicode/utf8"
+)
+
+// Positions and widths of the string builtins count runes, not bytes.
+
+func stringArg(args []Object, pos int) (string, *Error) {
+	str, ok := args[pos].(*String)
+	if !ok {
+		return "", argumentTypeError(STR_OBJ, args[pos].Type(), pos+1)
+	}
+	return str.Value, nil
+}
+
+func intArg(args []Object, pos int) (int, *Error) {
+	i, ok := args[pos].(*Integer)
+	if !ok {
+		return 0, argumentTypeError(INTEGER_OBJ, args[pos].Type(), pos+1)
+	}
+	return i.Value, nil
+}
+
+func stringArgs(args []Object, num int) ([]string, *Error) {
+	if len(args) != num {
+		return nil, argumentAmountError(num, len(args))
+	}
+	strs := make([]string, num)
+	for i := range args {
+		str, err := stringArg(args, i)
+		if err != nil {
+			return nil, err
+		}
+		strs[i] = str
+	}
+	return strs, nil
+}
+
+func stringArray(strs []string) *Array {
+	elements := make([]Object, len(strs))
+	for i, str := range strs {
+		elements[i] = &String{Value: str}
+	}
+	return &Array{Elements: elements}
+}
+
+// split and replace take a regex instead of a separator or old string too.
+// splitWithin checks the number of parts against p before it splits.
+func splitWithin(p *Policy) BuiltInFunction {
+	return func(args ...Object) Object {
+		if len(args) == 2 {
+			if re, ok := args[1].(*Regex); ok {
+				str, err := stringArg(args, 0)
+				if err != nil {
+					return err
+				}
+				limit := -1
+				if p != nil && p.MaxArrayLen > 0 {
+					limit = p.MaxArrayLen + 1
+				}
+				parts := re.Value.Split(str, limit)
+				if err := p.CheckArrayLen(len(parts)); err != nil {
+					return ErrorFrom(err)
+				}
+				return stringArray(parts)
+			}
+		}
+		strs, err := stringArgs(args, 2)
+		if err != nil {
+			return err
+		}
+		n := strings.Count(strs[0], strs[1]) + 1
+		if strs[1] == "" {
+			n = utf8.RuneCountInString(strs[0])
+		}
+		if err := p.CheckArrayLen(n); err != nil {
+			return ErrorFrom(err)
+		}
+		return stringArray(strings.Split(strs[0], strs[1]))
+	}
+}
+
+// joinWithin checks the length of the result against p before it joins.
+func joinWithin(p *Policy) BuiltInFunction {
+	return func(args ...Object) Object {
+		if len(args) != 2 {
+			return argumentAmountError(2, len(args))
+		}
+		arr, err := arrayArg(args, 0)
+		if err != nil {
+			return err
+		}
+		sep, err := stringArg(args, 1)
+		if err != nil {
+			return err
+		}
+
+		strs := make([]string, len(arr.Elements))
+		size := 0
+		for i, el := range arr.Elements {
+			str, ok := el.(*String)
+			if !ok {
+				return newError("Element on Position %d is Not a STRING", i)
+			}
+			strs[i] = str.Value
+			size += len(str.Value)
+			if i > 0 {
+				size += len(sep)
+			}
+		}
+		if err := p.CheckStringLen(size); err != nil {
+			return ErrorFrom(err)
+		}
+		return &String{Value: strings.Join(strs, sep)}
+	}
+}
+
+func replace(args ...Object) Object {
+	if len(args) == 3 {
+		if re, ok := args[1].(*Regex); ok {
+			str, err := stringArg(args, 0)
+			if err != nil {
+				return err
+			}
+			repl, err := stringArg(args, 2)
+			if err != nil {
+				return err
+			}
+			return &String{Value: re.Value.ReplaceAllString(str, repl)}
+		}
+	}
+	strs, err := stringArgs(args, 3)
+	if err != nil {
+		return err
+	}
+	return &String{Value: strings.ReplaceAll(strs[0], strs[1], strs[2])}
+}
+
+func contains(args ...Object) Object {
+	strs, err := stringArgs(args, 2)
+	if err != nil {
+		return err
+	}
+	return NativeBool(strings.Contains(strs[0], strs[1]))
+}
+
+func startsWith(args ...Object) Object {
+	strs, err := stringArgs(args, 2)
+	if err != nil {
+		return err
+	}
+	return NativeBool(strings.HasPrefix(strs[0], strs[1]))
+}
+
+func endsWith(args ...Object) Object {
+	strs, err := stringArgs(args, 2)
+	if err != nil {
+		return err
+	}
+	return NativeBool(strings.HasSuffix(strs[0], strs[1]))
+}
+
+// CharAt returns the character at index i of str. Strings are indexed by
+// character like len and the string builtins count them, not by byte.
+func CharAt(str string, i int) (*String, bool) {
+	if i < 0 {
+		return nil, false
+	}
+	for _, char := range str {
+		if i == 0 {
+			return &String{Value: string(char)}, true
+		}
+		i--
+	}
+	return nil, false
+}
+
+func indexOf(args ...Object) Object {
+	strs, err := stringArgs(args, 2)
+	if err != nil {
+		return err
+	}
+	idx := strings.Index(strs[0], strs[1])
+	if idx < 0 {
+		return &Integer{Value: -1}
+	}
+	return &Integer{Value: utf8.RuneCountInString(strs[0][:idx])}
+}
+
+// trim removes whitespace, or the characters of cutset when it is given, from
+// both ends of str.
+func trim(args ...Object) Object {
+	if len(args) != 1 && len(args) != 2 {
+		return argumentAmountError(2, len(args))
+	}
+	str, err := stringArg(args, 0)
+	if err != nil {
+		return err
+	}
+	if len(args) == 1 {
+		return &String{Value: strings.TrimSpace(str)}
+	}
+	cutset, err := stringArg(args, 1)
+	if err != nil {
+		return err
+	}
+	return &String{Value: strings.Trim(str, cutset)}
+}
+
+func upper(args ...Object) Object {
+	strs, err := stringArgs(args, 1)
+	if err != nil {
+		return err
+	}
+	return &String{Value: strings.ToUpper(strs[0])}
+}
+
+func lower(args ...Object) Object {
+	strs, err := stringArgs(args, 1)
+	if err != nil {
+		return err
+	}
+	return &String{Value: strings.ToLower(strs[0])}
+}
+
+// repeatWithin checks the length of the result for overflow and against p
+// before it repeats.
+func repeatWithin(p *Policy) BuiltInFunction {
+	return func(args ...Object) Object {
+		if len(args) != 2 {
+			return argumentAmountError(2, len(args))
+		}
+		str, err := stringArg(args, 0)
+		if err != nil {
+			return err
+		}
+		count, err := intArg(args, 1)
+		if err != nil {
+			return err
+		}
+		if count < 0 || (count > 0 && len(str) > math.MaxInt/count) {
+			return newError("Cant repeat a STRING %d times", count)
+		}
+		if err := p.CheckStringLen(len(str) * count); err != nil {
+			return ErrorFrom(err)
+		}
+		return &String{Value: strings.Repeat(str, count)}
+	}
+}
+
+// padWithin fills str up to width characters with pad, or spaces when it is
+// not given. Longer strings are returned as they are. The length of the
+// result is checked for overflow and against p before it is built.
+func padWithin(p *Policy, left bool) BuiltInFunction {
+	return func(args ...Object) Object {
+		if len(args) != 2 && len(args) != 3 {
+			return argumentAmountError(3, len(args))
+		}
+		str, err := stringArg(args, 0)
+		if err != nil {
+			return err
+		}
+		width, err := intArg(args, 1)
+		if err != nil {
+			return err
+		}
+		fill := " "
+		if len(args) == 3 {
+			padding, err := stringArg(args, 2)
+			if err != nil {
+				return err
+			}
+			if padding == "" {
+				return newError("Cant pad with an empty STRING")
+			}
+			fill = padding
+		}
+
+		missing := width - utf8.RuneCountInString(str)
+		if missing <= 0 {
+			return &String{Value: str}
+		}
+		if missing > (math.MaxInt-len(str))/utf8.UTFMax {
+			return newError("Cant pad a STRING to %d Characters", width)
+		}
+		runes := []rune(fill)
+		rest := string(runes[:missing%len(runes)])
+		size := len(str) + missing/len(runes)*len(fill) + len(rest)
+		if err := p.CheckStringLen(size); err != nil {
+			return ErrorFrom(err)
+		}
+
+		padding := strings.Repeat(fill, missing/len(runes)) + rest
+		if left {
+			return &String{Value: padding + str}
+		}
+		return &String{Value: str + padding}
+	}
+}
+
+func charsWithin(p *Policy) BuiltInFunction {
+	return func(args ...Object) Object {
+		strs, err := stringArgs(args, 1)
+		if err != nil {
+			return err
+		}
+		if err := p.CheckArrayLen(utf8.RuneCountInString(strs[0])); err != nil {
+			return ErrorFrom(err)
+		}
+		runes := []rune(strs[0])
+		elements := make([]Object, len(runes))
+		for i, r := range runes {
+			elements[i] = &String{Value: string(r)}
+		}
+		return &Array{Elements: elements}
+	}
+}
+
+func ord(args ...Object) Object {
+	strs, err := stringArgs(args, 1)
+	if err != nil {
+		return err
+	}
+	r, size := utf8.DecodeRuneInString(strs[0])
+	if size == 0 || size != len(strs[0]) {
+		return newError("ord expects a single Character got %q", strs[0])
+	}
+	return &Integer{Value: int(r)}
+}
+
+func chr(args ...Object) Object {
+	if len(args) != 1 {
+		return argumentAmountError(1, len(args))
+	}
+	code, err := intArg(args, 0)
+	if err != nil {
+		return err
+	}
+	if !utf8.ValidRune(rune(code)) || int(rune(code)) != code {
+		return newError("%d is Not a valid Character", code)
+	}
+	return &String{Value: string(rune(code))}
+}
+
+// format is printf without the printing.
+func format(args ...Object) Object {
+	str, err := sprintf(args...)
+	if err != nil {
+		return err
+	}
+	return &String{Value: str}
+}
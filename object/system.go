@@ -0,0 +1,50 @@
+package object
+
+// NoEnv hides every environment variable from env.
+func NoEnv(name string) (string, bool) {
+	return "", false
+}
+
+func argsFrom(args func() []string) BuiltInFunction {
+	return func(a ...Object) Object {
+		if len(a) != 0 {
+			return argumentAmountError(0, len(a))
+		}
+		return stringArray(args())
+	}
+}
+
+// envFrom returns the variable as a string, or null when it is not set.
+func envFrom(lookup func(name string) (string, bool)) BuiltInFunction {
+	return func(args ...Object) Object {
+		strs, err := stringArgs(args, 1)
+		if err != nil {
+			return err
+		}
+		value, ok := lookup(strs[0])
+		if !ok {
+			return NullVal
+		}
+		return &String{Value: value}
+	}
+}
+
+func exit(args ...Object) Object {
+	if len(args) > 1 {
+		return argumentAmountError(1, len(args))
+	}
+	code := 0
+	if len(args) == 1 {
+		c, err := intArg(args, 0)
+		if err != nil {
+			return err
+		}
+		code = c
+	}
+	err := &ExitError{Code: code}
+	return &Error{Message: err.Error(), Cause: err}
+}
+
+func noArgs() []string {
+	return nil
+}
@@ -2,6 +2,7 @@ package parser
 
 import (
 	"fmt"
+	"regexp"
 	"strconv"
 
 	"github.com/Arch-4ng3l/Monkey/ast"
@@ -12,6 +13,10 @@ import (
 const (
 	_ int = iota
 	LOWEST
+	TERNARY
+	COALESCE
+	PIPE
+	COMPOSE
 	RANGE
 	EQUALS
 	LESSGREATER
@@ -37,6 +42,13 @@ var precedences = map[token.TokenType]int{
 	token.LPAREN:   CALL,
 	token.LBRACKET: INDEX,
 	token.POWER:    EXPONENTS,
+
+	token.QUESTION:  TERNARY,
+	token.COALESCE:  COALESCE,
+	token.PIPE:      PIPE,
+	token.COMPOSE:   COMPOSE,
+	token.OPT_INDEX: INDEX,
+	token.OPT_DOT:   INDEX,
 }
 
 type (
@@ -72,10 +84,14 @@ func NewParser(l *lexer.Lexer) *Parser {
 	p.registerPrefix(token.LPAREN, p.parseGroupedExpression)
 	p.registerPrefix(token.IF, p.parseIfExpression)
 	p.registerPrefix(token.FUNCTION, p.parseFnLiteral)
+	p.registerPrefix(token.MACRO, p.parseMacroLiteral)
 	p.registerPrefix(token.STR, p.parseStrLiteral)
+	p.registerPrefix(token.REGEX, p.parseRegexLiteral)
 	p.registerPrefix(token.LBRACKET, p.parseArrLiteral)
 	p.registerPrefix(token.FOR, p.parseForLoop)
 	p.registerPrefix(token.WHILE, p.parseWhileLoop)
+	p.registerPrefix(token.LBRACE, p.parseHashLiteral)
+	p.registerPrefix(token.MATCH, p.parseMatchExpression)
 
 	p.infixParseFns = make(map[token.TokenType]infixParseFn)
 
@@ -99,6 +115,12 @@ func NewParser(l *lexer.Lexer) *Parser {
 	p.registeInfix(token.STAR_ASSIGN, p.parseInfixExpression)
 
 	p.registeInfix(token.LBRACKET, p.parseIndexExpression)
+	p.registeInfix(token.OPT_INDEX, p.parseIndexExpression)
+	p.registeInfix(token.OPT_DOT, p.parseOptionalField)
+	p.registeInfix(token.QUESTION, p.parseTernaryExpression)
+	p.registeInfix(token.COALESCE, p.parseInfixExpression)
+	p.registeInfix(token.PIPE, p.parsePipeExpression)
+	p.registeInfix(token.COMPOSE, p.parseInfixExpression)
 
 	p.nextToken()
 	p.nextToken()
@@ -137,7 +159,21 @@ func (p *Parser) parseForLoop() ast.Expression {
 	p.nextToken()
 	p.nextToken()
 
-	fl.LoopVar = p.parseLetStatement()
+	if !p.curTokenIs(token.LET) && !p.curTokenIs(token.CONST) {
+		return p.parseForInLoop(fl.Token)
+	}
+
+	stmt := &ast.LetStatement{Token: p.curToken}
+	if !p.parseBinding(stmt) {
+		return nil
+	}
+	if p.peekTokenIs(token.IN) {
+		if stmt.Pattern == nil {
+			return p.parseForInLoop(fl.Token, stmt.Name)
+		}
+		return p.parseForInLoop(fl.Token, stmt.Pattern)
+	}
+	fl.LoopVar = p.parseLetValue(stmt)
 
 	p.nextToken()
 
@@ -159,6 +195,110 @@ func (p *Parser) parseForLoop() ast.Expression {
 
 }
 
+func (p *Parser) parseForInLoop(tok token.Token, pattern ...ast.Expression) ast.Expression {
+	fi := &ast.ForInLoop{Token: tok}
+
+	if len(pattern) > 0 {
+		fi.Pattern = pattern[0]
+	} else {
+		fi.Pattern = p.parsePattern()
+	}
+	if fi.Pattern == nil || !p.expectedPeek(token.IN) {
+		return nil
+	}
+
+	p.nextToken()
+	fi.Iterable = p.parseExpression(LOWEST)
+
+	if !p.expectedPeek(token.RPAREN) || !p.expectedPeek(token.LBRACE) {
+		return nil
+	}
+
+	fi.Body = p.parseBlockStatement()
+
+	return fi
+}
+
+func (p *Parser) parsePattern() ast.Expression {
+	switch p.curToken.Type {
+	case token.IDENT:
+		return &ast.Ident{Token: p.curToken, Value: p.curToken.Literal}
+	case token.LBRACKET:
+		return p.parseArrayPattern()
+	case token.LBRACE:
+		return p.parseHashPattern()
+	}
+
+	msg := fmt.Sprintf("expected pattern, got %s", p.curToken.Type)
+	p.errors = append(p.errors, msg)
+	return nil
+}
+
+func (p *Parser) parseArrayPattern() ast.Expression {
+	pattern := &ast.ArrayPattern{Token: p.curToken}
+
+	for !p.peekTokenIs(token.RBRACKET) {
+		p.nextToken()
+
+		if p.curTokenIs(token.ELLIPSIS) {
+			if !p.expectedPeek(token.IDENT) {
+				return nil
+			}
+			pattern.Rest = &ast.Ident{Token: p.curToken, Value: p.curToken.Literal}
+			break
+		}
+
+		el := p.parsePattern()
+		if el == nil {
+			return nil
+		}
+		pattern.Elements = append(pattern.Elements, el)
+
+		if !p.peekTokenIs(token.RBRACKET) && !p.expectedPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	if !p.expectedPeek(token.RBRACKET) {
+		return nil
+	}
+
+	return pattern
+}
+
+func (p *Parser) parseHashPattern() ast.Expression {
+	pattern := &ast.HashPattern{Token: p.curToken}
+
+	for !p.peekTokenIs(token.RBRACE) {
+		if !p.expectedPeek(token.IDENT) {
+			return nil
+		}
+		key := p.curToken
+
+		var value ast.Expression = &ast.Ident{Token: key, Value: key.Literal}
+		if p.peekTokenIs(token.COLON) {
+			p.nextToken()
+			p.nextToken()
+			if value = p.parsePattern(); value == nil {
+				return nil
+			}
+		}
+
+		pattern.Keys = append(pattern.Keys, key.Literal)
+		pattern.Values = append(pattern.Values, value)
+
+		if !p.peekTokenIs(token.RBRACE) && !p.expectedPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	if !p.expectedPeek(token.RBRACE) {
+		return nil
+	}
+
+	return pattern
+}
+
 func (p *Parser) parseFloatLiteral() ast.Expression {
 
 	fl := &ast.FloatLiteral{Token: p.curToken}
@@ -175,10 +315,66 @@ func (p *Parser) parseFloatLiteral() ast.Expression {
 
 	return fl
 }
+func (p *Parser) parseTernaryExpression(condition ast.Expression) ast.Expression {
+	exp := &ast.TernaryExpression{
+		Token:     p.curToken,
+		Condition: condition,
+	}
+
+	p.nextToken()
+	exp.Consequence = p.parseExpression(LOWEST)
+
+	if !p.expectedPeek(token.COLON) {
+		return nil
+	}
+
+	p.nextToken()
+	exp.Alternative = p.parseExpression(LOWEST)
+
+	return exp
+}
+
+// parsePipeExpression rewrites `x |> f(y)` into the call `f(x, y)`.
+func (p *Parser) parsePipeExpression(left ast.Expression) ast.Expression {
+	call := &ast.CallExpression{Token: p.curToken}
+
+	p.nextToken()
+	right := p.parseExpression(PIPE)
+	if right == nil {
+		return nil
+	}
+
+	if inner, ok := right.(*ast.CallExpression); ok {
+		call.Function = inner.Function
+		call.Args = append([]ast.Expression{left}, inner.Args...)
+	} else {
+		call.Function = right
+		call.Args = []ast.Expression{left}
+	}
+
+	return call
+}
+
+func (p *Parser) parseOptionalField(left ast.Expression) ast.Expression {
+	exp := &ast.IndexExpression{
+		Token:    p.curToken,
+		Left:     left,
+		Optional: true,
+	}
+
+	if !p.expectedPeek(token.IDENT) {
+		return nil
+	}
+	exp.Index = &ast.StrLiteral{Token: p.curToken, Value: p.curToken.Literal}
+
+	return exp
+}
+
 func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
 	exp := &ast.IndexExpression{
-		Token: p.curToken,
-		Left:  left,
+		Token:    p.curToken,
+		Left:     left,
+		Optional: p.curTokenIs(token.OPT_INDEX),
 	}
 
 	p.nextToken()
@@ -189,9 +385,51 @@ func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
 		return nil
 	}
 
+	if p.peekTokenIs(token.ASSIGN) {
+		p.nextToken()
+		p.nextToken()
+		return &ast.IndexAssignExpression{
+			Token: exp.Token,
+			Left:  exp.Left,
+			Index: exp.Index,
+			Value: p.parseExpression(LOWEST),
+		}
+	}
+
 	return exp
 }
 
+func (p *Parser) parseHashLiteral() ast.Expression {
+	hash := &ast.HashLiteral{
+		Token: p.curToken,
+	}
+
+	for !p.peekTokenIs(token.RBRACE) {
+		p.nextToken()
+		key := p.parseExpression(LOWEST)
+
+		if !p.expectedPeek(token.COLON) {
+			return nil
+		}
+
+		p.nextToken()
+		value := p.parseExpression(LOWEST)
+
+		hash.Keys = append(hash.Keys, key)
+		hash.Values = append(hash.Values, value)
+
+		if !p.peekTokenIs(token.RBRACE) && !p.expectedPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	if !p.expectedPeek(token.RBRACE) {
+		return nil
+	}
+
+	return hash
+}
+
 func (p *Parser) parseArrLiteral() ast.Expression {
 	arr := &ast.ArrayLiteral{
 		Token: p.curToken,
@@ -209,6 +447,18 @@ func (p *Parser) parseStrLiteral() ast.Expression {
 	}
 }
 
+func (p *Parser) parseRegexLiteral() ast.Expression {
+	if _, err := regexp.Compile(p.curToken.Literal); err != nil {
+		msg := fmt.Sprintf("Token Literal isnt a Valid Regex got /%s/: %s", p.curToken.Literal, err)
+		p.errors = append(p.errors, msg)
+		return nil
+	}
+	return &ast.RegexLiteral{
+		Token: p.curToken,
+		Value: p.curToken.Literal,
+	}
+}
+
 func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
 	exp := &ast.CallExpression{
 		Token:    p.curToken,
@@ -249,47 +499,118 @@ func (p *Parser) parseFnLiteral() ast.Expression {
 	if !p.expectedPeek(token.LPAREN) {
 		return nil
 	}
-	lit.Params = p.parseFnParams()
+	lit.Params, lit.ParamTypes, lit.ParamPatterns = p.parseFnParams()
+
+	if p.peekTokenIs(token.ARROW) {
+		p.nextToken()
+		p.nextToken()
+		lit.ReturnType = p.parseTypeAnnotation()
+	}
 
 	if !p.expectedPeek(token.LBRACE) {
 		return nil
 	}
 
 	lit.Body = p.parseBlockStatement()
+	ast.MarkTailCalls(lit)
 
 	return lit
 }
 
-func (p *Parser) parseFnParams() []*ast.Ident {
-	idents := []*ast.Ident{}
-
-	if p.peekTokenIs(token.RPAREN) {
-		p.nextToken()
-		return idents
+func (p *Parser) parseMacroLiteral() ast.Expression {
+	lit := &ast.MacroLiteral{
+		Token: p.curToken,
 	}
 
-	p.nextToken()
+	if !p.expectedPeek(token.LPAREN) {
+		return nil
+	}
+	lit.Params, _, _ = p.parseFnParams()
 
-	ident := &ast.Ident{
-		Token: p.curToken,
-		Value: p.curToken.Literal,
+	if !p.expectedPeek(token.LBRACE) {
+		return nil
 	}
-	idents = append(idents, ident)
 
-	for p.peekTokenIs(token.COMMA) {
+	lit.Body = p.parseBlockStatement()
+
+	return lit
+}
+
+func (p *Parser) parseFnParams() ([]*ast.Ident, []*ast.TypeAnnotation, []ast.Expression) {
+	idents := []*ast.Ident{}
+	types := []*ast.TypeAnnotation{}
+	patterns := []ast.Expression{}
+	destructures := false
+
+	if p.peekTokenIs(token.RPAREN) {
 		p.nextToken()
+		return idents, types, nil
+	}
+
+	for {
 		p.nextToken()
+
 		ident := &ast.Ident{
 			Token: p.curToken,
 			Value: p.curToken.Literal,
 		}
+		var pattern ast.Expression
+		if p.curTokenIs(token.LBRACKET) || p.curTokenIs(token.LBRACE) {
+			if pattern = p.parsePattern(); pattern == nil {
+				return nil, nil, nil
+			}
+			ident.Value = pattern.String()
+			destructures = true
+		}
 		idents = append(idents, ident)
+		patterns = append(patterns, pattern)
+		types = append(types, p.parseOptionalType())
+
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
+		p.nextToken()
 	}
 
 	if !p.expectedPeek(token.RPAREN) {
+		return nil, nil, nil
+	}
+	if !destructures {
+		patterns = nil
+	}
+	return idents, types, patterns
+}
+
+func (p *Parser) parseOptionalType() *ast.TypeAnnotation {
+	if !p.peekTokenIs(token.COLON) {
 		return nil
 	}
-	return idents
+	p.nextToken()
+	p.nextToken()
+
+	return p.parseTypeAnnotation()
+}
+
+func (p *Parser) parseTypeAnnotation() *ast.TypeAnnotation {
+	ta := &ast.TypeAnnotation{Token: p.curToken}
+
+	switch p.curToken.Type {
+	case token.LBRACKET:
+		p.nextToken()
+		ta.Elem = p.parseTypeAnnotation()
+		if ta.Elem == nil || !p.expectedPeek(token.RBRACKET) {
+			return nil
+		}
+		ta.Name = ta.String()
+	case token.IDENT, token.FUNCTION:
+		ta.Name = p.curToken.Literal
+	default:
+		msg := fmt.Sprintf("expected type, got %s", p.curToken.Type)
+		p.errors = append(p.errors, msg)
+		return nil
+	}
+
+	return ta
 }
 func (p *Parser) parseIfExpression() ast.Expression {
 
@@ -502,10 +823,29 @@ func (p *Parser) expectedPeek(t token.TokenType) bool {
 func (p *Parser) parseLetStatement() *ast.LetStatement {
 	stmt := &ast.LetStatement{Token: p.curToken}
 
-	if !p.expectedPeek(token.IDENT) {
+	if !p.parseBinding(stmt) {
 		return nil
 	}
+
+	return p.parseLetValue(stmt)
+}
+
+func (p *Parser) parseBinding(stmt *ast.LetStatement) bool {
+	if p.peekTokenIs(token.LBRACKET) || p.peekTokenIs(token.LBRACE) {
+		p.nextToken()
+		stmt.Pattern = p.parsePattern()
+		return stmt.Pattern != nil
+	}
+
+	if !p.expectedPeek(token.IDENT) {
+		return false
+	}
 	stmt.Name = &ast.Ident{Token: p.curToken, Value: p.curToken.Literal}
+	return true
+}
+
+func (p *Parser) parseLetValue(stmt *ast.LetStatement) *ast.LetStatement {
+	stmt.Type = p.parseOptionalType()
 
 	if !p.expectedPeek(token.ASSIGN) {
 		return nil
@@ -513,6 +853,10 @@ func (p *Parser) parseLetStatement() *ast.LetStatement {
 	p.nextToken()
 	stmt.Value = p.parseExpression(LOWEST)
 
+	if fn, ok := stmt.Value.(*ast.FunctionLiteral); ok && stmt.Name != nil {
+		fn.Name = stmt.Name.Value
+	}
+
 	if p.peekTokenIs(token.SEMICOLON) {
 		p.nextToken()
 	}
@@ -522,10 +866,14 @@ func (p *Parser) parseLetStatement() *ast.LetStatement {
 
 func (p *Parser) parseStatement() ast.Statement {
 	switch p.curToken.Type {
-	case token.LET:
+	case token.LET, token.CONST:
 		return p.parseLetStatement()
 	case token.RETURN:
 		return p.parseReturnStatement()
+	case token.DEFER:
+		return p.parseDeferStatement()
+	case token.ENUM:
+		return p.parseEnumStatement()
 	default:
 		return p.parseExpressionStatement()
 	}
@@ -585,6 +933,157 @@ func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 
 }
 
+func (p *Parser) parseEnumStatement() *ast.EnumStatement {
+	stmt := &ast.EnumStatement{Token: p.curToken}
+
+	if !p.expectedPeek(token.IDENT) {
+		return nil
+	}
+	stmt.Name = &ast.Ident{Token: p.curToken, Value: p.curToken.Literal}
+
+	if !p.expectedPeek(token.LBRACE) {
+		return nil
+	}
+
+	for !p.peekTokenIs(token.RBRACE) {
+		if !p.expectedPeek(token.IDENT) {
+			return nil
+		}
+		variant := &ast.EnumVariant{
+			Name: &ast.Ident{Token: p.curToken, Value: p.curToken.Literal},
+		}
+		if p.peekTokenIs(token.LPAREN) {
+			p.nextToken()
+			variant.Params = p.parseIdentList(token.RPAREN)
+			if variant.Params == nil {
+				return nil
+			}
+		}
+		stmt.Variants = append(stmt.Variants, variant)
+
+		if !p.peekTokenIs(token.RBRACE) && !p.expectedPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	if !p.expectedPeek(token.RBRACE) {
+		return nil
+	}
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseMatchExpression() ast.Expression {
+	exp := &ast.MatchExpression{Token: p.curToken}
+
+	if !p.expectedPeek(token.LPAREN) {
+		return nil
+	}
+	call := &ast.CallExpression{
+		Token:    p.curToken,
+		Function: &ast.Ident{Token: token.Token{Type: token.IDENT, Literal: "match"}, Value: "match"},
+	}
+	p.nextToken()
+	exp.Subject = p.parseExpression(LOWEST)
+
+	// match(a, b) and match(a) without arms call the match builtin.
+	call.Args = []ast.Expression{exp.Subject}
+	for p.peekTokenIs(token.COMMA) {
+		p.nextToken()
+		p.nextToken()
+		call.Args = append(call.Args, p.parseExpression(LOWEST))
+	}
+	if !p.expectedPeek(token.RPAREN) {
+		return nil
+	}
+	if len(call.Args) > 1 || !p.peekTokenIs(token.LBRACE) {
+		return call
+	}
+	p.nextToken()
+
+	for !p.peekTokenIs(token.RBRACE) {
+		if !p.expectedPeek(token.IDENT) {
+			return nil
+		}
+		arm := &ast.MatchArm{
+			Variant: &ast.Ident{Token: p.curToken, Value: p.curToken.Literal},
+		}
+		if p.peekTokenIs(token.LPAREN) {
+			p.nextToken()
+			arm.Bindings = p.parseIdentList(token.RPAREN)
+			if arm.Bindings == nil {
+				return nil
+			}
+		}
+
+		if !p.expectedPeek(token.FAT_ARROW) {
+			return nil
+		}
+		p.nextToken()
+
+		if p.curTokenIs(token.LBRACE) {
+			arm.Body = p.parseBlockStatement()
+		} else {
+			arm.Body = &ast.BlockStatement{
+				Token:      p.curToken,
+				Statements: []ast.Statement{p.parseExpressionStatement()},
+			}
+		}
+		exp.Arms = append(exp.Arms, arm)
+
+		if p.peekTokenIs(token.COMMA) {
+			p.nextToken()
+		}
+	}
+
+	if !p.expectedPeek(token.RBRACE) {
+		return nil
+	}
+
+	return exp
+}
+
+func (p *Parser) parseIdentList(end token.TokenType) []*ast.Ident {
+	idents := []*ast.Ident{}
+
+	for !p.peekTokenIs(end) {
+		if !p.expectedPeek(token.IDENT) {
+			return nil
+		}
+		idents = append(idents, &ast.Ident{Token: p.curToken, Value: p.curToken.Literal})
+
+		if !p.peekTokenIs(end) && !p.expectedPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	if !p.expectedPeek(end) {
+		return nil
+	}
+
+	return idents
+}
+
+func (p *Parser) parseDeferStatement() *ast.DeferStatement {
+	stmt := &ast.DeferStatement{Token: p.curToken}
+
+	p.nextToken()
+
+	stmt.Call = p.parseExpression(LOWEST)
+	if stmt.Call == nil {
+		return nil
+	}
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
 func (p *Parser) ParseProgram() *ast.Program {
 	program := &ast.Program{}
 	program.Statements = []ast.Statement{}
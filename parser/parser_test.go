@@ -236,3 +236,180 @@ func testLetStatement(t *testing.T, s ast.Statement, name string) bool {
 	return true
 
 }
+
+func TestHashLiteralAndConst(t *testing.T) {
+	input := `const h = {"one": 1, two: 2 + 3};`
+
+	l := lexer.NewLexer(input)
+	p := NewParser(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt, ok := program.Statements[0].(*ast.LetStatement)
+	if !ok {
+		t.Fatalf("statement is not LetStatement got %T", program.Statements[0])
+	}
+	if !stmt.IsConst() {
+		t.Errorf("statement is not const")
+	}
+
+	hash, ok := stmt.Value.(*ast.HashLiteral)
+	if !ok {
+		t.Fatalf("value is not HashLiteral got %T", stmt.Value)
+	}
+	if hash.String() != "{one: 1, two: (2 + 3)}" {
+		t.Errorf("hash.String() wrong got %q", hash.String())
+	}
+}
+
+func TestTypeAnnotations(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"var x: int = 1;", "var x: int = 1;"},
+		{"var x: [[float]] = y;", "var x: [[float]] = y;"},
+		{"var f = func(a: float, b: [int], c) -> string { a };", "var f = func(a: float, b: [int], c) -> string a;"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.NewLexer(tt.input)
+		p := NewParser(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.Statements[0].String() != tt.expected {
+			t.Errorf("wrong String() want %q got %q", tt.expected, program.Statements[0].String())
+		}
+	}
+}
+
+func TestConditionalOperatorPrecedence(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"a ? b : c", "(a ? b : c)"},
+		{"a == 1 ? b + 1 : c * 2", "((a == 1) ? (b + 1) : (c * 2))"},
+		{"a ? b : c ? d : e", "(a ? b : (c ? d : e))"},
+		{"a ?? b + 1", "(a ?? (b + 1))"},
+		{"a ?? b ?? c", "((a ?? b) ?? c)"},
+		{"a == b ?? c", "((a == b) ?? c)"},
+		{"a?[1] ?? 2", "((a?[1]) ?? 2)"},
+		{"a?.b?.c", "((a?[b])?[c])"},
+		{"x ? a?.b : 0", "(x ? (a?[b]) : 0)"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.NewLexer(tt.input)
+		p := NewParser(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.Statements[0].String() != tt.expected {
+			t.Errorf("wrong String() want %q got %q", tt.expected, program.Statements[0].String())
+		}
+	}
+}
+
+func TestDestructuringPatterns(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"var [a, b] = x;", "var [a, b] = x;"},
+		{"var [a, [b, c], ...rest] = x;", "var [a, [b, c], ...rest] = x;"},
+		{"const {a, b: [c]} = x;", "const {a, b: [c]} = x;"},
+		{"func([a, b], {c}) { a }", "func([a, b], {c}) a"},
+		{"for (var [k, v] in pairs) { k }", "for ([k, v] in pairs) {\nk\n}"},
+		{"for (x in xs) { x }", "for (x in xs) {\nx\n}"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.NewLexer(tt.input)
+		p := NewParser(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.Statements[0].String() != tt.expected {
+			t.Errorf("wrong String() want %q got %q", tt.expected, program.Statements[0].String())
+		}
+	}
+}
+
+func TestPipeAndCompose(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"x |> f", "f(x)"},
+		{"x |> f(y, z)", "f(x, y, z)"},
+		{"x + 1 |> f |> g(2)", "g(f((x + 1)), 2)"},
+		{"f >> g >> h", "((f >> g) >> h)"},
+		{"x |> f >> g", "(f >> g)(x)"},
+		{"x |> func(a) { a }", "func(a) a(x)"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.NewLexer(tt.input)
+		p := NewParser(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.Statements[0].String() != tt.expected {
+			t.Errorf("wrong String() want %q got %q", tt.expected, program.Statements[0].String())
+		}
+	}
+}
+
+func TestEnumAndMatch(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"enum Color { Red, Green, Blue(r, g) }", "enum Color { Red, Green, Blue(r, g) }"},
+		{"match (c) { Red => 1, Blue(r, g) => { r + g }, _ => 0 }", "match (c) { Red => 1, Blue(r, g) => (r + g), _ => 0 }"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.NewLexer(tt.input)
+		p := NewParser(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.Statements[0].String() != tt.expected {
+			t.Errorf("wrong String() want %q got %q", tt.expected, program.Statements[0].String())
+		}
+	}
+}
+
+func TestRegexLiteral(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`/\d+/`, `/\d+/`},
+		{`/ab/i`, `/(?i)ab/`},
+		{`a / b / c`, "((a / b) / c)"},
+		{`split(s, /,\s*/)`, `split(s, /,\s*/)`},
+		{`match(/a/, s)["x"]`, `(match(/a/, s)[x])`},
+		{`match(x) |> len`, "len(match(x))"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.NewLexer(tt.input)
+		p := NewParser(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.Statements[0].String() != tt.expected {
+			t.Errorf("wrong String() want %q got %q", tt.expected, program.Statements[0].String())
+		}
+	}
+
+	p := NewParser(lexer.NewLexer("/(/"))
+	p.ParseProgram()
+	if len(p.Errors()) == 0 {
+		t.Errorf("expected an error for an invalid regex")
+	}
+}
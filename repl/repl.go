@@ -4,12 +4,14 @@ import (
 	"bufio"
 	"fmt"
 	"io"
+	"strings"
 
 	"github.com/Arch-4ng3l/Monkey/compiler"
 	"github.com/Arch-4ng3l/Monkey/eval"
 	"github.com/Arch-4ng3l/Monkey/lexer"
 	"github.com/Arch-4ng3l/Monkey/object"
 	"github.com/Arch-4ng3l/Monkey/parser"
+	"github.com/Arch-4ng3l/Monkey/typecheck"
 	"github.com/Arch-4ng3l/Monkey/vm"
 	"github.com/TwiN/go-color"
 )
@@ -38,17 +40,22 @@ const MONKEY_FACE = `
 `
 
 func Sart(in io.Reader, out io.Writer) {
-	scanner := bufio.NewScanner(in)
+	builtins := object.NewDefaultRegistry()
+	builtins.Input = in
+	builtins.Output = out
+	evaluator := eval.New(builtins)
+
 	fmt.Fprintf(out, "%s%s%s%s", color.Green, color.Bold, MONKEY_FACE, color.Reset)
 	env := object.NewEnv()
+	checker := typecheck.New(builtins)
+	macroEnv := object.NewEnv()
 	for {
 		fmt.Fprintf(out, PROMPT)
-		scanned := scanner.Scan()
-		if !scanned {
+		line, ok := readLine(builtins.Reader())
+		if !ok {
 			return
 		}
 
-		line := scanner.Text()
 		l := lexer.NewLexer(line)
 		p := parser.NewParser(l)
 		program := p.ParseProgram()
@@ -57,31 +64,49 @@ func Sart(in io.Reader, out io.Writer) {
 				fmt.Fprintf(out, "ERROR: %s\n", err)
 			}
 		}
-		eval.Eval(program, env)
+		eval.DefineMacros(program, macroEnv)
+		program, err := eval.ExpandMacros(program, macroEnv)
+		if err != nil {
+			fmt.Fprintf(out, "MACRO ERROR: %s\n", err)
+			continue
+		}
+		if errs := checker.Check(program); len(errs) != 0 {
+			for _, err := range errs {
+				fmt.Fprintf(out, "TYPE ERROR: %s\n", err)
+			}
+			continue
+		}
+		if err, ok := evaluator.Eval(program, env).(*object.Error); ok {
+			if _, exit := err.Cause.(*object.ExitError); exit {
+				return
+			}
+			fmt.Fprintln(out, err.Message)
+		}
 	}
 }
 
 func StartComp(in io.Reader, out io.Writer) {
-	scanner := bufio.NewScanner(in)
 	fmt.Fprintf(out, "%s%s%s%s", color.Green, color.Bold, MONKEY_FACE, color.Reset)
 	constansts := []object.Object{}
 
 	globals := make([]object.Object, vm.GlobalSize)
 
 	symbolTable := compiler.NewSymbolTable()
+	macroEnv := object.NewEnv()
 
-	for i, v := range object.Builtins {
-		symbolTable.DefineBuiltin(i, v.Name)
-	}
+	builtins := object.NewDefaultRegistry()
+	builtins.Input = in
+	builtins.Output = out
+	compiler.DefineBuiltins(symbolTable, builtins)
+	checker := typecheck.New(builtins)
 
 	for {
 		fmt.Fprintf(out, PROMPT)
-		scanned := scanner.Scan()
-		if !scanned {
+		line, ok := readLine(builtins.Reader())
+		if !ok {
 			return
 		}
 
-		line := scanner.Text()
 		l := lexer.NewLexer(line)
 		p := parser.NewParser(l)
 		program := p.ParseProgram()
@@ -91,8 +116,20 @@ func StartComp(in io.Reader, out io.Writer) {
 				fmt.Fprintf(out, "ERROR: %s\n", err)
 			}
 		}
-		comp := compiler.NewWithState(symbolTable, constansts)
-		err := comp.Compile(program)
+		eval.DefineMacros(program, macroEnv)
+		program, err := eval.ExpandMacros(program, macroEnv)
+		if err != nil {
+			fmt.Fprintf(out, "MACRO ERROR: %s\n", err)
+			continue
+		}
+		if errs := checker.Check(program); len(errs) != 0 {
+			for _, err := range errs {
+				fmt.Fprintf(out, "TYPE ERROR: %s\n", err)
+			}
+			continue
+		}
+		comp := compiler.NewWithState(builtins, symbolTable, constansts)
+		err = comp.Compile(program)
 		if err != nil {
 			fmt.Fprintf(out, "%s", err)
 			continue
@@ -102,9 +139,22 @@ func StartComp(in io.Reader, out io.Writer) {
 		constansts = code.Constants
 		vmachine := vm.NewWithGLobalStore(code, globals)
 		err = vmachine.Run()
+		if _, exit := err.(*object.ExitError); exit {
+			return
+		}
 		if err != nil {
 			fmt.Fprintf(out, "%s", err)
 			continue
 		}
 	}
 }
+
+// readLine reads the next line through the Reader of the Registry, so the
+// input builtins of the program share the input with the REPL.
+func readLine(in *bufio.Reader) (string, bool) {
+	line, err := in.ReadString('\n')
+	if err != nil && line == "" {
+		return "", false
+	}
+	return strings.TrimRight(line, "\r\n"), true
+}
@@ -15,6 +15,7 @@ const (
 	INT   = "INT"
 	FLOAT = "FLOAT"
 	STR   = "STR"
+	REGEX = "REGEX"
 
 	ASSIGN       = "="
 	PLUS         = "+"
@@ -37,6 +38,19 @@ const (
 
 	COMMA     = ","
 	SEMICOLON = ";"
+	COLON     = ":"
+	ARROW     = "->"
+	FAT_ARROW = "=>"
+
+	ELLIPSIS = "..."
+
+	PIPE    = "|>"
+	COMPOSE = ">>"
+
+	QUESTION  = "?"
+	COALESCE  = "??"
+	OPT_INDEX = "?["
+	OPT_DOT   = "?."
 
 	LPAREN   = "("
 	RPAREN   = ")"
@@ -46,26 +60,38 @@ const (
 	RBRACKET = "]"
 
 	FUNCTION = "FUNCTION"
+	MACRO    = "MACRO"
 	LET      = "LET"
+	CONST    = "CONST"
 	RETURN   = "RETURN"
+	DEFER    = "DEFER"
 	IF       = "IF"
 	ELSE     = "ELSE"
 	TRUE     = "TRUE"
 	FALSE    = "FALSE"
 	FOR      = "FOR"
 	WHILE    = "WHILE"
+	IN       = "IN"
+	ENUM     = "ENUM"
+	MATCH    = "MATCH"
 )
 
 var keywords = map[string]TokenType{
 	"func":   FUNCTION,
+	"macro":  MACRO,
 	"var":    LET,
+	"const":  CONST,
 	"return": RETURN,
+	"defer":  DEFER,
 	"if":     IF,
 	"else":   ELSE,
 	"true":   TRUE,
 	"false":  FALSE,
 	"for":    FOR,
 	"while":  WHILE,
+	"in":     IN,
+	"enum":   ENUM,
+	"match":  MATCH,
 }
 
 func LookUpIdent(input string) TokenType {
@@ -0,0 +1,538 @@
+package typecheck
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Arch-4ng3l/Monkey/ast"
+	"github.com/Arch-4ng3l/Monkey/object"
+)
+
+type Type string
+
+const (
+	INT    Type = "int"
+	FLOAT  Type = "float"
+	NUMBER Type = "number"
+	STRING Type = "string"
+	BOOL   Type = "bool"
+	NULL   Type = "null"
+	HASH   Type = "hash"
+	FUNC   Type = "func"
+	ANY    Type = "any"
+)
+
+func ArrayOf(elem Type) Type {
+	return "[" + elem + "]"
+}
+
+func (t Type) IsArray() bool {
+	return strings.HasPrefix(string(t), "[")
+}
+
+func (t Type) Elem() Type {
+	if !t.IsArray() {
+		return ANY
+	}
+	return t[1 : len(t)-1]
+}
+
+type funcSig struct {
+	params   []Type
+	variadic bool
+	optional int
+	ret      Type
+}
+
+type scope struct {
+	vars  map[string]Type
+	funcs map[string]*funcSig
+	outer *scope
+}
+
+func newScope(outer *scope) *scope {
+	return &scope{
+		vars:  make(map[string]Type),
+		funcs: make(map[string]*funcSig),
+		outer: outer,
+	}
+}
+
+func (s *scope) lookup(name string) (Type, *funcSig, bool) {
+	if t, ok := s.vars[name]; ok {
+		return t, s.funcs[name], true
+	}
+	if s.outer != nil {
+		return s.outer.lookup(name)
+	}
+	return ANY, nil, false
+}
+
+// declareFunc remembers the signature of value when it is a function literal
+// bound to name.
+func (s *scope) declareFunc(name string, value ast.Expression) {
+	if fn, ok := value.(*ast.FunctionLiteral); ok {
+		s.funcs[name] = signatureOf(fn)
+	} else {
+		delete(s.funcs, name)
+	}
+}
+
+// assignFunc is declareFunc for the scope that declared name.
+func (s *scope) assignFunc(name string, value ast.Expression) {
+	for ; s != nil; s = s.outer {
+		if _, ok := s.vars[name]; ok {
+			s.declareFunc(name, value)
+			return
+		}
+	}
+}
+
+type Checker struct {
+	scope    *scope
+	builtins *object.Registry
+	returns  []Type
+	errors   []string
+}
+
+// New returns a Checker that knows the signatures of the builtins of r, or of
+// the default builtins when r is nil.
+func New(r *object.Registry) *Checker {
+	if r == nil {
+		r = object.NewDefaultRegistry()
+	}
+	return &Checker{
+		scope:    newScope(nil),
+		builtins: r,
+		errors:   []string{},
+	}
+}
+
+// builtin returns the signature of the builtin called name, nil when it does
+// not declare one.
+func (c *Checker) builtin(name string) (*funcSig, bool) {
+	b, ok := c.builtins.Lookup(name)
+	if !ok || b.Sig == nil {
+		return nil, ok
+	}
+	params := []Type{}
+	for _, p := range b.Sig.Params {
+		params = append(params, fromObjectType(p))
+	}
+	return &funcSig{
+		params:   params,
+		variadic: b.Sig.Variadic,
+		optional: b.Sig.Optional,
+		ret:      fromObjectType(b.Sig.Return),
+	}, true
+}
+
+func (c *Checker) Errors() []string {
+	return c.errors
+}
+
+func (c *Checker) Check(program *ast.Program) []string {
+	c.errors = []string{}
+	for _, stmt := range program.Statements {
+		c.check(stmt)
+	}
+	return c.errors
+}
+
+func (c *Checker) errorf(format string, a ...interface{}) {
+	c.errors = append(c.errors, fmt.Sprintf(format, a...))
+}
+
+func (c *Checker) check(node ast.Node) Type {
+	switch node := node.(type) {
+	case *ast.ExpresssionStatement:
+		if node.Expression == nil {
+			return ANY
+		}
+		return c.check(node.Expression)
+
+	case *ast.BlockStatement:
+		res := Type(NULL)
+		for _, stmt := range node.Statements {
+			res = c.check(stmt)
+		}
+		return res
+
+	case *ast.LetStatement:
+		if node.Value == nil {
+			return ANY
+		}
+		got := c.check(node.Value)
+		if node.Pattern != nil {
+			c.declarePattern(node.Pattern)
+			return got
+		}
+		// Only annotated bindings keep their type, others can be assigned
+		// anything later on.
+		t := Type(ANY)
+		if node.Type != nil {
+			t = FromAnnotation(node.Type)
+			if !assignable(t, got) {
+				c.errorf("Cant use %s as %s in declaration of %s", got, t, node.Name.Value)
+			}
+		}
+		c.scope.vars[node.Name.Value] = t
+		c.scope.declareFunc(node.Name.Value, node.Value)
+		return got
+
+	case *ast.ReasignExpression:
+		got := c.check(node.Value)
+		want, _, ok := c.scope.lookup(node.Var.Value)
+		if ok && node.Operator == "=" && !assignable(want, got) {
+			c.errorf("Cant assign %s to %s of Type %s", got, node.Var.Value, want)
+		}
+		if ok && node.Operator == "=" {
+			c.scope.assignFunc(node.Var.Value, node.Value)
+		}
+		return got
+
+	case *ast.ReturnStatement:
+		if node.Value == nil {
+			return NULL
+		}
+		got := c.check(node.Value)
+		if len(c.returns) > 0 {
+			want := c.returns[len(c.returns)-1]
+			if !assignable(want, got) {
+				c.errorf("Cant return %s from function returning %s", got, want)
+			}
+		}
+		return got
+
+	case *ast.EnumStatement:
+		for _, v := range node.Variants {
+			c.scope.vars[v.Name.Value] = ANY
+			delete(c.scope.funcs, v.Name.Value)
+		}
+		c.scope.vars[node.Name.Value] = ANY
+		return NULL
+
+	case *ast.MatchExpression:
+		c.check(node.Subject)
+		res := Type("")
+		for _, arm := range node.Arms {
+			for _, b := range arm.Bindings {
+				c.scope.vars[b.Value] = ANY
+			}
+			t := c.check(arm.Body)
+			if res == "" {
+				res = t
+			} else if res != t {
+				res = ANY
+			}
+		}
+		if res == "" {
+			return ANY
+		}
+		return res
+
+	case *ast.DeferStatement:
+		c.check(node.Call)
+		return NULL
+
+	case *ast.FunctionLiteral:
+		c.checkFunction(node)
+		return FUNC
+
+	case *ast.CallExpression:
+		return c.checkCall(node)
+
+	case *ast.IfExpression:
+		c.check(node.Condition)
+		ifType := c.check(node.If)
+		if node.Else == nil {
+			return ANY
+		}
+		if elseType := c.check(node.Else); elseType != ifType {
+			return ANY
+		}
+		return ifType
+
+	case *ast.TernaryExpression:
+		c.check(node.Condition)
+		consequence := c.check(node.Consequence)
+		if alternative := c.check(node.Alternative); alternative != consequence {
+			return ANY
+		}
+		return consequence
+
+	case *ast.ForInLoop:
+		c.check(node.Iterable)
+		c.declarePattern(node.Pattern)
+		c.check(node.Body)
+		return NULL
+
+	case *ast.WhileLoop:
+		c.check(node.LoopCond)
+		c.check(node.Body)
+		return NULL
+
+	case *ast.ForLoop:
+		c.check(node.LoopVar)
+		c.check(node.LoopCond)
+		c.check(node.PostLoop)
+		c.check(node.Body)
+		return NULL
+
+	case *ast.IndexExpression:
+		left := c.check(node.Left)
+		c.check(node.Index)
+		switch {
+		case left.IsArray():
+			return left.Elem()
+		case left == STRING:
+			return STRING
+		}
+		return ANY
+
+	case *ast.IndexAssignExpression:
+		c.check(node.Left)
+		c.check(node.Index)
+		return c.check(node.Value)
+
+	case *ast.PrefixExpression:
+		right := c.check(node.Right)
+		if node.Operator == "!" {
+			return BOOL
+		}
+		return right
+
+	case *ast.InfixExpression:
+		return c.checkInfix(node)
+
+	case *ast.ArrayLiteral:
+		elem := Type("")
+		for _, el := range node.Elements {
+			t := c.check(el)
+			if elem == "" {
+				elem = t
+			} else if elem != t {
+				elem = ANY
+			}
+		}
+		if elem == "" {
+			elem = ANY
+		}
+		return ArrayOf(elem)
+
+	case *ast.HashLiteral:
+		for i := range node.Keys {
+			c.check(node.Keys[i])
+			c.check(node.Values[i])
+		}
+		return HASH
+
+	case *ast.Ident:
+		if t, _, ok := c.scope.lookup(node.Value); ok {
+			return t
+		}
+		if _, ok := c.builtin(node.Value); ok {
+			return FUNC
+		}
+		return ANY
+
+	case *ast.IntLiteral:
+		return INT
+	case *ast.FloatLiteral:
+		return FLOAT
+	case *ast.StrLiteral:
+		return STRING
+	case *ast.Boolean:
+		return BOOL
+	}
+
+	return ANY
+}
+
+func (c *Checker) checkFunction(fn *ast.FunctionLiteral) {
+	c.scope = newScope(c.scope)
+	for i, p := range fn.Params {
+		if i < len(fn.ParamPatterns) && fn.ParamPatterns[i] != nil {
+			c.declarePattern(fn.ParamPatterns[i])
+			continue
+		}
+		c.scope.vars[p.Value] = ANY
+		if i < len(fn.ParamTypes) && fn.ParamTypes[i] != nil {
+			c.scope.vars[p.Value] = FromAnnotation(fn.ParamTypes[i])
+		}
+	}
+
+	ret := Type(ANY)
+	if fn.ReturnType != nil {
+		ret = FromAnnotation(fn.ReturnType)
+	}
+	c.returns = append(c.returns, ret)
+
+	last := c.check(fn.Body)
+	n := len(fn.Body.Statements)
+	if n > 0 {
+		if _, ok := fn.Body.Statements[n-1].(*ast.ExpresssionStatement); ok && !assignable(ret, last) {
+			c.errorf("Cant return %s from function returning %s", last, ret)
+		}
+	}
+
+	c.returns = c.returns[:len(c.returns)-1]
+	c.scope = c.scope.outer
+}
+
+func (c *Checker) declarePattern(pattern ast.Expression) {
+	ast.Walk(pattern, func(n ast.Node) {
+		if ident, ok := n.(*ast.Ident); ok {
+			c.scope.vars[ident.Value] = ANY
+			delete(c.scope.funcs, ident.Value)
+		}
+	})
+}
+
+func (c *Checker) checkCall(call *ast.CallExpression) Type {
+	args := []Type{}
+	for _, a := range call.Args {
+		args = append(args, c.check(a))
+	}
+
+	var sig *funcSig
+	name := call.Function.String()
+	switch fn := call.Function.(type) {
+	case *ast.Ident:
+		if _, s, ok := c.scope.lookup(fn.Value); ok {
+			sig = s
+		} else {
+			sig, _ = c.builtin(fn.Value)
+		}
+	case *ast.FunctionLiteral:
+		c.check(fn)
+		sig = signatureOf(fn)
+	default:
+		c.check(fn)
+	}
+
+	if sig == nil {
+		return ANY
+	}
+
+	if len(args) < len(sig.params)-sig.optional || (!sig.variadic && len(args) > len(sig.params)) {
+		c.errorf("%s: Want %d Arguments got %d", name, len(sig.params), len(args))
+		return sig.ret
+	}
+
+	for i, got := range args {
+		want := sig.params[len(sig.params)-1]
+		if i < len(sig.params) {
+			want = sig.params[i]
+		}
+		if !assignable(want, got) {
+			c.errorf("%s: Argument %d has to be of Type %s got %s", name, i+1, want, got)
+		}
+	}
+
+	return sig.ret
+}
+
+func (c *Checker) checkInfix(node *ast.InfixExpression) Type {
+	left := c.check(node.Left)
+	right := c.check(node.Right)
+
+	switch node.Operator {
+	case "==", "!=", "<", ">", "<=", ">=":
+		return BOOL
+	case ">>":
+		return FUNC
+	case "??":
+		if left == NULL || left == right {
+			return right
+		}
+		return ANY
+	}
+
+	// Hashes can define operator methods, so only the runtime knows whether
+	// they support the operator.
+	if left == ANY || right == ANY || left == HASH || right == HASH {
+		return ANY
+	}
+
+	switch {
+	case left == INT && right == INT:
+		if node.Operator == "^" {
+			return FLOAT
+		}
+		return INT
+	case isNumber(left) && isNumber(right):
+		return FLOAT
+	case left == STRING && right == STRING && node.Operator == "+":
+		return STRING
+	}
+
+	c.errorf("Type Mismatch %s %s %s", left, node.Operator, right)
+	return ANY
+}
+
+func signatureOf(fn *ast.FunctionLiteral) *funcSig {
+	sig := &funcSig{ret: ANY}
+	for i := range fn.Params {
+		t := Type(ANY)
+		if i < len(fn.ParamTypes) && fn.ParamTypes[i] != nil {
+			t = FromAnnotation(fn.ParamTypes[i])
+		}
+		sig.params = append(sig.params, t)
+	}
+	if fn.ReturnType != nil {
+		sig.ret = FromAnnotation(fn.ReturnType)
+	}
+	return sig
+}
+
+func FromAnnotation(ta *ast.TypeAnnotation) Type {
+	if ta.Elem != nil {
+		return ArrayOf(FromAnnotation(ta.Elem))
+	}
+	switch ta.Name {
+	case "int", "float", "number", "string", "bool", "null", "hash", "func":
+		return Type(ta.Name)
+	}
+	return ANY
+}
+
+func fromObjectType(t object.ObjectType) Type {
+	switch t {
+	case object.INTEGER_OBJ:
+		return INT
+	case object.FLOAT_OBJ:
+		return FLOAT
+	case object.NUMBER_OBJ:
+		return NUMBER
+	case object.STR_OBJ:
+		return STRING
+	case object.BOOLEAN_OBJ:
+		return BOOL
+	case object.NULL:
+		return NULL
+	case object.ARR_OBJ:
+		return ArrayOf(ANY)
+	case object.HASH_OBJ:
+		return HASH
+	case object.FUNCTION_OBJ, object.BUILTIN_OBJ, object.COMPILED_FUNCTION_OBJ, object.CLOSURE_OBJ:
+		return FUNC
+	}
+	return ANY
+}
+
+func isNumber(t Type) bool {
+	return t == INT || t == FLOAT || t == NUMBER
+}
+
+func assignable(want, got Type) bool {
+	switch {
+	case want == ANY || got == ANY || want == got:
+		return true
+	case want == NUMBER || want == FLOAT:
+		return isNumber(got)
+	case want.IsArray() && got.IsArray():
+		return assignable(want.Elem(), got.Elem())
+	}
+	return false
+}
@@ -0,0 +1,91 @@
+package typecheck
+
+import (
+	"testing"
+
+	"github.com/Arch-4ng3l/Monkey/lexer"
+	"github.com/Arch-4ng3l/Monkey/object"
+	"github.com/Arch-4ng3l/Monkey/parser"
+)
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{`var x: int = 1;`, []string{}},
+		{`var x: float = 1;`, []string{}},
+		{`var x: int = 1.5;`, []string{"Cant use float as int in declaration of x"}},
+		{`var x: [int] = [1, 2]; var y: string = x[0];`, []string{"Cant use int as string in declaration of y"}},
+		{`var x = "a"; x = 1;`, []string{}},
+		{`var x: string = "a"; x = 1;`, []string{"Cant assign int to x of Type string"}},
+		{`var f = func(a) { a }; f = func(a, b) { a }; f(1, 2);`, []string{}},
+		{`var f = func(a: int) { a }; var g = func() { f("a") };`, []string{"f: Argument 1 has to be of Type int got string"}},
+		{`randInt("a");`, []string{"randInt: Argument 1 has to be of Type int got string"}},
+		{`sin(1, 2);`, []string{"sin: Want 1 Arguments got 2"}},
+		{`var s: string = toStr(1.0); sin(s);`, []string{"sin: Argument 1 has to be of Type number got string"}},
+		{`print(1, "a", [1]);`, []string{}},
+		{`sort([2, 1]); sort([2, 1], func(a, b) { a < b });`, []string{}},
+		{`sort([2, 1], 1);`, []string{"sort: Argument 2 has to be of Type func got int"}},
+		{`reduce([1]);`, []string{"reduce: Want 3 Arguments got 1"}},
+		{`1 + "a";`, []string{"Type Mismatch int + string"}},
+		{`var v: hash = {"__add__": func(a, b) { a }}; v + v; v * 2; "v" + v;`, []string{}},
+		{`var v: hash = {}; var n: int = v - v;`, []string{}},
+		{`var v: [int] = [1]; v + v;`, []string{"Type Mismatch [int] + [int]"}},
+		{`var f = func(a: float, b: [int]) -> string { toStr(a) }; f(1, [1]);`, []string{}},
+		{`var f = func(a: float, b: [int]) -> string { toStr(a) }; f(1, ["a"]);`, []string{"f: Argument 2 has to be of Type [int] got [string]"}},
+		{`var f = func(a) -> int { return "a"; };`, []string{"Cant return string from function returning int"}},
+		{`var f = func(a: float) -> int { a + 1 };`, []string{"Cant return float from function returning int"}},
+		{`var f = func(a) { a }; var y: int = f("a");`, []string{}},
+		{`var g = sin >> toStr; "a" |> randInt;`, []string{"randInt: Argument 1 has to be of Type int got string"}},
+	}
+
+	for _, tt := range tests {
+		p := parser.NewParser(lexer.NewLexer(tt.input))
+		program := p.ParseProgram()
+		if len(p.Errors()) != 0 {
+			t.Fatalf("parser errors for %q: %v", tt.input, p.Errors())
+		}
+
+		errs := New(nil).Check(program)
+		if len(errs) != len(tt.expected) {
+			t.Errorf("%q: wrong number of errors want %v got %v", tt.input, tt.expected, errs)
+			continue
+		}
+		for i, err := range errs {
+			if err != tt.expected[i] {
+				t.Errorf("%q: wrong error want %q got %q", tt.input, tt.expected[i], err)
+			}
+		}
+	}
+}
+
+func TestRegistrySignatures(t *testing.T) {
+	r := object.NewRegistry()
+	r.Register("half", &object.BuiltIn{
+		Fn:  func(args ...object.Object) object.Object { return args[0] },
+		Sig: &object.Signature{Params: []object.ObjectType{object.INTEGER_OBJ}, Return: object.INTEGER_OBJ},
+	})
+
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{`var x: int = half(2);`, []string{}},
+		{`half("a");`, []string{"half: Argument 1 has to be of Type int got string"}},
+		{`sin("a");`, []string{}},
+	}
+	for _, tt := range tests {
+		p := parser.NewParser(lexer.NewLexer(tt.input))
+		errs := New(r).Check(p.ParseProgram())
+		if len(errs) != len(tt.expected) {
+			t.Errorf("%q: wrong number of errors want %v got %v", tt.input, tt.expected, errs)
+			continue
+		}
+		for i, err := range errs {
+			if err != tt.expected[i] {
+				t.Errorf("%q: wrong error want %q got %q", tt.input, tt.expected[i], err)
+			}
+		}
+	}
+}
@@ -7,8 +7,12 @@ import (
 
 type Frame struct {
 	fn          *object.CompiledFunction
+	free        []*object.Cell
 	ip          int
 	BasePointer int
+
+	deferred  []int
+	returning object.Object
 }
 
 func NewFrame(fn *object.CompiledFunction, basePointer int) *Frame {
@@ -22,3 +26,15 @@ func NewFrame(fn *object.CompiledFunction, basePointer int) *Frame {
 func (f *Frame) Instructions() code.Instructions {
 	return f.fn.Instructions
 }
+
+// unwind moves ip to the most recently deferred block and remembers the value
+// the frame returns once all deferred blocks ran.
+func (f *Frame) unwind(returnValue object.Object) bool {
+	if len(f.deferred) == 0 {
+		return false
+	}
+	f.returning = returnValue
+	f.ip = f.deferred[len(f.deferred)-1] - 1
+	f.deferred = f.deferred[:len(f.deferred)-1]
+	return true
+}
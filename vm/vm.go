@@ -1,7 +1,9 @@
 package vm
 
 import (
+	"context"
 	"fmt"
+	"time"
 
 	"github.com/Arch-4ng3l/Monkey/code"
 	"github.com/Arch-4ng3l/Monkey/compiler"
@@ -9,11 +11,14 @@ import (
 )
 
 const StackSize = 2048
-const FrameSize = 2048
+const FrameSize = 128
 const GlobalSize = 65536
 
-var True = &object.Boolean{Value: true}
-var False = &object.Boolean{Value: false}
+const DefaultMaxStackSize = 1 << 20
+const DefaultMaxCallDepth = 10000
+
+var True = object.TrueVal
+var False = object.FalseVal
 var Null = &object.Null{}
 
 type Vm struct {
@@ -24,9 +29,31 @@ type Vm struct {
 	globals      []object.Object
 	frames       []*Frame
 	frameIdx     int
+
+	// cells are the captured variables that still live on the stack, in the
+	// order they were captured.
+	cells []*object.Cell
+
+	MaxStackSize int
+	MaxCallDepth int
+
+	// MaxSteps and Timeout bound a RunContext call. Zero means no limit.
+	MaxSteps int
+	Timeout  time.Duration
+
+	budget *object.Budget
+
+	builtins *object.Registry
+	sandbox  *object.Policy
 }
 
+// New returns a Vm for bytecode that uses the Registry the bytecode was
+// compiled with.
 func New(bytecode *compiler.Bytecode) *Vm {
+	builtins := bytecode.Builtins
+	if builtins == nil {
+		builtins = object.NewDefaultRegistry()
+	}
 	mainFn := &object.CompiledFunction{Instructions: bytecode.Instructions}
 	mainFrame := NewFrame(mainFn, 0)
 
@@ -39,8 +66,27 @@ func New(bytecode *compiler.Bytecode) *Vm {
 		globals:      make([]object.Object, GlobalSize),
 		frames:       frames,
 		frameIdx:     1,
+		builtins:     builtins,
+		MaxStackSize: DefaultMaxStackSize,
+		MaxCallDepth: DefaultMaxCallDepth,
 	}
 }
+
+// NewSandboxed returns a Vm that runs bytecode under p. Builtins p does not
+// allow fail when they are loaded.
+func NewSandboxed(bytecode *compiler.Bytecode, p *object.Policy) *Vm {
+	vm := New(bytecode)
+	vm.Sandbox(p)
+	return vm
+}
+
+// Sandbox makes vm run under p from now on.
+func (vm *Vm) Sandbox(p *object.Policy) {
+	vm.builtins = p.Apply(vm.builtins)
+	vm.sandbox = p
+	vm.MaxSteps = p.Steps(vm.MaxSteps)
+}
+
 func NewWithGLobalStore(bytecode *compiler.Bytecode, s []object.Object) *Vm {
 	vm := New(bytecode)
 	vm.globals = s
@@ -51,9 +97,48 @@ func (vm *Vm) currentFrame() *Frame {
 	return vm.frames[vm.frameIdx-1]
 }
 
-func (vm *Vm) pushFrame(f *Frame) {
-	vm.frames[vm.frameIdx] = f
+func (vm *Vm) pushFrame(f *Frame) error {
+	if vm.frameIdx > vm.MaxCallDepth {
+		chain := append(vm.callChain(), object.FunctionName(f.fn.Name))
+		return &object.StackOverflowError{Kind: "call depth", Limit: vm.MaxCallDepth, Chain: chain}
+	}
+	if vm.frameIdx == len(vm.frames) {
+		vm.frames = append(vm.frames, f)
+	} else {
+		vm.frames[vm.frameIdx] = f
+	}
 	vm.frameIdx++
+	return nil
+}
+
+func (vm *Vm) callChain() []string {
+	chain := []string{}
+	for _, f := range vm.frames[1:vm.frameIdx] {
+		chain = append(chain, object.FunctionName(f.fn.Name))
+	}
+	return chain
+}
+
+// growStack makes room for size slots, doubling the stack up to MaxStackSize.
+func (vm *Vm) growStack(size int) error {
+	if size <= len(vm.stack) {
+		return nil
+	}
+	if size > vm.MaxStackSize {
+		return &object.StackOverflowError{Kind: "stack size", Limit: vm.MaxStackSize, Chain: vm.callChain()}
+	}
+
+	newSize := 2 * len(vm.stack)
+	if newSize < size {
+		newSize = size
+	}
+	if newSize > vm.MaxStackSize {
+		newSize = vm.MaxStackSize
+	}
+	stack := make([]object.Object, newSize)
+	copy(stack, vm.stack)
+	vm.stack = stack
+	return nil
 }
 
 func (vm *Vm) popFrame() *Frame {
@@ -69,11 +154,61 @@ func (vm *Vm) StackTop() object.Object {
 }
 
 func (vm *Vm) Run() error {
+	err := vm.run(0)
+	if err != nil {
+		vm.closeCells(0)
+	}
+	return err
+}
+
+// RunContext is like Run but stops with a *object.LimitError once ctx is done
+// or MaxSteps or Timeout is exceeded.
+func (vm *Vm) RunContext(ctx context.Context) error {
+	vm.budget = object.NewBudget(ctx, vm.MaxSteps, vm.Timeout)
+	defer func() { vm.budget = nil }()
+	if err := vm.budget.Check(); err != nil {
+		return err
+	}
+	return vm.Run()
+}
+
+// run executes instructions until the frame stack shrinks back to base frames,
+// which lets operator methods call back into compiled functions. When an error
+// unwinds frames their deferred blocks still run, errors raised by them are
+// dropped in favour of the first one.
+func (vm *Vm) run(base int) error {
+	err := vm.execute(base)
+	if err == nil {
+		return nil
+	}
+	for {
+		idx := vm.frameIdx
+		for idx > base && len(vm.frames[idx-1].deferred) == 0 {
+			idx--
+		}
+		if idx == base {
+			return err
+		}
+		if idx < vm.frameIdx {
+			vm.closeCells(vm.frames[idx].BasePointer)
+		}
+		vm.frameIdx = idx
+		vm.currentFrame().unwind(Null)
+		vm.execute(idx - 1)
+	}
+}
+
+func (vm *Vm) execute(base int) error {
 	var i int
 	var ins code.Instructions
 	var op code.Opcode
 
-	for vm.currentFrame().ip < len(vm.currentFrame().Instructions())-1 {
+	for vm.frameIdx > base && vm.currentFrame().ip < len(vm.currentFrame().Instructions())-1 {
+		if vm.budget != nil {
+			if err := vm.budget.Step(); err != nil {
+				return err
+			}
+		}
 		vm.currentFrame().ip++
 		i = vm.currentFrame().ip
 		ins = vm.currentFrame().Instructions()
@@ -81,10 +216,13 @@ func (vm *Vm) Run() error {
 
 		switch op {
 		case code.OpGetBuiltin:
-			idx := code.ReadUint8(ins[i+1:])
-			vm.currentFrame().ip++
-			def := object.Builtins[idx]
-			err := vm.push(def.Builtin)
+			idx := code.ReadUint16(ins[i+1:])
+			vm.currentFrame().ip += 2
+			def := vm.builtins.At(int(idx))
+			if def == nil {
+				return &object.SandboxError{Name: vm.builtins.Name(int(idx))}
+			}
+			err := vm.push(def)
 			if err != nil {
 				return err
 			}
@@ -99,21 +237,49 @@ func (vm *Vm) Run() error {
 				return err
 			}
 
+		case code.OpTailCall:
+			numArgs := int(code.ReadUint8(ins[i+1:]))
+			vm.currentFrame().ip++
+
+			err := vm.executeTailCall(numArgs)
+			if err != nil {
+				return err
+			}
+
 		case code.OpReturnValue:
 			val := vm.pop()
-			frame := vm.popFrame()
-			vm.stackPointer = frame.BasePointer - 1
-			err := vm.push(val)
+			if vm.currentFrame().unwind(val) {
+				continue
+			}
+			err := vm.returnFromFrame(val)
 			if err != nil {
 				return err
 			}
 
 		case code.OpReturn:
-			frame := vm.popFrame()
-			vm.stackPointer = frame.BasePointer - 1
+			if vm.currentFrame().unwind(Null) {
+				continue
+			}
+			err := vm.returnFromFrame(Null)
+			if err != nil {
+				return err
+			}
 
-			err := vm.push(Null)
+		case code.OpDefer:
+			end := int(code.ReadUint16(ins[i+1:]))
+			frame := vm.currentFrame()
+			frame.deferred = append(frame.deferred, i+3)
+			frame.ip = end - 1
 
+		case code.OpDeferEnd:
+			frame := vm.currentFrame()
+			if frame.returning == nil {
+				return fmt.Errorf("OpDeferEnd outside of a returning frame")
+			}
+			if frame.unwind(frame.returning) {
+				continue
+			}
+			err := vm.returnFromFrame(frame.returning)
 			if err != nil {
 				return err
 			}
@@ -133,6 +299,60 @@ func (vm *Vm) Run() error {
 				return err
 			}
 
+		case code.OpGetFree:
+			freeIdx := code.ReadUint8(ins[i+1:])
+			vm.currentFrame().ip++
+			cell := vm.currentFrame().free[freeIdx]
+			val := cell.Value
+			if cell.Open {
+				val = vm.stack[cell.Slot]
+			}
+			err := vm.push(val)
+			if err != nil {
+				return err
+			}
+
+		case code.OpSetFree:
+			freeIdx := code.ReadUint8(ins[i+1:])
+			vm.currentFrame().ip++
+			cell := vm.currentFrame().free[freeIdx]
+			if cell.Open {
+				vm.stack[cell.Slot] = vm.pop()
+			} else {
+				cell.Value = vm.pop()
+			}
+
+		case code.OpCaptureLocal:
+			localIdx := code.ReadUint8(ins[i+1:])
+			vm.currentFrame().ip++
+			err := vm.push(vm.captureLocal(vm.currentFrame().BasePointer + int(localIdx)))
+			if err != nil {
+				return err
+			}
+
+		case code.OpCaptureFree:
+			freeIdx := code.ReadUint8(ins[i+1:])
+			vm.currentFrame().ip++
+			err := vm.push(vm.currentFrame().free[freeIdx])
+			if err != nil {
+				return err
+			}
+
+		case code.OpClosure:
+			constIdx := code.ReadUint16(ins[i+1:])
+			numFree := int(code.ReadUint8(ins[i+3:]))
+			vm.currentFrame().ip += 3
+			free := make([]*object.Cell, numFree)
+			for j := range free {
+				free[j] = vm.stack[vm.stackPointer-numFree+j].(*object.Cell)
+			}
+			vm.stackPointer -= numFree
+			fn := vm.constans[constIdx].(*object.CompiledFunction)
+			err := vm.push(&object.Closure{Fn: fn, Free: free})
+			if err != nil {
+				return err
+			}
+
 		case code.OpJmp:
 			pos := int(code.ReadUint16(ins[i+1:]))
 			vm.currentFrame().ip = pos - 1
@@ -145,6 +365,14 @@ func (vm *Vm) Run() error {
 				vm.currentFrame().ip = pos - 1
 			}
 
+		case code.OpJmpNull, code.OpJmpNotNull:
+			pos := int(code.ReadUint16(ins[i+1:]))
+			vm.currentFrame().ip += 2
+			isNull := vm.StackTop().Type() == object.NULL
+			if isNull == (op == code.OpJmpNull) {
+				vm.currentFrame().ip = pos - 1
+			}
+
 		case code.OpIndex:
 			index := vm.pop()
 			left := vm.pop()
@@ -164,6 +392,93 @@ func (vm *Vm) Run() error {
 				return err
 			}
 
+		case code.OpHash:
+			numElements := int(code.ReadUint16(ins[i+1:]))
+			vm.currentFrame().ip += 2
+			hash, err := vm.buildHash(vm.stackPointer-numElements, vm.stackPointer)
+			if err != nil {
+				return err
+			}
+			vm.stackPointer = vm.stackPointer - numElements
+
+			err = vm.push(hash)
+			if err != nil {
+				return err
+			}
+
+		case code.OpSetIndex:
+			val := vm.pop()
+			index := vm.pop()
+			left := vm.pop()
+			err := vm.executeSetIndex(left, index, val)
+			if err != nil {
+				return err
+			}
+
+		case code.OpDestructure:
+			kind := code.ReadUint8(ins[i+1:])
+			vm.currentFrame().ip++
+			want := object.ObjectType(object.ARR_OBJ)
+			if kind == code.DestructureHash {
+				want = object.HASH_OBJ
+			}
+			if got := vm.StackTop().Type(); got != want {
+				return fmt.Errorf("Cant destructure %s as %s", got, want)
+			}
+
+		case code.OpSlice:
+			start := int(code.ReadUint16(ins[i+1:]))
+			vm.currentFrame().ip += 2
+			arr := vm.pop().(*object.Array)
+			rest := []object.Object{}
+			if start < len(arr.Elements) {
+				rest = append(rest, arr.Elements[start:]...)
+			}
+			err := vm.push(&object.Array{Elements: rest})
+			if err != nil {
+				return err
+			}
+
+		case code.OpIterable:
+			iterable := vm.pop()
+			items, ok := object.Iterate(iterable)
+			if !ok {
+				return fmt.Errorf("Cant iterate over %s", iterable.Type())
+			}
+			err := vm.push(&object.Array{Elements: items})
+			if err != nil {
+				return err
+			}
+
+		case code.OpLen:
+			arr := vm.pop().(*object.Array)
+			err := vm.push(&object.Integer{Value: len(arr.Elements)})
+			if err != nil {
+				return err
+			}
+
+		case code.OpIsVariant:
+			of := vm.pop()
+			val := vm.pop()
+			err := vm.push(vm.boolToBoolObject(object.IsVariant(val, of)))
+			if err != nil {
+				return err
+			}
+
+		case code.OpNoMatch:
+			return fmt.Errorf("No match arm for %s", vm.pop().Inspect())
+
+		case code.OpCompose:
+			second := vm.pop()
+			first := vm.pop()
+			if !object.IsCallable(first) || !object.IsCallable(second) {
+				return fmt.Errorf("Cant compose %s and %s", first.Type(), second.Type())
+			}
+			err := vm.push(&object.ComposedFunction{First: first, Second: second})
+			if err != nil {
+				return err
+			}
+
 		case code.OpConstant:
 			constIndex := code.ReadUint16(ins[i+1:])
 			vm.currentFrame().ip += 2
@@ -188,13 +503,13 @@ func (vm *Vm) Run() error {
 				return err
 			}
 
-		case code.OpAdd, code.OpDiv, code.OpMul, code.OpSub:
+		case code.OpAdd, code.OpDiv, code.OpMul, code.OpSub, code.OpPow:
 			err := vm.executeBinaryOperation(op)
 			if err != nil {
 				return err
 			}
 
-		case code.OpEqual, code.OpNotEqual, code.OpGreaterThan:
+		case code.OpEqual, code.OpNotEqual, code.OpGreaterThan, code.OpGreaterEqual:
 			err := vm.executeComparision(op)
 			if err != nil {
 				return err
@@ -238,49 +553,318 @@ func (vm *Vm) Run() error {
 	return nil
 }
 
+func (vm *Vm) returnFromFrame(val object.Object) error {
+	frame := vm.popFrame()
+	vm.closeCells(frame.BasePointer)
+	vm.stackPointer = frame.BasePointer - 1
+	return vm.push(val)
+}
+
+// captureLocal returns the cell of stack slot slot, sharing it with the
+// closures that captured the same variable before.
+func (vm *Vm) captureLocal(slot int) *object.Cell {
+	for _, cell := range vm.cells {
+		if cell.Slot == slot {
+			return cell
+		}
+	}
+	cell := &object.Cell{Slot: slot, Open: true}
+	vm.cells = append(vm.cells, cell)
+	return cell
+}
+
+// closeCells moves the captured variables at slot from and above off the
+// stack before their frames go away.
+func (vm *Vm) closeCells(from int) {
+	open := vm.cells[:0]
+	for _, cell := range vm.cells {
+		if cell.Slot < from {
+			open = append(open, cell)
+			continue
+		}
+		cell.Value = vm.stack[cell.Slot]
+		cell.Open = false
+	}
+	for i := len(open); i < len(vm.cells); i++ {
+		vm.cells[i] = nil
+	}
+	vm.cells = open
+}
+
 func (vm *Vm) executeCall(numArgs int) error {
 	callee := vm.stack[vm.stackPointer-1-numArgs]
 
 	switch callee := callee.(type) {
 
 	case *object.CompiledFunction:
-		return vm.callFunction(callee, numArgs)
+		return vm.callFunction(callee, nil, numArgs)
+
+	case *object.Closure:
+		return vm.callFunction(callee.Fn, callee.Free, numArgs)
 
 	case *object.BuiltIn:
 		return vm.callBuiltin(callee, numArgs)
+
+	case *object.ComposedFunction:
+		return vm.callComposed(callee, numArgs)
+
+	case *object.EnumConstructor:
+		res := callee.Construct(vm.stack[vm.stackPointer-numArgs : vm.stackPointer])
+		if err, ok := res.(*object.Error); ok {
+			return fmt.Errorf("%s", err.Message)
+		}
+		vm.stackPointer = vm.stackPointer - numArgs - 1
+		return vm.push(res)
 	}
 
-	return nil
+	return fmt.Errorf("Calling non Function %s", callee.Type())
+}
+
+// Call calls fn with args. It implements object.Caller for builtins and lets
+// hosts call compiled functions after a run has finished.
+func (vm *Vm) Call(fn object.Object, args ...object.Object) (object.Object, error) {
+	res, err := vm.invoke(fn, args...)
+	if err != nil {
+		return nil, err
+	}
+	if e, ok := res.(*object.Error); ok {
+		return nil, e
+	}
+	return res, nil
+}
+
+// invoke calls fn on top of the running frames. A failed call leaves the
+// frames and the stack as it found them.
+func (vm *Vm) invoke(fn object.Object, args ...object.Object) (object.Object, error) {
+	base, sp := vm.frameIdx, vm.stackPointer
+	res, err := vm.invokeFrom(base, fn, args...)
+	if err != nil {
+		vm.closeCells(sp)
+		vm.frameIdx, vm.stackPointer = base, sp
+		return nil, err
+	}
+	return res, nil
+}
+
+func (vm *Vm) invokeFrom(base int, fn object.Object, args ...object.Object) (object.Object, error) {
+	err := vm.push(fn)
+	if err != nil {
+		return nil, err
+	}
+	for _, arg := range args {
+		err = vm.push(arg)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = vm.executeCall(len(args))
+	if err != nil {
+		return nil, err
+	}
+	if vm.frameIdx > base {
+		err = vm.run(base)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return vm.pop(), nil
+}
+
+func (vm *Vm) stringify(obj object.Object) (object.Object, error) {
+	fn, ok := object.Method(obj, object.STR_METHOD)
+	if !ok {
+		return obj, nil
+	}
+
+	res, err := vm.invoke(fn, obj)
+	if err != nil {
+		return nil, err
+	}
+	if res.Type() != object.STR_OBJ {
+		return nil, fmt.Errorf("%s has to return a STRING got %s", object.STR_METHOD, res.Type())
+	}
+	return res, nil
+}
+
+var opMethods = map[code.Opcode]string{
+	code.OpAdd:          "+",
+	code.OpSub:          "-",
+	code.OpMul:          "*",
+	code.OpDiv:          "/",
+	code.OpPow:          "^",
+	code.OpEqual:        "==",
+	code.OpNotEqual:     "!=",
+	code.OpGreaterThan:  ">",
+	code.OpGreaterEqual: ">=",
+}
+
+func (vm *Vm) executeOperatorMethod(operator string, left, right object.Object) (bool, error) {
+	negate := false
+
+	switch operator {
+	case ">":
+		operator = "<"
+		left, right = right, left
+	case ">=":
+		operator = "<"
+		negate = true
+	case "!=":
+		if _, ok := object.FindMethod(object.NE_METHOD, left, right); !ok {
+			operator = "=="
+			negate = true
+		}
+	}
+
+	if operator == "+" && (left.Type() == object.STR_OBJ || right.Type() == object.STR_OBJ) {
+		if ok, err := vm.executeStrMethodConcat(left, right); ok {
+			return true, err
+		}
+	}
+
+	fn, ok := object.FindMethod(object.OperatorMethods[operator], left, right)
+	if !ok {
+		return false, nil
+	}
+
+	res, err := vm.invoke(fn, left, right)
+	if err != nil {
+		return true, err
+	}
+	if negate {
+		res = vm.boolToBoolObject(!isTrue(res))
+	}
+	return true, vm.push(res)
+}
+
+func (vm *Vm) executeStrMethodConcat(left, right object.Object) (bool, error) {
+	if _, ok := object.FindMethod(object.STR_METHOD, left, right); !ok {
+		return false, nil
+	}
+
+	left, err := vm.stringify(left)
+	if err != nil {
+		return true, err
+	}
+	right, err = vm.stringify(right)
+	if err != nil {
+		return true, err
+	}
+	return true, vm.executeBinaryStrOperation(code.OpAdd, left, right)
 }
 
 func (vm *Vm) callBuiltin(fn *object.BuiltIn, numArgs int) error {
 	args := vm.stack[vm.stackPointer-numArgs : vm.stackPointer]
+	if fn.Stringify {
+		strArgs := make([]object.Object, numArgs)
+		for i, arg := range args {
+			str, err := vm.stringify(arg)
+			if err != nil {
+				return err
+			}
+			strArgs[i] = str
+		}
+		args = strArgs
+	}
 
-	res := fn.Fn(args...)
+	var res object.Object
+	if fn.CallFn != nil {
+		res = fn.CallFn(vm, append([]object.Object{}, args...)...)
+	} else {
+		res = fn.Fn(args...)
+	}
+	if e, ok := res.(*object.Error); ok {
+		if e.Cause != nil {
+			return e.Cause
+		}
+		return e
+	}
 	vm.stackPointer = vm.stackPointer - numArgs - 1
 	if res != nil {
-		vm.push(res)
-	} else {
-		vm.push(Null)
+		return vm.push(res)
+	}
+	return vm.push(Null)
+}
+func (vm *Vm) callComposed(fn *object.ComposedFunction, numArgs int) error {
+	args := make([]object.Object, numArgs)
+	copy(args, vm.stack[vm.stackPointer-numArgs:vm.stackPointer])
+	vm.stackPointer = vm.stackPointer - numArgs - 1
+
+	res, err := vm.invoke(fn.First, args...)
+	if err != nil {
+		return err
+	}
+	res, err = vm.invoke(fn.Second, res)
+	if err != nil {
+		return err
 	}
+	return vm.push(res)
+}
+
+// executeTailCall replaces the current frame with the callee instead of
+// pushing a new one. Frames with pending defers fall back to a regular call.
+func (vm *Vm) executeTailCall(numArgs int) error {
+	var callee *object.CompiledFunction
+	var free []*object.Cell
+	switch fn := vm.stack[vm.stackPointer-1-numArgs].(type) {
+	case *object.CompiledFunction:
+		callee = fn
+	case *object.Closure:
+		callee, free = fn.Fn, fn.Free
+	}
+	frame := vm.currentFrame()
+	if callee == nil || len(frame.deferred) > 0 || vm.frameIdx == 1 {
+		return vm.executeCall(numArgs)
+	}
+	if callee.NumParams != numArgs {
+		return fmt.Errorf("Wrong Number Of Arguments Want %d Got %d", callee.NumParams, numArgs)
+	}
+
+	err := vm.growStack(frame.BasePointer + callee.NumLocals)
+	if err != nil {
+		return err
+	}
+	vm.closeCells(frame.BasePointer)
+	copy(vm.stack[frame.BasePointer-1:], vm.stack[vm.stackPointer-1-numArgs:vm.stackPointer])
+	frame.fn = callee
+	frame.free = free
+	frame.ip = -1
+	vm.stackPointer = frame.BasePointer + callee.NumLocals
+
 	return nil
 }
-func (vm *Vm) callFunction(fn *object.CompiledFunction, numArgs int) error {
+
+func (vm *Vm) callFunction(fn *object.CompiledFunction, free []*object.Cell, numArgs int) error {
 	if fn.NumParams != numArgs {
 		return fmt.Errorf("Wrong Number Of Arguments Want %d Got %d", fn.NumParams, numArgs)
 	}
 
 	frame := NewFrame(fn, vm.stackPointer-numArgs)
-	vm.pushFrame(frame)
+	frame.free = free
+	err := vm.growStack(frame.BasePointer + fn.NumLocals)
+	if err != nil {
+		return err
+	}
+	err = vm.pushFrame(frame)
+	if err != nil {
+		return err
+	}
 	vm.stackPointer = frame.BasePointer + fn.NumLocals
 
 	return nil
 }
 
 func (vm *Vm) executeIndex(left, index object.Object) error {
+	if left.Type() == object.HASH_OBJ {
+		return vm.executeHashIdx(left, index)
+	}
 	if index.Type() != object.INTEGER_OBJ {
 		return fmt.Errorf("Cant use %s As indext", index.Type())
 	}
+	if variant, ok := left.(*object.EnumVariant); ok {
+		return vm.executeArrIdx(&object.Array{Elements: variant.Values}, index)
+	}
 	if left.Type() == object.ARR_OBJ {
 		return vm.executeArrIdx(left, index)
 	}
@@ -291,14 +875,81 @@ func (vm *Vm) executeIndex(left, index object.Object) error {
 	return fmt.Errorf("Cant index %s with %s", left.Type(), index.Type())
 }
 
+func (vm *Vm) executeHashIdx(left, index object.Object) error {
+	hash := left.(*object.Hash)
+	key, ok := index.(object.Hashable)
+	if !ok {
+		return fmt.Errorf("Unusable as Hash Key: %s", index.Type())
+	}
+	pair, ok := hash.Pairs[key.HashKey()]
+	if !ok {
+		if fn, ok := object.Method(hash, object.INDEX_METHOD); ok {
+			res, err := vm.invoke(fn, hash, index)
+			if err != nil {
+				return err
+			}
+			return vm.push(res)
+		}
+		return vm.push(Null)
+	}
+	return vm.push(pair.Value)
+}
+
+func (vm *Vm) executeSetIndex(left, index, val object.Object) error {
+	switch left := left.(type) {
+	case *object.Array:
+		if left.Frozen {
+			return fmt.Errorf("Cant modify frozen %s", left.Type())
+		}
+		i, ok := index.(*object.Integer)
+		if !ok {
+			return fmt.Errorf("Cant use %s As index for %s", index.Type(), left.Type())
+		}
+		if i.Value < 0 || i.Value >= len(left.Elements) {
+			return fmt.Errorf("Index %d out of Range", i.Value)
+		}
+		left.Elements[i.Value] = val
+
+	case *object.Hash:
+		if left.Frozen {
+			return fmt.Errorf("Cant modify frozen %s", left.Type())
+		}
+		key, ok := index.(object.Hashable)
+		if !ok {
+			return fmt.Errorf("Unusable as Hash Key: %s", index.Type())
+		}
+		left.Pairs[key.HashKey()] = object.HashPair{Key: index, Value: val}
+
+	default:
+		return fmt.Errorf("Cant assign to index of %s", left.Type())
+	}
+
+	return vm.push(val)
+}
+
+func (vm *Vm) buildHash(startIdx, endIdx int) (object.Object, error) {
+	pairs := make(map[object.HashKey]object.HashPair)
+	for i := startIdx; i < endIdx; i += 2 {
+		key := vm.stack[i]
+		val := vm.stack[i+1]
+
+		hashKey, ok := key.(object.Hashable)
+		if !ok {
+			return nil, fmt.Errorf("Unusable as Hash Key: %s", key.Type())
+		}
+		pairs[hashKey.HashKey()] = object.HashPair{Key: key, Value: val}
+	}
+	return &object.Hash{Pairs: pairs}, nil
+}
+
 func (vm *Vm) executeStrIdx(left, index object.Object) error {
 	str := left.(*object.String)
 	i := index.(*object.Integer).Value
-	max := len(str.Value) - 1
-	if i < 0 || i > max {
+	char, ok := object.CharAt(str.Value, i)
+	if !ok {
 		return vm.push(Null)
 	}
-	return vm.push(&object.String{Value: string(str.Value[i])})
+	return vm.push(char)
 }
 
 func (vm *Vm) executeArrIdx(left, index object.Object) error {
@@ -321,6 +972,13 @@ func (vm *Vm) buildArr(startIdx, endIdx int) object.Object {
 
 func (vm *Vm) executeMinusOperator() error {
 	operand := vm.pop()
+	if fn, ok := object.Method(operand, object.NEG_METHOD); ok {
+		res, err := vm.invoke(fn, operand)
+		if err != nil {
+			return err
+		}
+		return vm.push(res)
+	}
 	if operand.Type() != object.INTEGER_OBJ {
 		return fmt.Errorf("Operand Is not An Integer Object: %s", operand.Type())
 	}
@@ -350,28 +1008,70 @@ func (vm *Vm) executeComparision(op code.Opcode) error {
 	if left.Type() == object.INTEGER_OBJ && right.Type() == object.INTEGER_OBJ {
 		return vm.executeIntegerComparision(op, left, right)
 	}
+	if leftVal, rightVal, ok := floatOperands(left, right); ok {
+		return vm.push(vm.boolToBoolObject(compare(op, leftVal, rightVal)))
+	}
+	if left.Type() == object.STR_OBJ && right.Type() == object.STR_OBJ {
+		leftVal := left.(*object.String).Value
+		rightVal := right.(*object.String).Value
+		return vm.push(vm.boolToBoolObject(compare(op, leftVal, rightVal)))
+	}
+	if variant, ok := left.(*object.EnumVariant); ok && (op == code.OpEqual || op == code.OpNotEqual) {
+		return vm.push(vm.boolToBoolObject(variant.Equals(right) == (op == code.OpEqual)))
+	}
+	if ok, err := vm.executeOperatorMethod(opMethods[op], left, right); ok {
+		return err
+	}
 	switch op {
 	case code.OpEqual:
-		vm.push(vm.boolToBoolObject(left == right))
+		return vm.push(vm.boolToBoolObject(left == right))
 	case code.OpNotEqual:
-		vm.push(vm.boolToBoolObject(left != right))
+		return vm.push(vm.boolToBoolObject(left != right))
 	}
 
-	return nil
+	return fmt.Errorf("unsupported Types for comparison: %s %s", left.Type(), right.Type())
 }
-func (vm *Vm) executeIntegerComparision(op code.Opcode, left, right object.Object) error {
-	leftVal := left.(*object.Integer).Value
-	rightVal := right.(*object.Integer).Value
+
+// floatOperands returns left and right as floats when one is a FLOAT and the
+// other a FLOAT or an INTEGER.
+func floatOperands(left, right object.Object) (float64, float64, bool) {
+	if left.Type() != object.FLOAT_OBJ && right.Type() != object.FLOAT_OBJ {
+		return 0, 0, false
+	}
+	leftVal, ok := floatValue(left)
+	if !ok {
+		return 0, 0, false
+	}
+	rightVal, ok := floatValue(right)
+	return leftVal, rightVal, ok
+}
+
+func floatValue(obj object.Object) (float64, bool) {
+	switch obj := obj.(type) {
+	case *object.Float:
+		return obj.Value, true
+	case *object.Integer:
+		return float64(obj.Value), true
+	}
+	return 0, false
+}
+
+func compare[T int | float64 | string](op code.Opcode, leftVal, rightVal T) bool {
 	switch op {
 	case code.OpGreaterThan:
-		vm.push(vm.boolToBoolObject(leftVal > rightVal))
+		return leftVal > rightVal
+	case code.OpGreaterEqual:
+		return leftVal >= rightVal
 	case code.OpEqual:
-		vm.push(vm.boolToBoolObject(leftVal == rightVal))
-	case code.OpNotEqual:
-		vm.push(vm.boolToBoolObject(leftVal != rightVal))
+		return leftVal == rightVal
+	default:
+		return leftVal != rightVal
 	}
-
-	return nil
+}
+func (vm *Vm) executeIntegerComparision(op code.Opcode, left, right object.Object) error {
+	leftVal := left.(*object.Integer).Value
+	rightVal := right.(*object.Integer).Value
+	return vm.push(vm.boolToBoolObject(compare(op, leftVal, rightVal)))
 }
 func (vm *Vm) boolToBoolObject(input bool) *object.Boolean {
 	if input {
@@ -389,10 +1089,16 @@ func (vm *Vm) executeBinaryOperation(op code.Opcode) error {
 	if leftType == object.INTEGER_OBJ && rightType == object.INTEGER_OBJ {
 		return vm.executeBinaryIntOperation(op, left, right)
 	}
+	if leftVal, rightVal, ok := floatOperands(left, right); ok {
+		return vm.executeBinaryFloatOperation(op, leftVal, rightVal)
+	}
 	if leftType == object.STR_OBJ && rightType == object.STR_OBJ {
 		return vm.executeBinaryStrOperation(op, left, right)
 	}
-	return nil
+	if ok, err := vm.executeOperatorMethod(opMethods[op], left, right); ok {
+		return err
+	}
+	return fmt.Errorf("unsupported Types for binary Operation: %s %s", leftType, rightType)
 }
 func (vm *Vm) executeBinaryStrOperation(op code.Opcode, left, right object.Object) error {
 	leftVal := left.(*object.String).Value
@@ -408,9 +1114,32 @@ func (vm *Vm) executeBinaryStrOperation(op code.Opcode, left, right object.Objec
 
 }
 
+func (vm *Vm) executeBinaryFloatOperation(op code.Opcode, leftVal, rightVal float64) error {
+	var res float64
+	switch op {
+	case code.OpAdd:
+		res = leftVal + rightVal
+	case code.OpDiv:
+		res = leftVal / rightVal
+	case code.OpMul:
+		res = leftVal * rightVal
+	case code.OpSub:
+		res = leftVal - rightVal
+	case code.OpPow:
+		res = object.Power(leftVal, rightVal)
+	default:
+		return fmt.Errorf("unknown Operator for floats: %d", op)
+	}
+
+	return vm.push(&object.Float{Value: res})
+}
+
 func (vm *Vm) executeBinaryIntOperation(op code.Opcode, left, right object.Object) error {
 	leftVal := left.(*object.Integer).Value
 	rightVal := right.(*object.Integer).Value
+	if op == code.OpPow {
+		return vm.push(&object.Float{Value: object.Power(float64(leftVal), float64(rightVal))})
+	}
 	var res int
 	switch op {
 	case code.OpAdd:
@@ -429,8 +1158,16 @@ func (vm *Vm) executeBinaryIntOperation(op code.Opcode, left, right object.Objec
 }
 
 func (vm *Vm) push(obj object.Object) error {
-	if vm.stackPointer >= StackSize {
-		return fmt.Errorf("Stack Overflow")
+	if vm.sandbox != nil {
+		if err := vm.sandbox.CheckSize(obj); err != nil {
+			return err
+		}
+	}
+	if vm.stackPointer >= len(vm.stack) {
+		err := vm.growStack(vm.stackPointer + 1)
+		if err != nil {
+			return err
+		}
 	}
 	vm.stack[vm.stackPointer] = obj
 	vm.stackPointer++
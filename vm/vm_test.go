@@ -1,8 +1,12 @@
 package vm
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/Arch-4ng3l/Monkey/ast"
 	"github.com/Arch-4ng3l/Monkey/compiler"
@@ -52,11 +56,60 @@ func TestArrayExpression(t *testing.T) {
 
 }
 
+func TestHashAndIndexAssignment(t *testing.T) {
+	tests := []vmTestCase{
+		{`var h = {"a": 1, 2: 3}; h["a"] + h[2]`, 4},
+		{`var h = {}; h["b"] = 2; h["b"]`, 2},
+		{`{true: 1}[false]`, Null},
+		{`var a = [1, 2, 3]; a[1] = 5; a[1]`, 5},
+		{`var x = 1; x += 2; x`, 3},
+		{`var f = func() { var y = 2; y *= 3; y }; f()`, 6},
+		{`const c = 4; c`, 4},
+		{`var x = 1; var f = func() { const x = 2; x }; f() + x`, 3},
+	}
+	runVmTest(t, tests)
+}
+
+func TestConstantsAndFreeze(t *testing.T) {
+	compileErrors := []struct {
+		input    string
+		expected string
+	}{
+		{"const x = 1; x = 2;", "cant assign to constant x"},
+		{"const x = 1; var x = 2;", "cant redeclare constant x"},
+		{"var x = 1; const x = 2;", "cant redeclare x as constant"},
+		{"var x = 1; const [x] = [2];", "cant redeclare x as constant"},
+		{"len = 1;", "cant assign to builtin len"},
+	}
+	for _, tt := range compileErrors {
+		comp := compiler.New(nil)
+		err := comp.Compile(parse(tt.input))
+		if err == nil || err.Error() != tt.expected {
+			t.Errorf("wrong compile error want %q got %v", tt.expected, err)
+		}
+	}
+
+	comp := compiler.New(nil)
+	err := comp.Compile(parse(`var h = freeze({"a": [1]}); var a = h["a"]; a[0] = 2;`))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	err = New(comp.Bytecode()).Run()
+	if err == nil || err.Error() != "Cant modify frozen ARRAY" {
+		t.Errorf("wrong runtime error got %v", err)
+	}
+}
+
 func TestStringExpressions(t *testing.T) {
 	tests := []vmTestCase{
 		{`"monkey"`, "monkey"},
 		{`"mon" + "key"`, "monkey"},
 		{`var x = "mon" + "key"; x[1]`, "o"},
+		{`len("héllo")`, 5},
+		{`var s = "héllo"; s[indexOf(s, "l")]`, "l"},
+		{`var s = "héllo"; s[1] + s[len(s) - 1]`, "éo"},
+		{`var s = ""; for (c in "héllo") { s = c + s }; s`, "olléh"},
+		{`"héllo"[5]`, Null},
 	}
 	runVmTest(t, tests)
 }
@@ -134,8 +187,32 @@ func TestBoolArithmetic(t *testing.T) {
 		{"(1 < 2) == true", true},
 		{"(1 > 2) == true", false},
 		{"(1 > 2) == (2 < 1)", true},
+		{"1.5 > 1.0", true},
+		{"1 < 1.5", true},
+		{"2.0 == 2", true},
+		{"0.5 != 0.5", false},
+		{`"a" < "b"`, true},
+		{`"b" > "a"`, true},
+		{`"a" == "a"`, true},
+		{`"a" != "a"`, false},
+		{"2 <= 2", true},
+		{"3 >= 4", false},
+		{"1.5 <= 1", false},
+		{`"a" <= "b"`, true},
+		{`"b" >= "b"`, true},
+		{"[1, 2 <= 1][1]", false},
+		{"var a = 1; var b = 2; [a <= b, a >= b] == [true, false]", false},
 	}
 	runVmTest(t, tests)
+
+	comp := compiler.New(nil)
+	if err := comp.Compile(parse(`1 > "a"`)); err != nil {
+		t.Fatalf("%s", err)
+	}
+	err := New(comp.Bytecode()).Run()
+	if err == nil || err.Error() != "unsupported Types for comparison: INTEGER STRING" {
+		t.Errorf("expected a comparison error got %v", err)
+	}
 }
 
 func TestIntegerArithmetic(t *testing.T) {
@@ -151,6 +228,17 @@ func TestIntegerArithmetic(t *testing.T) {
 	runVmTest(t, tests)
 }
 
+func TestFloatArithmetic(t *testing.T) {
+	tests := []vmTestCase{
+		{"1.5 * 2", 3.0},
+		{"1 + 0.5", 1.5},
+		{"2 ^ 3", 8.0},
+		{"2.0 ^ -1", 0.5},
+		{"var x = 3; x ^ 2 - 1", 8.0},
+	}
+	runVmTest(t, tests)
+}
+
 func testBoolObject(expected bool, actual object.Object) error {
 	result, ok := actual.(*object.Boolean)
 	if !ok {
@@ -192,7 +280,7 @@ func runVmTest(t *testing.T, tests []vmTestCase) {
 
 	for _, tt := range tests {
 		program := parse(tt.input)
-		comp := compiler.New()
+		comp := compiler.New(nil)
 		err := comp.Compile(program)
 		if err != nil {
 			t.Fatalf("%s", err)
@@ -237,6 +325,11 @@ func testExpectedObject(t *testing.T, expected interface{}, actual object.Object
 				t.Errorf("test Integer Object failed: %s", err)
 			}
 		}
+	case float64:
+		result, ok := actual.(*object.Float)
+		if !ok || result.Value != expected {
+			t.Errorf("object is not the Float %f got %s", expected, actual.Inspect())
+		}
 	case bool:
 		err := testBoolObject(expected, actual)
 		if err != nil {
@@ -249,3 +342,651 @@ func testExpectedObject(t *testing.T, expected interface{}, actual object.Object
 		}
 	}
 }
+
+func TestOperatorOverloading(t *testing.T) {
+	vec := `
+	var vec = func(x, y) {
+		{
+			"x": x,
+			"y": y,
+			"__add__": func(a, b) { vec(a["x"] + b["x"], a["y"] + b["y"]) },
+			"__eq__": func(a, b) { a["x"] == b["x"] },
+			"__lt__": func(a, b) { a["x"] < b["x"] },
+			"__pow__": func(a, k) { a["x"] * k },
+			"__neg__": func(a) { vec(-a["x"], -a["y"]) },
+			"__index__": func(a, i) { if (i == 0) { a["x"] } else { a["y"] } },
+			"__str__": func(a) { "(" + toStr(a["x"]) + ", " + toStr(a["y"]) + ")" }
+		}
+	};
+	`
+	tests := []vmTestCase{
+		{vec + `(vec(1, 2) + vec(3, 4))["y"]`, 6},
+		{vec + `vec(1, 2) == vec(1, 5)`, true},
+		{vec + `vec(1, 2) != vec(1, 5)`, false},
+		{vec + `vec(1, 2) < vec(2, 0)`, true},
+		{vec + `vec(1, 2) > vec(2, 0)`, false},
+		{vec + `vec(1, 2) >= vec(1, 0)`, true},
+		{vec + `vec(1, 2) <= vec(0, 0)`, false},
+		{vec + `vec(1, 2) <= vec(1, 0)`, true},
+		{vec + `vec(3, 2) ^ 2`, 6},
+		{vec + `(-vec(1, 2))["x"]`, -1},
+		{vec + `vec(1, 2)[1]`, 2},
+		{vec + `"v=" + vec(1, 2)`, "v=(1, 2)"},
+		{vec + `toStr(vec(7, 8))`, "(7, 8)"},
+		{vec + `var v = vec(1, 1); v + v + v == vec(3, 0)`, true},
+	}
+	runVmTest(t, tests)
+}
+
+func TestConditionalOperators(t *testing.T) {
+	tests := []vmTestCase{
+		{"true ? 1 : 2", 1},
+		{"1 > 2 ? 1 : 2", 2},
+		{"var x = 5; x > 3 ? x > 4 ? 1 : 2 : 3", 1},
+		{"[][0] ?? 7", 7},
+		{"3 ?? 7", 3},
+		{"var n = 0; var f = func() { n = 1; 2 }; 3 ?? f(); n", 0},
+		{"var a = [[1, 2]]; a?[0]?[1]", 2},
+		{"var a = [[1, 2]]; a?[3]?[1]", Null},
+		{`var h = {"user": {"name": 1}}; h?.user?.name`, 1},
+		{`var h = {}; h?.user?.name ?? 9`, 9},
+		{"var n = [][0]; n?[0][1]", Null},
+		{"var n = [][0]; n?[0][1][2] ?? 5", 5},
+		{`var h = {}; h?.user?.name[0]["first"]`, Null},
+		{"var c = 0; var f = func() { c += 1; 0 }; var n = [][0]; n?[f()][f()]; c", 0},
+		{"var a = [[1, 2]]; a?[0][1]", 2},
+		{"var a = [][0]; [a?[0][1], 3][1]", 3},
+	}
+	runVmTest(t, tests)
+}
+
+func TestDestructuring(t *testing.T) {
+	tests := []vmTestCase{
+		{"var [a, b] = [1, 2]; a * 10 + b", 12},
+		{"var [a, [b, c]] = [1, [2, 3]]; a + b + c", 6},
+		{"var [a, b] = [1]; b", Null},
+		{"var [a, ...rest] = [1, 2, 3]; rest", []int{2, 3}},
+		{"var [a, b, ...rest] = [1]; len(rest)", 0},
+		{`var {x, y: [first]} = {"x": 1, "y": [5]}; x + first`, 6},
+		{`var {x, z} = {"x": 1}; z`, Null},
+		{`var f = func([a, b], {c}) { a + b + c }; f([1, 2], {"c": 3})`, 6},
+		{"var s = 0; for (x in [1, 2, 3]) { s += x }; s", 6},
+		{"var s = 0; for (var [a, b] in [[1, 2], [3, 4]]) { s += a * b }; s", 14},
+		{`var s = 0; for ([k, v] in {"a": 1, "b": 2}) { s += v }; s`, 3},
+		{`var f = func() { var s = 0; for (x in [1, 2, 3]) { s += x }; s }; f()`, 6},
+		{`var f = func() { for (x in [1, 2, 3]) { if (x == 2) { return x; } } }; f()`, 2},
+	}
+	runVmTest(t, tests)
+
+	errors := []struct {
+		input    string
+		expected string
+	}{
+		{"var [a] = 1;", "Cant destructure INTEGER as ARRAY"},
+		{"var {a} = [1];", "Cant destructure ARRAY as HASH"},
+		{"for (x in 1) { x }", "Cant iterate over INTEGER"},
+	}
+
+	for _, tt := range errors {
+		comp := compiler.New(nil)
+		err := comp.Compile(parse(tt.input))
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		err = New(comp.Bytecode()).Run()
+		if err == nil || err.Error() != tt.expected {
+			t.Errorf("wrong error want %q got %v", tt.expected, err)
+		}
+	}
+}
+
+func TestPipeAndCompose(t *testing.T) {
+	tests := []vmTestCase{
+		{"var add = func(a, b) { a + b }; 1 |> add(2)", 3},
+		{"var add = func(a, b) { a + b }; 1 |> add(2) |> add(3)", 6},
+		{"[3, 1, 2] |> sort |> len", 3},
+		{"var inc = func(x) { x + 1 }; var dbl = func(x) { x * 2 }; (inc >> dbl)(3)", 8},
+		{"var inc = func(x) { x + 1 }; var f = inc >> inc >> inc; 1 |> f", 4},
+		{"var first = func(a) { a[0] }; var f = sort >> first; f([3, 1, 2])", 1},
+		{"var f = push >> len; f([1], 2)", 2},
+		{"var inc = func(x) { x + 1 }; var g = func(f) { f(1) + 1 }; g(inc >> inc)", 4},
+	}
+	runVmTest(t, tests)
+}
+
+func TestClosures(t *testing.T) {
+	mk := "var mk = func(n) { func(x) { x + n } }; "
+	tests := []vmTestCase{
+		{mk + "mk(3)(1)", 4},
+		{mk + "(mk(10) >> mk(100))(1)", 111},
+		{mk + "map([1, 2], mk(3))", []int{4, 5}},
+		{mk + "var two = mk(2); var three = mk(3); 1 |> two |> three", 6},
+		{"var g = func(a) { func(b) { a + b } }; g(1)(2)", 3},
+		{"var f = func(a) { func(b) { func(c) { a + b + c } } }; f(1)(2)(3)", 6},
+		{"var counter = func() { var n = 0; func() { n += 1; n } }; var c = counter(); c(); c(); c()", 3},
+		{"var f = func() { var x = 1; var g = func() { x }; x = 2; g() }; f()", 2},
+		{"var f = func() { var x = 1; var set = func() { x = 5 }; set(); x }; f()", 5},
+		{"var pair = func() { var n = 0; [func() { n += 1 }, func() { n }] }; var p = pair(); p[0](); p[0](); p[1]()", 2},
+		{"var f = func() { var fact = func(n) { n < 2 ? 1 : n * fact(n - 1) }; fact(5) }; f()", 120},
+		{"var f = func(n) { var loop = func(i, acc) { i == 0 ? acc : loop(i - 1, acc + n) }; loop(10000, 0) }; f(2)", 20000},
+		{"var f = func() { var fns = []; for (x in [1, 2, 3]) { fns = push(fns, func() { x }) }; fns[0]() }; f()", 3},
+		{"var x = 0; var f = func() { x = 5 }; f(); x", 5},
+	}
+	runVmTest(t, tests)
+}
+
+func TestDefer(t *testing.T) {
+	tests := []vmTestCase{
+		{`var log = {"s": ""}; var f = func() { defer log["s"] = log["s"] + "1"; defer log["s"] = log["s"] + "2"; log["s"] = "0"; }; f(); log["s"]`, "021"},
+		{`var log = {"s": ""}; var f = func(x) { defer log["s"] = log["s"] + "d"; if (x) { return "r"; } log["s"] = "n"; }; f(true) + log["s"]`, "rd"},
+		{`var log = {"s": ""}; var f = func(x) { if (x) { defer log["s"] = "inner"; } "done" }; f(true) + log["s"]`, "doneinner"},
+		{`var log = {"s": "a"}; var f = func() { var x = "b"; defer log["s"] = log["s"] + x; x = "c"; }; f(); log["s"]`, "ac"},
+		{`var f = func() { defer 1; 2 }; var g = func() { f() + f() }; g()`, 4},
+	}
+	runVmTest(t, tests)
+
+	comp := compiler.New(nil)
+	err := comp.Compile(parse("defer len([]);"))
+	if err == nil || err.Error() != "cant defer outside of a function" {
+		t.Errorf("wrong error got %v", err)
+	}
+
+	var out bytes.Buffer
+	r := object.NewDefaultRegistry()
+	r.Output = &out
+	comp = compiler.New(r)
+	input := `var g = func(a) { a - 1 }; var f = func() { defer print("c"); g("x") }; var h = func() { defer print("h"); defer 1 + "a"; f() }; h()`
+	if err := comp.Compile(parse(input)); err != nil {
+		t.Fatalf("%s", err)
+	}
+	err = New(comp.Bytecode()).Run()
+	if err == nil || err.Error() != "unsupported Types for binary Operation: STRING INTEGER" {
+		t.Errorf("wrong error got %v", err)
+	}
+	if out.String() != "c\nh\n" {
+		t.Errorf("deferred calls did not run on error got %q", out.String())
+	}
+}
+
+func TestEnums(t *testing.T) {
+	definition := `enum Color { Red, Green, Blue(r, g) };
+var name = func(c) { match (c) { Red => "red", Green => "green", Blue(r, g) => "blue" + toStr(r + g) } };
+`
+	tests := []vmTestCase{
+		{definition + "name(Red)", "red"},
+		{definition + "name(Blue(1, 2))", "blue3"},
+		{definition + "typeof(Green)", "Color"},
+		{definition + "toStr(Blue(1, [2]))", "Color.Blue(1, [2])"},
+		{definition + "Red == Red", true},
+		{definition + "Red == Green", false},
+		{definition + "Blue(1, 2) == Blue(1, 2)", true},
+		{definition + "Blue(1, 2) != Blue(2, 1)", true},
+		{definition + `match (Green) { Red => "r", _ => "other" }`, "other"},
+		{definition + `match (Blue(5, 6)) { Blue(r, g) => { var s = r * g; s }, _ => 0 }`, 30},
+		{definition + "Blue(5, 6)[1]", 6},
+	}
+	runVmTest(t, tests)
+
+	compileErrors := []struct {
+		input    string
+		expected string
+	}{
+		{`match (Red) { Red => 1, Green => 2 }`, "match on Color is not exhaustive missing Blue"},
+		{`match (Red) { Red => 1, Blue(r) => 2, _ => 3 }`, "Blue: want 2 bindings got 1"},
+		{`var x = 1; match (Red) { x => 1 }`, "x is not an enum variant"},
+		{`var f = func(c) { match (c) { Red => 1 } }; 1`, "match on Color is not exhaustive missing Green, Blue"},
+		{`if (false) { match (Red) { Red => 1, Green => 2 } }`, "match on Color is not exhaustive missing Blue"},
+	}
+
+	for _, tt := range compileErrors {
+		comp := compiler.New(nil)
+		err := comp.Compile(parse(definition + tt.input))
+		if err == nil || err.Error() != tt.expected {
+			t.Errorf("wrong error want %q got %v", tt.expected, err)
+		}
+	}
+
+	runtimeErrors := []struct {
+		input    string
+		expected string
+	}{
+		{"match (1) { Red => 1, Green => 2, Blue(r, g) => 3 }", "No match arm for 1"},
+		{"Blue(1)", "Color.Blue: Want 2 Arguments got 1"},
+	}
+
+	for _, tt := range runtimeErrors {
+		comp := compiler.New(nil)
+		err := comp.Compile(parse(definition + tt.input))
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		err = New(comp.Bytecode()).Run()
+		if err == nil || err.Error() != tt.expected {
+			t.Errorf("wrong error want %q got %v", tt.expected, err)
+		}
+	}
+}
+
+func TestTailCalls(t *testing.T) {
+	tests := []vmTestCase{
+		{"var count = func(n, acc) { if (n == 0) { acc } else { count(n - 1, acc + 1) } }; count(100000, 0)", 100000},
+		{"var count = func(n, acc) { if (n == 0) { return acc; } return count(n - 1, acc + 2); }; count(100000, 0)", 200000},
+		{"var step = func(n, f) { n == 0 ? 7 : f(n - 1, f) }; step(100000, step)", 7},
+		{"var fib = func(n) { if (n < 2) { n } else { fib(n - 1) + fib(n - 2) } }; fib(15)", 610},
+		{"var inc = func(x) { x + 1 }; var f = func(x) { (inc >> inc)(x) }; f(1)", 3},
+		{"var f = func(x) { len(x) }; f([1, 2])", 2},
+	}
+	runVmTest(t, tests)
+}
+
+func TestStackLimits(t *testing.T) {
+	run := func(input string, configure func(*Vm)) (object.Object, error) {
+		comp := compiler.New(nil)
+		err := comp.Compile(parse(input))
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		vm := New(comp.Bytecode())
+		configure(vm)
+		err = vm.Run()
+		if err != nil {
+			return nil, err
+		}
+		return vm.LastPoppedStackElement(), nil
+	}
+
+	_, err := run("var down = func(n) { 1 + down(n + 1) }; var start = func() { 1 + down(0) }; start()", func(vm *Vm) {
+		vm.MaxCallDepth = 100
+	})
+	overflow, ok := err.(*object.StackOverflowError)
+	if !ok {
+		t.Fatalf("error is not a StackOverflowError got %T (%v)", err, err)
+	}
+	want := "Stack Overflow: call depth limit of 100 exceeded in start -> down -> down -> down -> down -> ... 91 more -> down -> down -> down -> down -> down"
+	if overflow.Error() != want {
+		t.Errorf("wrong message want %q got %q", want, overflow.Error())
+	}
+
+	res, err := run("var f = func(n) { if (n == 0) { 0 } else { 1 + f(n - 1) } }; f(5000)", func(vm *Vm) {})
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	testExpectedObject(t, 5000, res)
+
+	_, err = run("var f = func(n) { if (n == 0) { 0 } else { 1 + f(n - 1) } }; f(5000)", func(vm *Vm) {
+		vm.MaxStackSize = 4096
+	})
+	overflow, ok = err.(*object.StackOverflowError)
+	if !ok || overflow.Kind != "stack size" {
+		t.Errorf("expected stack size overflow got %v", err)
+	}
+}
+
+func TestLoops(t *testing.T) {
+	tests := []vmTestCase{
+		{"var i = 0; while (i < 10) { i += 1 }; i", 10},
+		{"var s = 0; for (var i = 0; i < 5; i += 1) { s += i }; s", 10},
+		{"var f = func(n) { var s = 0; while (0 < n) { s += n; n -= 1 }; return s; }; f(4)", 10},
+	}
+	runVmTest(t, tests)
+}
+
+func TestExecutionLimits(t *testing.T) {
+	run := func(ctx context.Context, input string, configure func(*Vm)) error {
+		comp := compiler.New(nil)
+		err := comp.Compile(parse(input))
+		if err != nil {
+			t.Fatalf("%s", err)
+		}
+		vm := New(comp.Bytecode())
+		configure(vm)
+		return vm.RunContext(ctx)
+	}
+	testLimit := func(err error, kind string) {
+		t.Helper()
+		var limit *object.LimitError
+		if !errors.As(err, &limit) {
+			t.Fatalf("error is not a LimitError got %v", err)
+		}
+		if limit.Kind != kind {
+			t.Errorf("wrong limit want %q got %q", kind, limit.Kind)
+		}
+	}
+
+	err := run(context.Background(), "while (true) {}", func(vm *Vm) { vm.MaxSteps = 10000 })
+	testLimit(err, object.LimitSteps)
+
+	err = run(context.Background(), "while (true) {}", func(vm *Vm) { vm.Timeout = 20 * time.Millisecond })
+	testLimit(err, object.LimitDeadline)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err = run(ctx, "while (true) {}", func(vm *Vm) {})
+	testLimit(err, object.LimitDeadline)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("error does not wrap context.DeadlineExceeded")
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	testLimit(run(ctx, "while (true) {}", func(vm *Vm) {}), object.LimitCanceled)
+
+	err = run(context.Background(), "var s = 0; for (var i = 0; i < 100; i += 1) { s += i }; s", func(vm *Vm) { vm.MaxSteps = 100000 })
+	if err != nil {
+		t.Errorf("unexpected error %s", err)
+	}
+}
+
+func TestSandbox(t *testing.T) {
+	var out bytes.Buffer
+	policy := &object.Policy{
+		Modules:        []string{"core", "io"},
+		Capabilities:   []object.Capability{object.CapOutput},
+		MaxArrayLen:    3,
+		MaxStringLen:   5,
+		MaxOutputBytes: 8,
+		MaxSteps:       1000,
+	}
+	r := object.NewDefaultRegistry()
+	r.Output = &out
+	run := func(input string) (object.Object, error) {
+		comp := compiler.NewSandboxed(r, policy)
+		err := comp.Compile(parse(input))
+		if err != nil {
+			return nil, err
+		}
+		vm := NewSandboxed(comp.Bytecode(), policy)
+		err = vm.RunContext(context.Background())
+		if err != nil {
+			return nil, err
+		}
+		return vm.LastPoppedStackElement(), nil
+	}
+	testSandboxError := func(input, want string) {
+		t.Helper()
+		_, err := run(input)
+		var sandboxErr *object.SandboxError
+		if !errors.As(err, &sandboxErr) {
+			t.Fatalf("error is not a SandboxError got %v", err)
+		}
+		if err.Error() != want {
+			t.Errorf("wrong message want %q got %q", want, err.Error())
+		}
+	}
+
+	res, err := run("len([1, 2, 3])")
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	testExpectedObject(t, 3, res)
+
+	_, err = run("sin(1)")
+	if err == nil || err.Error() != "builtin sin is not allowed in the sandbox" {
+		t.Errorf("expected compile error got %v", err)
+	}
+
+	testSandboxError("[1, 2, 3, 4]", "Sandbox: array size limit of 3 exceeded")
+	testSandboxError("push([1, 2, 3], 4)", "Sandbox: array size limit of 3 exceeded")
+	testSandboxError(`"abc" + "def"`, "Sandbox: string size limit of 5 exceeded")
+	testSandboxError(`print("abc"); print("def"); print("ghi")`, "Sandbox: output limit of 8 exceeded")
+	if out.String() != "abc\ndef\n" {
+		t.Errorf("wrong output got %q", out.String())
+	}
+
+	_, err = run("while (true) {}")
+	var limit *object.LimitError
+	if !errors.As(err, &limit) {
+		t.Errorf("expected the step limit of the sandbox got %v", err)
+	}
+
+	comp := compiler.New(nil)
+	if err := comp.Compile(parse("sin(1)")); err != nil {
+		t.Fatalf("%s", err)
+	}
+	err = NewSandboxed(comp.Bytecode(), policy).Run()
+	if err == nil || err.Error() != "Sandbox: sin is not allowed" {
+		t.Errorf("expected the vm to refuse sin got %v", err)
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	var out bytes.Buffer
+	r := object.NewDefaultRegistry()
+	r.Output = &out
+	r.RegisterFunc("double", func(args ...object.Object) object.Object {
+		return &object.Integer{Value: 2 * args[0].(*object.Integer).Value}
+	})
+
+	comp := compiler.New(r)
+	err := comp.Compile(parse(`printf("%d", double(21)); print(toFloat("1.5")); double(2)`))
+	if err != nil {
+		t.Fatalf("%s", err)
+	}
+	vm := New(comp.Bytecode())
+	if err := vm.Run(); err != nil {
+		t.Fatalf("%s", err)
+	}
+	testExpectedObject(t, 4, vm.LastPoppedStackElement())
+	if out.String() != "42\n1.500000\n" {
+		t.Errorf("wrong output got %q", out.String())
+	}
+
+	err = compiler.New(nil).Compile(parse("double(21)"))
+	if err == nil || err.Error() != "undefined variable double" {
+		t.Errorf("expected double to be undefined without the registry got %v", err)
+	}
+
+	r = object.NewDefaultRegistry()
+	for i := 0; i < 280; i++ {
+		n := i
+		r.RegisterFunc(fmt.Sprintf("host%d", i), func(args ...object.Object) object.Object {
+			return &object.Integer{Value: n}
+		})
+	}
+	comp = compiler.New(r)
+	if err := comp.Compile(parse("host199() + host279()")); err != nil {
+		t.Fatalf("%s", err)
+	}
+	vm = New(comp.Bytecode())
+	if err := vm.Run(); err != nil {
+		t.Fatalf("%s", err)
+	}
+	testExpectedObject(t, 478, vm.LastPoppedStackElement())
+}
+
+func TestCaller(t *testing.T) {
+	r := object.NewDefaultRegistry()
+	r.Register("twice", &object.BuiltIn{CallFn: func(c object.Caller, args ...object.Object) object.Object {
+		res, err := c.Call(args[0], args[1])
+		if err != nil {
+			return object.ErrorFrom(err)
+		}
+		res, err = c.Call(args[0], res)
+		if err != nil {
+			return object.ErrorFrom(err)
+		}
+		return res
+	}})
+	r.Bind("apply", func(f func(int) int, x int) int { return f(x) })
+
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"twice(func(x) { x * 3 }, 2)", 18},
+		{"apply(func(x) { x + 1 }, 41)", 42},
+		{"var inner = func(y) { y * 2 }; twice(func(x) { apply(inner, x) }, 5)", 20},
+		{"var n = 0; twice(func(x) { n = n + x; n }, 4); n", 8},
+		{`apply(func(x) { x + "a" }, 1)`, "unsupported Types for binary Operation: INTEGER STRING"},
+	}
+	for _, tt := range tests {
+		comp := compiler.New(r)
+		if err := comp.Compile(parse(tt.input)); err != nil {
+			t.Fatalf("%s", err)
+		}
+		vm := New(comp.Bytecode())
+		err := vm.Run()
+		if msg, ok := tt.expected.(string); ok {
+			if err == nil || err.Error() != msg {
+				t.Errorf("expected error %q got %v", msg, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %s", tt.input, err)
+		}
+		testExpectedObject(t, tt.expected, vm.LastPoppedStackElement())
+	}
+
+	comp := compiler.New(r)
+	if err := comp.Compile(parse(`var bad = func(x) { x - 1 }; var good = func(x) { x + 1 }; [bad, good]`)); err != nil {
+		t.Fatalf("%s", err)
+	}
+	vm := New(comp.Bytecode())
+	if err := vm.Run(); err != nil {
+		t.Fatalf("%s", err)
+	}
+	fns := vm.LastPoppedStackElement().(*object.Array).Elements
+	for i := 0; i < 20000; i++ {
+		if _, err := vm.Call(fns[0], &object.String{Value: "s"}); err == nil {
+			t.Fatalf("expected an error calling bad")
+		}
+	}
+	res, err := vm.Call(fns[1], &object.Integer{Value: 1})
+	if err != nil {
+		t.Fatalf("calling after failed calls: %s", err)
+	}
+	testExpectedObject(t, 2, res)
+}
+
+func TestCollections(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"map([1, 2, 3], func(x) { x * 2 })", "[2, 4, 6]"},
+		{"filter([1, 2, 3, 4], func(x) { x > 2 })", "[3, 4]"},
+		{"reduce([1, 2, 3, 4], func(acc, x) { acc + x })", "10"},
+		{"reduce([], func(acc, x) { acc + x }, 5)", "5"},
+		{"find([1, 2, 3], func(x) { x > 1 })", "2"},
+		{"find([1, 2, 3], func(x) { x > 5 })", "null"},
+		{"any([1, 2], func(x) { x > 1 })", "true"},
+		{"all([1, 2], func(x) { x > 1 })", "false"},
+		{"all([], func(x) { false })", "true"},
+		{`zip([1, 2, 3], ["a", "b"])`, "[[1, a], [2, b]]"},
+		{`enumerate(["a", "b"])`, "[[0, a], [1, b]]"},
+		{"flatten([[1, [2]], [3], 4])", "[1, [2], 3, 4]"},
+		{"flatten([[1, [2]], [3], 4], 2)", "[1, 2, 3, 4]"},
+		{`unique([1, 2, 1, "a", "a", 1.5, 1.5, true])`, "[1, 2, a, 1.500000, true]"},
+		{`groupBy(["ab", "c", "de"], func(s) { len(s) })[2]`, "[ab, de]"},
+		{`groupBy(["ab", "c", "de", "fgh"], func(s) { len(s) })`, "{1: [c], 2: [ab, de], 3: [fgh]}"},
+		{`{"b": 1, "a": 2, "c": {"z": 1, "y": 2}}`, "{a: 2, b: 1, c: {y: 2, z: 1}}"},
+		{"sort([3, 1, 2])", "[1, 2, 3]"},
+		{`sort(["b", "c", "a"])`, "[a, b, c]"},
+		{`sort([2.5, 1, 1.5])`, "[1, 1.500000, 2.500000]"},
+		{`map([1.5], func(x) { x * 2.0 })`, "[3.000000]"},
+		{`map([1, 2], func(x) { x / 2.0 - 0.5 })`, "[0.000000, 0.500000]"},
+		{`sort([1.5, 0.5, 2.5], func(a, b) { a < b })`, "[0.500000, 1.500000, 2.500000]"},
+		{`sort([1.5, 2, 0.5], func(a, b) { a > b })`, "[2, 1.500000, 0.500000]"},
+		{`sort(["b", "c", "a"], func(a, b) { a < b })`, "[a, b, c]"},
+		{`sort(["b", "c", "a"], func(a, b) { a > b })`, "[c, b, a]"},
+		{`var a = 2.5; [a, 0.5]`, "[2.500000, 0.500000]"},
+		{"sort([1, 3, 2], func(a, b) { b - a })", "[3, 2, 1]"},
+		{`sort([[2, "a"], [1, "b"], [2, "c"], [1, "d"]], func(a, b) { a[0] < b[0] })`, "[[1, b], [1, d], [2, a], [2, c]]"},
+		{`join(map(split("a b c", " "), func(s) { upper(s) }), "")`, "ABC"},
+		{`padLeft(format("%d", indexOf("grüße", "e")), 3, "0")`, "004"},
+	}
+	for _, tt := range tests {
+		comp := compiler.New(nil)
+		if err := comp.Compile(parse(tt.input)); err != nil {
+			t.Fatalf("%s", err)
+		}
+		vm := New(comp.Bytecode())
+		if err := vm.Run(); err != nil {
+			t.Fatalf("%s: %s", tt.input, err)
+		}
+		if got := vm.LastPoppedStackElement().Inspect(); got != tt.expected {
+			t.Errorf("%s: want %s got %s", tt.input, tt.expected, got)
+		}
+	}
+
+	runtimeErrors := []struct {
+		input    string
+		expected string
+	}{
+		{`map([1], func(x) { x + "a" })`, "unsupported Types for binary Operation: INTEGER STRING"},
+		{"reduce([], func(acc, x) { acc + x })", "Cant reduce an empty ARRAY without an initial Value"},
+		{`sort([1, "a"])`, "Cant compare STRING and INTEGER"},
+		{`sort([1, 2], func(a, b) { "a" })`, "Comparator has to return an INTEGER or BOOLEAN got STRING"},
+	}
+	for _, tt := range runtimeErrors {
+		runVmErrorTest(t, tt.input, tt.expected)
+	}
+}
+
+func TestRegex(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`match(/(\d+)-(\d+)/, "on 2024-05 ok")`, "[2024-05, 2024, 05]"},
+		{`match(/x/, "abc")`, "null"},
+		{`match(/(a)|(b)/, "b")`, "[b, null, b]"},
+		{`match(/(?P<year>\d{4})-(?P<month>\d\d)/, "2024-05")["month"]`, "05"},
+		{`matchAll(/\d+/, "a1b22c333")`, "[[1], [22], [333]]"},
+		{`matchAll(/(?P<k>\w+)=(?P<v>\w+)/, "a=1 b=2")[1]["v"]`, "2"},
+		{`replace("a1b22", /\d+/, "#")`, "a#b#"},
+		{`replace("john smith", /(?P<first>\w+) (?P<last>\w+)/, "${last} ${first}")`, "smith john"},
+		{`split("a, b,c", /,\s*/)`, "[a, b, c]"},
+		{`match(regex("^AB"), "abc")`, "null"},
+		{`match(/^ab/i, "ABc")`, "[AB]"},
+		{`typeof(/a/)`, "REGEX"},
+		{`/a+/`, "/a+/"},
+		{`100 / 2 / 5`, "10"},
+	}
+	for _, tt := range tests {
+		comp := compiler.New(nil)
+		if err := comp.Compile(parse(tt.input)); err != nil {
+			t.Fatalf("%s", err)
+		}
+		vm := New(comp.Bytecode())
+		if err := vm.Run(); err != nil {
+			t.Fatalf("%s: %s", tt.input, err)
+		}
+		if got := vm.LastPoppedStackElement().Inspect(); got != tt.expected {
+			t.Errorf("%s: want %s got %s", tt.input, tt.expected, got)
+		}
+	}
+
+	runVmErrorTest(t, `regex("(")`, "Invalid Regex /(/: error parsing regexp: missing closing ): `(`")
+	runVmErrorTest(t, `match(1, "a")`, "Argument 1 has to be of Type REGEX got INTEGER")
+}
+
+func TestBuiltinErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`writeFile("/nonexistent/dir/x", "a"); "continued"`, "File Error: open /nonexistent/dir/x: no such file or directory"},
+		{`chr(-1)`, "-1 is Not a valid Character"},
+		{`jsonParse("{bad")`, "Invalid JSON: invalid character 'b' looking for beginning of object key string"},
+		{`[ord("")]`, `ord expects a single Character got ""`},
+		{`len(1); "continued"`, "No Supported Datatype INTEGER"},
+	}
+	for _, tt := range tests {
+		runVmErrorTest(t, tt.input, tt.expected)
+	}
+}
+
+// runVmErrorTest runs input and expects it to stop with the error expected.
+func runVmErrorTest(t *testing.T, input, expected string) {
+	t.Helper()
+	comp := compiler.New(nil)
+	if err := comp.Compile(parse(input)); err != nil {
+		t.Fatalf("%s", err)
+	}
+	err := New(comp.Bytecode()).Run()
+	if err == nil || err.Error() != expected {
+		t.Errorf("%s: wrong error want %q got %v", input, expected, err)
+	}
+}